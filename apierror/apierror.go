@@ -0,0 +1,170 @@
+// Package apierror mendefinisikan satu bentuk error untuk seluruh API.
+// Setiap error dikirim sebagai JSON:
+//
+//	{"error": {"code": "validation_failed", "message": "...", "details": [...], "request_id": "..."}}
+//
+// Penyebab internal (misalnya error MySQL) hanya dicatat di log, tidak pernah dikirim ke klien.
+package apierror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"booking_system_app/logging"
+)
+
+// Kode error yang bisa dibaca mesin
+const (
+	CodeBadRequest       = "bad_request"
+	CodeInvalidBody      = "invalid_body"
+	CodeBodyTooLarge     = "body_too_large"
+	CodeValidation       = "validation_failed"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeTooManyRequests  = "too_many_requests"
+	CodeInternal         = "internal_error"
+	CodeUnavailable      = "service_unavailable"
+)
+
+// FieldError menjelaskan kesalahan pada satu field input
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error adalah error API dengan status HTTP, kode dan pesan untuk klien
+type Error struct {
+	Status  int          `json:"-"`
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Details []FieldError `json:"details,omitempty"`
+
+	// Cause adalah penyebab internal yang hanya dicatat di log
+	Cause error `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return e.Message + ": " + e.Cause.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// New membuat Error dengan status, kode dan pesan tertentu
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// BadRequest untuk input yang tidak bisa diproses
+func BadRequest(message string) *Error {
+	return New(http.StatusBadRequest, CodeBadRequest, message)
+}
+
+// InvalidBody untuk body request yang bukan JSON valid
+func InvalidBody() *Error {
+	return New(http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
+}
+
+// BodyTooLarge untuk body request yang melebihi batas ukuran
+func BodyTooLarge() *Error {
+	return New(http.StatusRequestEntityTooLarge, CodeBodyTooLarge, "Request body too large")
+}
+
+// Validation untuk input yang gagal validasi, semua field yang salah dikirim sekaligus
+func Validation(details []FieldError) *Error {
+	e := New(http.StatusUnprocessableEntity, CodeValidation, "Validation failed")
+	e.Details = details
+	return e
+}
+
+// Unauthorized untuk kredensial yang tidak ada atau tidak valid
+func Unauthorized(message string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+// Forbidden untuk pengguna yang tidak punya akses
+func Forbidden(message string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, message)
+}
+
+// NotFound untuk resource yang tidak ada
+func NotFound(message string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, message)
+}
+
+// MethodNotAllowed untuk method HTTP yang tidak didukung route
+func MethodNotAllowed() *Error {
+	return New(http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Invalid request method")
+}
+
+// Conflict untuk data yang bentrok dengan data yang sudah ada
+func Conflict(message string) *Error {
+	return New(http.StatusConflict, CodeConflict, message)
+}
+
+// TooManyRequests untuk klien yang sedang dibatasi
+func TooManyRequests(message string) *Error {
+	return New(http.StatusTooManyRequests, CodeTooManyRequests, message)
+}
+
+// Unavailable untuk layanan yang sementara tidak siap melayani request
+func Unavailable(message string, cause error) *Error {
+	e := New(http.StatusServiceUnavailable, CodeUnavailable, message)
+	e.Cause = cause
+	return e
+}
+
+// Internal membungkus error tak terduga. Klien hanya menerima pesan umum.
+// Query yang melewati deadline dilaporkan sebagai 503 agar klien tahu bisa mencoba lagi.
+func Internal(cause error) *Error {
+	if errors.Is(cause, context.DeadlineExceeded) {
+		return Unavailable("Request timed out", cause)
+	}
+	e := New(http.StatusInternalServerError, CodeInternal, "Internal server error")
+	e.Cause = cause
+	return e
+}
+
+// Envelope adalah bentuk JSON yang dikirim ke klien
+type Envelope struct {
+	Error Body `json:"error"`
+}
+
+// Body adalah isi Envelope: Error ditambah request ID
+type Body struct {
+	*Error
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Write mengirim err sebagai JSON. Error selain *Error dianggap error internal.
+// Error 5xx dicatat beserta penyebabnya menggunakan logger request.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		apiErr = Internal(err)
+	}
+
+	logger := logging.FromContext(r.Context())
+	if errors.Is(apiErr.Cause, context.Canceled) {
+		// Klien sudah memutus koneksi, bukan kesalahan server
+		logger.Info("request canceled by client", "error", apiErr.Cause)
+	} else if apiErr.Status >= http.StatusInternalServerError {
+		logger.Error("request failed", "code", apiErr.Code, "error", apiErr.Cause)
+	} else if apiErr.Cause != nil {
+		logger.Debug("request rejected", "code", apiErr.Code, "error", apiErr.Cause)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(apiErr.Status)
+	json.NewEncoder(w).Encode(Envelope{Error: Body{Error: apiErr, RequestID: w.Header().Get("X-Request-ID")}})
+}
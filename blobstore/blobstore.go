@@ -0,0 +1,198 @@
+// Package blobstore menyimpan file biner seperti foto properti dan kamar.
+// Handler hanya bergantung pada interface BlobStore sehingga penyimpanan
+// lokal bisa diganti object storage tanpa mengubah handler.
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound dikembalikan Open jika key tidak ada
+var ErrNotFound = errors.New("blobstore: not found")
+
+// Info adalah metadata blob untuk header Content-Length dan Last-Modified
+type Info struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// BlobStore menyimpan blob berdasarkan key berbentuk path relatif, misalnya
+// "photos/ab12cd.jpg". Implementasi yang tersedia: LocalStore untuk
+// filesystem lokal dan MemoryStore untuk pengembangan dan pengujian.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, Info, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// ValidKey memeriksa bahwa key adalah path relatif yang bersih, tanpa ".."
+// sehingga tidak bisa keluar dari direktori penyimpanan
+func ValidKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." || segment == "." {
+			return false
+		}
+	}
+	return true
+}
+
+// LocalStore menyimpan setiap blob sebagai file di bawah Dir
+type LocalStore struct {
+	Dir string
+}
+
+// NewLocalStore membuat LocalStore baru dan memastikan direktori tujuan ada
+func NewLocalStore(dir string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating blob directory: %v", err)
+	}
+	return &LocalStore{Dir: dir}, nil
+}
+
+func (s *LocalStore) path(key string) (string, error) {
+	if !ValidKey(key) {
+		return "", fmt.Errorf("blobstore: invalid key %q", key)
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(key)), nil
+}
+
+// Put menulis blob ke file sementara lalu me-rename-nya, sehingga pembaca
+// tidak pernah melihat file yang baru setengah tertulis
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("error creating blob directory: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("error creating blob file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing blob %s: %v", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing blob %s: %v", key, err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("error storing blob %s: %v", key, err)
+	}
+	return nil
+}
+
+// Open membuka blob untuk dibaca; pemanggil wajib menutupnya
+func (s *LocalStore) Open(ctx context.Context, key string) (io.ReadSeekCloser, Info, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, Info{}, err
+	}
+	target, err := s.path(key)
+	if err != nil {
+		return nil, Info{}, ErrNotFound
+	}
+	f, err := os.Open(target)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, Info{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, Info{}, fmt.Errorf("error opening blob %s: %v", key, err)
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, Info{}, fmt.Errorf("error reading blob %s: %v", key, err)
+	}
+	return f, Info{Size: stat.Size(), ModTime: stat.ModTime()}, nil
+}
+
+// Delete menghapus blob. Key yang sudah tidak ada tidak dianggap error.
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error deleting blob %s: %v", key, err)
+	}
+	return nil
+}
+
+// MemoryStore menyimpan blob di memori
+type MemoryStore struct {
+	mu    sync.Mutex
+	blobs map[string]memoryBlob
+}
+
+type memoryBlob struct {
+	data    []byte
+	modTime time.Time
+}
+
+// NewMemoryStore membuat MemoryStore kosong
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{blobs: map[string]memoryBlob{}}
+}
+
+// Put menyimpan salinan isi r
+func (s *MemoryStore) Put(ctx context.Context, key string, r io.Reader) error {
+	if !ValidKey(key) {
+		return fmt.Errorf("blobstore: invalid key %q", key)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blobs[key] = memoryBlob{data: data, modTime: time.Now()}
+	return nil
+}
+
+// Open membuka blob untuk dibaca
+func (s *MemoryStore) Open(ctx context.Context, key string) (io.ReadSeekCloser, Info, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	blob, ok := s.blobs[key]
+	if !ok {
+		return nil, Info{}, ErrNotFound
+	}
+	return nopCloser{bytes.NewReader(blob.data)}, Info{Size: int64(len(blob.data)), ModTime: blob.modTime}, nil
+}
+
+// Delete menghapus blob
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blobs, key)
+	return nil
+}
+
+type nopCloser struct {
+	*bytes.Reader
+}
+
+func (nopCloser) Close() error { return nil }
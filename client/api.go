@@ -0,0 +1,385 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"booking_system_app/database"
+)
+
+// Tipe request dan response adalah alias dari struct server
+type (
+	Response                = database.Response
+	RegisterRequest         = database.RegisterRequest
+	Profile                 = database.Profile
+	UpdateProfileRequest    = database.UpdateProfileRequest
+	TOTPSetupResponse       = database.TOTPSetupResponse
+	RecoveryCodesResponse   = database.RecoveryCodesResponse
+	Property                = database.Property
+	PropertyLocation        = database.PropertyLocation
+	Room                    = database.Room
+	RoomBed                 = database.RoomBed
+	RoomFeatures            = database.RoomFeatures
+	Amenity                 = database.Amenity
+	Photo                   = database.Photo
+	UpdatePhotoRequest      = database.UpdatePhotoRequest
+	SearchCriteria          = database.SearchCriteria
+	GeoPoint                = database.GeoPoint
+	GeoBounds               = database.GeoBounds
+	RoomSearchResult        = database.RoomSearchResult
+	RoomSearchResponse      = database.RoomSearchResponse
+	ListOptions             = database.ListOptions
+	PageInfo                = database.PageInfo
+	BookingRequest          = database.BookingRequest
+	BookingDetail           = database.BookingDetail
+	PaymentDetails          = database.PaymentDetails
+	BookingResponse         = database.BookingResponse
+	Booking                 = database.Booking
+	ReviewRequest           = database.ReviewRequest
+	ReviewRatings           = database.ReviewRatings
+	Review                  = database.Review
+	ReviewListResponse      = database.ReviewListResponse
+	PropertyRating          = database.PropertyRating
+	UserListResponse        = database.UserListResponse
+	UserBookingListResponse = database.UserBookingListResponse
+)
+
+// Register membuat akun customer baru
+func (c *Client) Register(ctx context.Context, req RegisterRequest) (*Response, error) {
+	var resp Response
+	if err := c.do(ctx, http.MethodPost, "/register", req, &resp, false); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Login masuk dengan email dan password lalu menyimpan token sesi di Client.
+// Jika akun memakai 2FA, Login mengembalikan respons beserta ErrMFARequired;
+// lanjutkan dengan VerifyTwoFactor (atau EnrollTwoFactor jika
+// resp.MFAEnrollmentRequired bernilai true).
+func (c *Client) Login(ctx context.Context, email, password string) (*Response, error) {
+	var resp Response
+	req := database.LoginRequest{Email: email, Password: password}
+	if err := c.do(ctx, http.MethodPost, "/login", req, &resp, false); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if resp.MFARequired {
+		c.pendingToken = resp.Token
+		return &resp, ErrMFARequired
+	}
+	c.pendingToken = ""
+	c.setToken(resp.Token)
+	return &resp, nil
+}
+
+// VerifyTwoFactor menyelesaikan login dengan kode TOTP atau recovery code
+func (c *Client) VerifyTwoFactor(ctx context.Context, code string) (*Response, error) {
+	c.mu.Lock()
+	pending := c.pendingToken
+	c.mu.Unlock()
+	if pending == "" {
+		return nil, ErrNotLoggedIn
+	}
+
+	var resp Response
+	req := database.TwoFactorLoginRequest{Token: pending, Code: code}
+	if err := c.do(ctx, http.MethodPost, "/login/2fa", req, &resp, false); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pendingToken = ""
+	c.setToken(resp.Token)
+	return &resp, nil
+}
+
+// EnrollTwoFactor memulai pendaftaran TOTP saat login untuk role yang diwajibkan 2FA.
+// Setelah kode dari aplikasi authenticator didapat, panggil VerifyTwoFactor.
+func (c *Client) EnrollTwoFactor(ctx context.Context) (*TOTPSetupResponse, error) {
+	c.mu.Lock()
+	pending := c.pendingToken
+	c.mu.Unlock()
+	if pending == "" {
+		return nil, ErrNotLoggedIn
+	}
+
+	var resp TOTPSetupResponse
+	req := database.TwoFactorLoginRequest{Token: pending}
+	if err := c.do(ctx, http.MethodPost, "/login/2fa/enroll", req, &resp, false); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RefreshToken memperbarui token sesi sekarang juga. Biasanya tidak perlu
+// dipanggil karena Client memperbarui token otomatis sebelum kedaluwarsa.
+func (c *Client) RefreshToken(ctx context.Context) error {
+	var resp Response
+	if err := c.do(ctx, http.MethodPost, "/me/token", nil, &resp, true); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setToken(resp.Token)
+	return nil
+}
+
+// Profile mengambil profil pengguna yang sedang login
+func (c *Client) Profile(ctx context.Context) (*Profile, error) {
+	var resp Profile
+	if err := c.do(ctx, http.MethodGet, "/me", nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UpdateProfile mengubah nama dan/atau nomor telepon; field nil tidak diubah
+func (c *Client) UpdateProfile(ctx context.Context, req UpdateProfileRequest) error {
+	return c.do(ctx, http.MethodPatch, "/me", req, nil, true)
+}
+
+// ChangePassword mengganti password pengguna yang sedang login
+func (c *Client) ChangePassword(ctx context.Context, currentPassword, newPassword string) error {
+	req := database.ChangePasswordRequest{CurrentPassword: currentPassword, NewPassword: newPassword}
+	return c.do(ctx, http.MethodPost, "/me/password", req, nil, true)
+}
+
+// AddProperty menambahkan properti baru
+func (c *Client) AddProperty(ctx context.Context, property Property) error {
+	return c.do(ctx, http.MethodPost, "/properties", property, nil, true)
+}
+
+// UpdatePropertyLocation mengubah koordinat properti; kedua field nil menghapusnya
+func (c *Client) UpdatePropertyLocation(ctx context.Context, propertyID int, location PropertyLocation) error {
+	return c.do(ctx, http.MethodPut, fmt.Sprintf("/properties/%d/location", propertyID), location, nil, true)
+}
+
+// SetPropertyAmenities mengganti fasilitas properti dengan kode dari ListAmenities
+func (c *Client) SetPropertyAmenities(ctx context.Context, propertyID int, amenities []string) error {
+	req := database.PropertyAmenitiesRequest{Amenities: amenities}
+	return c.do(ctx, http.MethodPut, fmt.Sprintf("/properties/%d/amenities", propertyID), req, nil, true)
+}
+
+// ListAmenities mengambil katalog fasilitas
+func (c *Client) ListAmenities(ctx context.Context) ([]Amenity, error) {
+	var resp []Amenity
+	if err := c.do(ctx, http.MethodGet, "/amenities", nil, &resp, false); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// AddRoom menambahkan kamar ke properti
+func (c *Client) AddRoom(ctx context.Context, propertyID int, room Room) error {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/properties/%d/rooms", propertyID), room, nil, true)
+}
+
+// UpdateRoomStatus mengubah status kamar (available, booked, maintenance)
+func (c *Client) UpdateRoomStatus(ctx context.Context, roomID int, status string) error {
+	req := database.UpdateStatusRequest{Status: status}
+	return c.do(ctx, http.MethodPut, fmt.Sprintf("/rooms/%d/status", roomID), req, nil, true)
+}
+
+// UpdateRoomFeatures mengganti kapasitas, ukuran, tempat tidur dan fasilitas kamar
+func (c *Client) UpdateRoomFeatures(ctx context.Context, roomID int, features RoomFeatures) error {
+	return c.do(ctx, http.MethodPut, fmt.Sprintf("/rooms/%d/features", roomID), features, nil, true)
+}
+
+// UploadPropertyPhoto mengunggah foto properti. filename hanya dipakai
+// sebagai nama file di form; jenis gambar ditentukan server dari isinya.
+func (c *Client) UploadPropertyPhoto(ctx context.Context, propertyID int, filename string, photo io.Reader) (*Photo, error) {
+	return c.uploadPhoto(ctx, fmt.Sprintf("/properties/%d/photos", propertyID), filename, photo)
+}
+
+// UploadRoomPhoto mengunggah foto kamar
+func (c *Client) UploadRoomPhoto(ctx context.Context, roomID int, filename string, photo io.Reader) (*Photo, error) {
+	return c.uploadPhoto(ctx, fmt.Sprintf("/rooms/%d/photos", roomID), filename, photo)
+}
+
+func (c *Client) uploadPhoto(ctx context.Context, path, filename string, photo io.Reader) (*Photo, error) {
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	part, err := form.CreateFormFile("photo", filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, photo); err != nil {
+		return nil, fmt.Errorf("reading photo: %w", err)
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	var resp Photo
+	body := rawBody{contentType: form.FormDataContentType(), data: buf.Bytes()}
+	if err := c.do(ctx, http.MethodPost, path, body, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListPropertyPhotos mengambil foto properti sesuai urutan
+func (c *Client) ListPropertyPhotos(ctx context.Context, propertyID int) ([]Photo, error) {
+	var resp []Photo
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/properties/%d/photos", propertyID), nil, &resp, false); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ListRoomPhotos mengambil foto kamar sesuai urutan
+func (c *Client) ListRoomPhotos(ctx context.Context, roomID int) ([]Photo, error) {
+	var resp []Photo
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/rooms/%d/photos", roomID), nil, &resp, false); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UpdatePhoto memindahkan foto ke posisi lain dan/atau mengubah status cover
+func (c *Client) UpdatePhoto(ctx context.Context, photoID int, req UpdatePhotoRequest) (*Photo, error) {
+	var resp Photo
+	if err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/photos/%d", photoID), req, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeletePhoto menghapus foto beserta file-nya
+func (c *Client) DeletePhoto(ctx context.Context, photoID int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/photos/%d", photoID), nil, nil, true)
+}
+
+// SearchRooms mencari kamar berdasarkan kriteria. Untuk halaman berikutnya,
+// isi criteria.Cursor dengan NextCursor dari respons sebelumnya.
+func (c *Client) SearchRooms(ctx context.Context, criteria SearchCriteria) (*RoomSearchResponse, error) {
+	var resp RoomSearchResponse
+	if err := c.do(ctx, http.MethodPost, "/rooms/search", criteria, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Book memesan satu atau beberapa kamar atas nama pengguna yang sedang login
+// sekaligus memproses pembayaran
+func (c *Client) Book(ctx context.Context, req BookingRequest) (*BookingResponse, error) {
+	var resp BookingResponse
+	if err := c.do(ctx, http.MethodPost, "/bookings", req, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetBooking mengambil detail booking milik pengguna (atau properti yang dikelola)
+func (c *Client) GetBooking(ctx context.Context, bookingID int) (*Booking, error) {
+	var resp Booking
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/bookings/%d", bookingID), nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CancelBooking membatalkan booking yang belum melewati tanggal check-in
+func (c *Client) CancelBooking(ctx context.Context, bookingID int) (*Booking, error) {
+	var resp Booking
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/bookings/%d/cancel", bookingID), nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CompleteBooking menandai booking selesai setelah check-out (staff/admin)
+func (c *Client) CompleteBooking(ctx context.Context, bookingID int) (*Booking, error) {
+	var resp Booking
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/bookings/%d/complete", bookingID), nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CreateReview menulis ulasan untuk booking completed milik pengguna
+func (c *Client) CreateReview(ctx context.Context, bookingID int, req ReviewRequest) (*Review, error) {
+	var resp Review
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/bookings/%d/review", bookingID), req, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListPropertyReviews mengambil ulasan published dan rating agregat properti
+func (c *Client) ListPropertyReviews(ctx context.Context, propertyID int, opts ListOptions) (*ReviewListResponse, error) {
+	var resp ReviewListResponse
+	path := fmt.Sprintf("/properties/%d/reviews", propertyID) + "?" + listQuery(opts).Encode()
+	if err := c.do(ctx, http.MethodGet, path, nil, &resp, false); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ReplyToReview menulis atau mengganti balasan staff atas ulasan
+func (c *Client) ReplyToReview(ctx context.Context, reviewID int, reply string) (*Review, error) {
+	var resp Review
+	req := database.ReviewReplyRequest{Reply: reply}
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("/reviews/%d/reply", reviewID), req, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ModerateReview mengubah status ulasan menjadi published atau hidden (admin)
+func (c *Client) ModerateReview(ctx context.Context, reviewID int, status, reason string) (*Review, error) {
+	var resp Review
+	req := database.ModerateReviewRequest{Status: status, Reason: reason}
+	if err := c.do(ctx, http.MethodPut, fmt.Sprintf("/admin/reviews/%d/moderation", reviewID), req, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListUsers menampilkan daftar pengguna (admin). query boleh kosong.
+func (c *Client) ListUsers(ctx context.Context, query string, opts ListOptions) (*UserListResponse, error) {
+	params := listQuery(opts)
+	if query != "" {
+		params.Set("q", query)
+	}
+	var resp UserListResponse
+	if err := c.do(ctx, http.MethodGet, "/admin/users?"+params.Encode(), nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListUserBookings menampilkan riwayat booking seorang pengguna (admin)
+func (c *Client) ListUserBookings(ctx context.Context, userID int, opts ListOptions) (*UserBookingListResponse, error) {
+	var resp UserBookingListResponse
+	path := fmt.Sprintf("/admin/users/%d/bookings", userID) + "?" + listQuery(opts).Encode()
+	if err := c.do(ctx, http.MethodGet, path, nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// listQuery membuat parameter limit/cursor/sort; nilai kosong memakai default server
+func listQuery(opts ListOptions) url.Values {
+	params := url.Values{}
+	if opts.Limit > 0 {
+		params.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Cursor != "" {
+		params.Set("cursor", opts.Cursor)
+	}
+	if opts.Sort != "" {
+		params.Set("sort", opts.Sort)
+	}
+	return params
+}
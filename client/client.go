@@ -0,0 +1,208 @@
+// Package client adalah SDK Go untuk booking API. Request dan response
+// memakai struct yang sama dengan server (package database), sehingga
+// perubahan payload langsung terlihat saat kompilasi.
+//
+// Contoh:
+//
+//	c := client.New("http://localhost:8080")
+//	if _, err := c.Login(ctx, "user@example.com", "secret"); err != nil {
+//		var apiErr *client.Error
+//		if errors.As(err, &apiErr) && apiErr.Code == apierror.CodeUnauthorized { ... }
+//	}
+//	page, err := c.SearchRooms(ctx, client.SearchCriteria{RoomType: "double", MaxPrice: 500})
+//
+// Token sesi disimpan di Client dan diperbarui otomatis lewat POST /me/token
+// sebelum kedaluwarsa.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRefreshBefore adalah sisa umur token saat Client memperbaruinya
+const DefaultRefreshBefore = time.Hour
+
+// Client memanggil booking API. Aman dipakai dari beberapa goroutine.
+type Client struct {
+	baseURL       string
+	httpClient    *http.Client
+	apiKey        string
+	refreshBefore time.Duration
+
+	mu           sync.Mutex
+	token        string
+	tokenExpiry  time.Time
+	pendingToken string // token parsial dari Login saat 2FA dibutuhkan
+}
+
+// Option mengubah konfigurasi Client
+type Option func(*Client)
+
+// WithHTTPClient memakai http.Client sendiri, misalnya dengan timeout atau transport khusus
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithAPIKey mengautentikasi setiap request dengan API key (header X-API-Key).
+// API key tidak kedaluwarsa lewat refresh sehingga Login tidak diperlukan.
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithToken memakai JWT yang sudah dimiliki, misalnya dari sesi sebelumnya
+func WithToken(token string) Option {
+	return func(c *Client) { c.setToken(token) }
+}
+
+// WithRefreshBefore mengatur kapan token diperbarui, dihitung dari waktu kedaluwarsanya
+func WithRefreshBefore(d time.Duration) Option {
+	return func(c *Client) { c.refreshBefore = d }
+}
+
+// New membuat Client untuk server di baseURL, misalnya "https://api.example.com"
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+		refreshBefore: DefaultRefreshBefore,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Token mengembalikan JWT yang sedang dipakai, misalnya untuk disimpan
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+func (c *Client) setToken(token string) {
+	c.token = token
+	c.tokenExpiry = tokenExpiry(token)
+}
+
+// tokenExpiry membaca klaim exp dari JWT tanpa memverifikasi tanda tangan;
+// verifikasi tetap dilakukan server. Nilai nol berarti exp tidak diketahui.
+func tokenExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		ExpiresAt int64 `json:"exp"`
+	}
+	if json.Unmarshal(payload, &claims) != nil || claims.ExpiresAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.ExpiresAt, 0)
+}
+
+// authHeader mengembalikan header Authorization untuk request terautentikasi,
+// memperbarui token lebih dulu jika sudah mendekati kedaluwarsa
+func (c *Client) authHeader(ctx context.Context) (string, string, error) {
+	if c.apiKey != "" {
+		return "X-API-Key", c.apiKey, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token == "" {
+		return "", "", ErrNotLoggedIn
+	}
+
+	if !c.tokenExpiry.IsZero() && time.Until(c.tokenExpiry) < c.refreshBefore {
+		// Token yang sudah kedaluwarsa tidak bisa diperbarui, server akan menjawab 401
+		if time.Now().Before(c.tokenExpiry) {
+			var resp Response
+			err := c.send(ctx, http.MethodPost, "/me/token", nil, &resp, "Authorization", "Bearer "+c.token)
+			if err != nil {
+				return "", "", fmt.Errorf("refreshing token: %w", err)
+			}
+			c.setToken(resp.Token)
+		}
+	}
+	return "Authorization", "Bearer " + c.token, nil
+}
+
+// do mengirim request ke path. body dan out boleh nil. auth menentukan
+// apakah kredensial Client dikirim.
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}, auth bool) error {
+	if !auth {
+		return c.send(ctx, method, path, body, out, "", "")
+	}
+	name, value, err := c.authHeader(ctx)
+	if err != nil {
+		return err
+	}
+	return c.send(ctx, method, path, body, out, name, value)
+}
+
+// rawBody adalah body request yang sudah di-encode, misalnya multipart/form-data;
+// body lain di-encode sebagai JSON
+type rawBody struct {
+	contentType string
+	data        []byte
+}
+
+func (c *Client) send(ctx context.Context, method, path string, body, out interface{}, headerName, headerValue string) error {
+	var reader io.Reader
+	contentType := "application/json"
+	switch b := body.(type) {
+	case nil:
+	case rawBody:
+		reader = bytes.NewReader(b.data)
+		contentType = b.contentType
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if headerName != "" {
+		req.Header.Set(headerName, headerValue)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return decodeError(resp)
+	}
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding %s %s response: %w", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"booking_system_app/apierror"
+)
+
+// ErrNotLoggedIn dikembalikan untuk method terautentikasi sebelum Login atau WithToken/WithAPIKey
+var ErrNotLoggedIn = errors.New("client: not logged in")
+
+// ErrMFARequired dikembalikan Login jika akun perlu langkah 2FA; lanjutkan
+// dengan VerifyTwoFactor atau EnrollTwoFactor
+var ErrMFARequired = errors.New("client: two-factor authentication required")
+
+// Error adalah respons error dari API, dengan kode dari package apierror
+// (misalnya apierror.CodeValidation) dan detail per field untuk error validasi
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Details    []apierror.FieldError
+	RequestID  string
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("booking api: %d %s: %s", e.StatusCode, e.Code, e.Message)
+	if e.RequestID != "" {
+		msg += " (request_id " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// IsCode memeriksa apakah err adalah *Error dengan kode tertentu
+func IsCode(err error, code string) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// decodeError membaca envelope error API. Respons yang bukan envelope JSON
+// (misalnya dari proxy) tetap dikembalikan sebagai *Error dengan status HTTP-nya.
+func decodeError(resp *http.Response) error {
+	apiErr := &Error{StatusCode: resp.StatusCode, RequestID: resp.Header.Get("X-Request-ID")}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	var envelope apierror.Envelope
+	if json.Unmarshal(data, &envelope) == nil && envelope.Error.Error != nil {
+		apiErr.Code = envelope.Error.Code
+		apiErr.Message = envelope.Error.Message
+		apiErr.Details = envelope.Error.Details
+		if envelope.Error.RequestID != "" {
+			apiErr.RequestID = envelope.Error.RequestID
+		}
+		return apiErr
+	}
+
+	apiErr.Code = http.StatusText(resp.StatusCode)
+	apiErr.Message = string(data)
+	return apiErr
+}
@@ -0,0 +1,508 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"booking_system_app/apierror"
+	"booking_system_app/logging"
+	"booking_system_app/middleware"
+	"booking_system_app/notify"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Struct untuk profil pengguna yang sedang login
+type Profile struct {
+	UserID       int    `json:"user_id"`
+	Name         string `json:"name"`
+	Email        string `json:"email"`
+	PhoneNumber  string `json:"phone_number"`
+	Role         string `json:"role"`
+	PendingEmail string `json:"pending_email,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// Struct untuk request perubahan profil, field yang kosong (nil) tidak diubah
+type UpdateProfileRequest struct {
+	Name        *string `json:"name"`
+	PhoneNumber *string `json:"phone_number"`
+}
+
+// Struct untuk request perubahan password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// Struct untuk request perubahan email
+type ChangeEmailRequest struct {
+	NewEmail        string `json:"new_email"`
+	CurrentPassword string `json:"current_password"`
+}
+
+// Struct untuk konfirmasi token (perubahan email dan sejenisnya)
+type ConfirmTokenRequest struct {
+	Token string `json:"token"`
+}
+
+// Struct untuk request penghapusan akun
+type DeleteAccountRequest struct {
+	CurrentPassword string `json:"current_password"`
+}
+
+// checkPassword memverifikasi password pengguna dengan hash di database
+func checkPassword(ctx context.Context, db *sql.DB, userID int, password string) error {
+	var storedHash string
+	err := db.QueryRowContext(ctx, `SELECT password_hash FROM users WHERE user_id = ?`, userID).Scan(&storedHash)
+	if err != nil {
+		return err
+	}
+	return bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(password))
+}
+
+// newRandomToken membuat token acak beserta hash SHA-256 yang disimpan di database
+func newRandomToken() (token string, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err = rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
+
+// hashToken menghitung hash SHA-256 dari token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetProfile menampilkan profil pengguna yang sedang login
+func GetProfile(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var profile Profile
+	var name, phone, pendingEmail sql.NullString
+
+	query := `
+		SELECT u.user_id, u.name, u.email, u.phone_number, u.role, u.created_at,
+		       (SELECT t.payload FROM user_tokens t
+		        WHERE t.user_id = u.user_id AND t.purpose = ? AND t.used_at IS NULL AND t.expires_at > NOW()
+		        ORDER BY t.created_at DESC LIMIT 1)
+		FROM users u WHERE u.email = ?
+	`
+	err := db.QueryRowContext(ctx, query, tokenPurposeEmailChange, middleware.UserEmail(r)).Scan(
+		&profile.UserID, &name, &profile.Email, &phone, &profile.Role, &profile.CreatedAt, &pendingEmail)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			apierror.Write(w, r, apierror.NotFound("User not found"))
+			return
+		}
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching profile: %w", err)))
+		return
+	}
+	profile.Name = name.String
+	profile.PhoneNumber = phone.String
+	profile.PendingEmail = pendingEmail.String
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+// UpdateProfile memperbarui nama dan/atau nomor telepon pengguna yang sedang login
+func UpdateProfile(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var req UpdateProfileRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	if req.Name == nil && req.PhoneNumber == nil {
+		apierror.Write(w, r, apierror.BadRequest("Nothing to update"))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	// Nilai lama dibaca untuk snapshot audit
+	var userID int
+	var oldName, oldPhone sql.NullString
+	query := `SELECT user_id, name, phone_number FROM users WHERE email = ? FOR UPDATE`
+	err = tx.QueryRowContext(ctx, query, middleware.UserEmail(r)).Scan(&userID, &oldName, &oldPhone)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching profile: %w", err)))
+		return
+	}
+
+	// COALESCE mempertahankan nilai lama untuk field yang tidak dikirim
+	query = `UPDATE users SET name = COALESCE(?, name), phone_number = COALESCE(?, phone_number) WHERE user_id = ?`
+	_, err = tx.ExecContext(ctx, query, req.Name, req.PhoneNumber, userID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error updating profile: %w", err)))
+		return
+	}
+
+	err = recordAudit(ctx, tx, r, auditEvent{
+		Action:     auditProfileUpdate,
+		EntityType: entityUser,
+		EntityID:   userID,
+		Before:     profileSnapshot(&oldName.String, &oldPhone.String),
+		After:      profileSnapshot(req.Name, req.PhoneNumber),
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Message: "Profile updated successfully"})
+}
+
+// ChangePassword mengganti password setelah password lama diverifikasi dengan bcrypt
+func ChangePassword(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var req ChangePasswordRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	userID, err := getUserIDByEmail(ctx, db, middleware.UserEmail(r))
+	if err != nil {
+		apierror.Write(w, r, apierror.NotFound("User not found"))
+		return
+	}
+
+	err = checkPassword(ctx, db, userID, req.CurrentPassword)
+	if err != nil {
+		apierror.Write(w, r, apierror.Unauthorized("Current password is incorrect"))
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error hashing password: %w", err)))
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	// Semua sesi lama dicabut, termasuk token yang dipakai request ini
+	_, err = tx.ExecContext(ctx, `UPDATE users SET password_hash = ?, sessions_valid_after = NOW() WHERE user_id = ?`, hash, userID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error changing password: %w", err)))
+		return
+	}
+
+	err = recordAudit(ctx, tx, r, auditEvent{
+		Action:     auditPasswordChange,
+		EntityType: entityUser,
+		EntityID:   userID,
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Message: "Password changed successfully"})
+}
+
+// RequestEmailChange memulai perubahan email. Email baru baru dipakai
+// setelah pemiliknya mengonfirmasi token lewat ConfirmEmailChange.
+func RequestEmailChange(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var req ChangeEmailRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	userID, err := getUserIDByEmail(ctx, db, middleware.UserEmail(r))
+	if err != nil {
+		apierror.Write(w, r, apierror.NotFound("User not found"))
+		return
+	}
+
+	err = checkPassword(ctx, db, userID, req.CurrentPassword)
+	if err != nil {
+		apierror.Write(w, r, apierror.Unauthorized("Current password is incorrect"))
+		return
+	}
+
+	// Pastikan email baru belum dipakai akun lain
+	var exists int
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE email = ?`, req.NewEmail).Scan(&exists)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error checking email: %w", err)))
+		return
+	}
+	if exists > 0 {
+		apierror.Write(w, r, apierror.Conflict("Email is already in use"))
+		return
+	}
+
+	token, err := issueToken(ctx, db, userID, tokenPurposeEmailChange, req.NewEmail)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error generating token: %w", err)))
+		return
+	}
+
+	err = mailer.Send(r.Context(), notify.Message{
+		To:      req.NewEmail,
+		Subject: "Confirm your new email address",
+		Body:    "Use the following token to confirm your new email address:\n\n" + token + "\n\nIf you did not request this change, ignore this email.",
+	})
+	if err != nil {
+		logging.FromContext(r.Context()).Error("error sending email change confirmation", "user_id", userID, "error", err)
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error sending confirmation email: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(Response{Message: "Confirmation token sent to " + req.NewEmail})
+}
+
+// ConfirmEmailChange menerapkan email baru setelah token dikonfirmasi.
+// Token JWT lama berisi email lama sehingga pengguna harus login ulang.
+func ConfirmEmailChange(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var req ConfirmTokenRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	userID, err := getUserIDByEmail(ctx, db, middleware.UserEmail(r))
+	if err != nil {
+		apierror.Write(w, r, apierror.NotFound("User not found"))
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	tokenUserID, newEmail, err := consumeToken(ctx, tx, req.Token, tokenPurposeEmailChange)
+	if err != nil || tokenUserID != userID {
+		if err != nil && err != errInvalidToken {
+			apierror.Write(w, r, apierror.Internal(err))
+			return
+		}
+		apierror.Write(w, r, apierror.BadRequest("Invalid or expired token"))
+		return
+	}
+
+	// Audit ditulis sebelum email diganti agar aktor masih cocok dengan email lama
+	err = recordAudit(ctx, tx, r, auditEvent{
+		Action:     auditEmailChange,
+		EntityType: entityUser,
+		EntityID:   userID,
+		Before:     map[string]string{"email": piiDigest(middleware.UserEmail(r))},
+		After:      map[string]string{"email": piiDigest(newEmail)},
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	// Konfirmasi token membuktikan kepemilikan email baru
+	_, err = tx.ExecContext(ctx, `UPDATE users SET email = ?, email_verified_at = NOW() WHERE user_id = ?`, newEmail, userID)
+	if err != nil {
+		if isDuplicateEntry(err) {
+			apierror.Write(w, r, apierror.Conflict("Email is already in use"))
+			return
+		}
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error changing email: %w", err)))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Message: "Email changed successfully, please login again"})
+}
+
+// DeleteAccount menganonimkan data pribadi pengguna, termasuk email dan IP di
+// login_attempts. Baris users tetap ada sehingga riwayat bookings dan payments
+// tetap utuh untuk keperluan akuntansi.
+func DeleteAccount(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var req DeleteAccountRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	userID, err := getUserIDByEmail(ctx, db, middleware.UserEmail(r))
+	if err != nil {
+		apierror.Write(w, r, apierror.NotFound("User not found"))
+		return
+	}
+
+	err = checkPassword(ctx, db, userID, req.CurrentPassword)
+	if err != nil {
+		apierror.Write(w, r, apierror.Unauthorized("Current password is incorrect"))
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	// Audit ditulis sebelum data akun dianonimkan agar aktor masih bisa dikenali
+	err = recordAudit(ctx, tx, r, auditEvent{
+		Action:     auditAccountDelete,
+		EntityType: entityUser,
+		EntityID:   userID,
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	// Email diganti alamat unik yang tidak bisa dipakai login, password dikosongkan
+	query := `
+		UPDATE users
+		SET name = 'Deleted User',
+		    email = CONCAT('deleted-', user_id, '@deleted.invalid'),
+		    phone_number = NULL,
+		    password_hash = '',
+		    totp_secret = NULL,
+		    totp_enabled = 0,
+		    deleted_at = NOW()
+		WHERE user_id = ?
+	`
+	_, err = tx.ExecContext(ctx, query, userID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error deleting account: %w", err)))
+		return
+	}
+
+	// login_attempts menyimpan email dan IP mentah. Snapshot audit_logs tidak
+	// perlu dibersihkan karena data pribadi di sana hanya berupa piiDigest.
+	query = `UPDATE login_attempts SET email = NULL, ip_address = NULL WHERE user_id = ? OR email = ?`
+	_, err = tx.ExecContext(ctx, query, userID, middleware.UserEmail(r))
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error removing login attempts: %w", err)))
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM user_tokens WHERE user_id = ?`, userID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error removing account tokens: %w", err)))
+		return
+	}
+
+	// API key akun yang dihapus ikut dicabut, tidak hanya sesi JWT
+	_, err = tx.ExecContext(ctx, `UPDATE api_keys SET revoked_at = NOW() WHERE user_id = ? AND revoked_at IS NULL`, userID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error revoking account API keys: %w", err)))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Message: "Account deleted successfully"})
+}
+
+// RefreshToken menukar JWT yang masih berlaku dengan token baru, sehingga klien
+// yang aktif tidak perlu login ulang setiap 24 jam. Sesi yang sudah berumur
+// maxSessionAge sejak login (klaim orig_iat) tidak bisa di-refresh. Token dari
+// sebelum ganti password atau akun dinonaktifkan sudah ditolak middleware.
+func RefreshToken(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	principal := middleware.CurrentPrincipal(r)
+	if principal == nil || principal.APIKeyID != 0 {
+		apierror.Write(w, r, apierror.BadRequest("Only session tokens can be refreshed"))
+		return
+	}
+	// Token lama tanpa orig_iat diperlakukan seperti sesi yang sudah habis
+	if principal.SessionStart.IsZero() || time.Since(principal.SessionStart) >= maxSessionAge {
+		apierror.Write(w, r, apierror.Unauthorized("Session expired, please login again"))
+		return
+	}
+
+	tokenString, err := signSessionToken(principal.Email, false, principal.SessionStart)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("could not create JWT token: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Message: "Token refreshed", Token: tokenString})
+}
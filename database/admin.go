@@ -0,0 +1,575 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"booking_system_app/apierror"
+	"booking_system_app/logging"
+	"booking_system_app/middleware"
+	"booking_system_app/validate"
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Role yang dikenal oleh sistem
+const (
+	RoleCustomer = "customer"
+	RoleStaff    = "staff"
+	RoleAdmin    = "admin"
+)
+
+// isDuplicateEntry memeriksa apakah error berasal dari pelanggaran UNIQUE KEY di MySQL
+func isDuplicateEntry(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
+
+// getUserIDByEmail mengambil user_id berdasarkan email
+func getUserIDByEmail(ctx context.Context, q rowQuerier, email string) (int, error) {
+	var userID int
+	err := q.QueryRowContext(ctx, `SELECT user_id FROM users WHERE email = ?`, email).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
+
+// recordRoleChange mencatat setiap perubahan role ke tabel role_audit_logs.
+// oldRole kosong berarti akun baru dibuat dengan role tersebut.
+func recordRoleChange(ctx context.Context, tx *sql.Tx, userID int, oldRole, newRole string, changedBy int) error {
+	var previous interface{}
+	if oldRole != "" {
+		previous = oldRole
+	}
+	query := `INSERT INTO role_audit_logs (user_id, old_role, new_role, changed_by) VALUES (?, ?, ?, ?)`
+	_, err := tx.ExecContext(ctx, query, userID, previous, newRole, changedBy)
+	return err
+}
+
+// CreateUser menangani pembuatan akun oleh admin, termasuk akun staff dan admin
+func CreateUser(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var req RegisterRequest
+
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	if err := req.validateForAdmin(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	// Admin yang membuat akun dicatat sebagai pelaku perubahan role
+	adminID, err := getUserIDByEmail(ctx, db, middleware.UserEmail(r))
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error identifying admin: %w", err)))
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error hashing password: %w", err)))
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO users (name, email, password_hash, phone_number, role) VALUES (?, ?, ?, ?, ?)`
+	result, err := tx.ExecContext(ctx, query, req.Name, req.Email, hash, req.PhoneNumber, req.Role)
+	if err != nil {
+		if isDuplicateEntry(err) {
+			apierror.Write(w, r, apierror.Conflict("Email is already registered"))
+			return
+		}
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error creating user: %w", err)))
+		return
+	}
+
+	userID, err := result.LastInsertId()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error retrieving user ID: %w", err)))
+		return
+	}
+
+	err = recordRoleChange(ctx, tx, int(userID), "", req.Role, adminID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error recording role change: %w", err)))
+		return
+	}
+
+	err = recordAudit(ctx, tx, r, auditEvent{
+		Action:     auditUserCreate,
+		EntityType: entityUser,
+		EntityID:   userID,
+		After:      userSnapshot(req.Name, req.Email, req.PhoneNumber, req.Role),
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	err = sendVerificationEmail(ctx, db, int(userID), req.Email)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("error sending verification email", "user_id", userID, "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Message: "User created successfully with role " + req.Role})
+}
+
+// Struct untuk data pengguna pada daftar admin
+type UserSummary struct {
+	UserID        int    `json:"user_id"`
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	PhoneNumber   string `json:"phone_number"`
+	Role          string `json:"role"`
+	Disabled      bool   `json:"disabled"`
+	EmailVerified bool   `json:"email_verified"`
+	Deleted       bool   `json:"deleted"`
+	CreatedAt     string `json:"created_at"`
+}
+
+// Struct untuk respons daftar pengguna dengan paging
+type UserListResponse struct {
+	Users []UserSummary `json:"users"`
+	PageInfo
+}
+
+// Struct untuk request perubahan role oleh admin
+type ChangeRoleRequest struct {
+	UserID int    `json:"user_id,omitempty"` // diambil dari path pada PUT /admin/users/{id}/role
+	Role   string `json:"role"`
+}
+
+// Struct untuk request menonaktifkan/mengaktifkan akun
+type SetUserStatusRequest struct {
+	UserID   int  `json:"user_id,omitempty"` // diambil dari path pada PUT /admin/users/{id}/status
+	Disabled bool `json:"disabled"`
+}
+
+// Struct untuk data booking milik seorang pengguna
+type UserBooking struct {
+	BookingID    int     `json:"booking_id"`
+	RoomID       int     `json:"room_id"`
+	RoomName     string  `json:"room_name"`
+	PropertyName string  `json:"property_name"`
+	CheckInDate  string  `json:"check_in_date" format:"date"`
+	CheckOutDate string  `json:"check_out_date" format:"date"`
+	TotalPrice   float64 `json:"total_price"`
+	Status       string  `json:"status"`
+	CreatedAt    string  `json:"created_at"`
+}
+
+// Struct untuk respons daftar booking pengguna dengan paging
+type UserBookingListResponse struct {
+	Bookings []UserBooking `json:"bookings"`
+	PageInfo
+}
+
+// Sort yang didukung daftar pengguna dan daftar booking pengguna. users.name
+// boleh NULL; tanpa COALESCE kondisi keyset tidak pernah cocok dengan baris
+// NULL sehingga pengguna tanpa nama hilang dari halaman berikutnya.
+var (
+	userListSpec = listSpec{
+		sorts: map[string]sortKey{
+			"id":         {"user_id", sortNumber},
+			"email":      {"email", sortString},
+			"name":       {"COALESCE(name, '')", sortString},
+			"created_at": {"created_at", sortTime},
+		},
+		defaultSort: "id",
+		idColumn:    "user_id",
+	}
+	userBookingListSpec = listSpec{
+		sorts: map[string]sortKey{
+			"created_at":    {"b.created_at", sortTime},
+			"check_in_date": {"b.check_in_date", sortString},
+		},
+		defaultSort: "-created_at",
+		idColumn:    "b.booking_id",
+	}
+)
+
+// ListUsers menampilkan daftar pengguna, bisa dicari berdasarkan email/nama lewat parameter q.
+// Sort: id (default), email, name, created_at.
+func ListUsers(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	list, err := userListSpec.parse(listOptionsFromQuery(r))
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	search := containsPattern(r.URL.Query().Get("q"))
+	conditions := []string{`(email LIKE ? ESCAPE '\\' OR name LIKE ? ESCAPE '\\')`}
+	args := []interface{}{search, search}
+
+	var total int
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users `+sqlConditions(conditions), args...).Scan(&total)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error counting users: %w", err)))
+		return
+	}
+
+	if after, afterArgs := list.where(); after != "" {
+		conditions = append(conditions, after)
+		args = append(args, afterArgs...)
+	}
+	orderBy, limitArgs := list.orderBy()
+	query := `
+		SELECT user_id, name, email, phone_number, role, disabled,
+		       email_verified_at IS NOT NULL, deleted_at IS NOT NULL, created_at
+		FROM users
+		` + sqlConditions(conditions) + `
+		` + orderBy
+	rows, err := db.QueryContext(ctx, query, append(args, limitArgs...)...)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error listing users: %w", err)))
+		return
+	}
+	defer rows.Close()
+
+	users := []UserSummary{}
+	fetched := 0
+	for rows.Next() {
+		var user UserSummary
+		var name, phone sql.NullString
+		err := rows.Scan(&user.UserID, &name, &user.Email, &phone, &user.Role, &user.Disabled,
+			&user.EmailVerified, &user.Deleted, &user.CreatedAt)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(fmt.Errorf("error reading users: %w", err)))
+			return
+		}
+		user.Name = name.String
+		user.PhoneNumber = phone.String
+		fetched++
+		if fetched <= list.limit {
+			users = append(users, user)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error reading users: %w", err)))
+		return
+	}
+
+	page := list.pageInfo(total, fetched, func() (interface{}, int64) {
+		last := users[len(users)-1]
+		var value interface{}
+		switch list.field() {
+		case "id":
+			value = last.UserID
+		case "email":
+			value = last.Email
+		case "name":
+			value = last.Name
+		case "created_at":
+			value = last.CreatedAt
+		}
+		return value, int64(last.UserID)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(UserListResponse{Users: users, PageInfo: page})
+}
+
+// ChangeUserRole mengubah role pengguna dan mencatat perubahannya di role_audit_logs
+func ChangeUserRole(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var req ChangeRoleRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	// Route REST membawa ID di path, route lama di body
+	if _, err := pathInt(r, "id", &req.UserID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	adminID, err := getUserIDByEmail(ctx, db, middleware.UserEmail(r))
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error identifying admin: %w", err)))
+		return
+	}
+
+	// Admin tidak boleh menurunkan role dirinya sendiri agar sistem tidak kehilangan admin
+	if req.UserID == adminID {
+		apierror.Write(w, r, apierror.BadRequest("Admins cannot change their own role"))
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	var oldRole string
+	err = tx.QueryRowContext(ctx, `SELECT role FROM users WHERE user_id = ? FOR UPDATE`, req.UserID).Scan(&oldRole)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			apierror.Write(w, r, apierror.NotFound("User not found"))
+			return
+		}
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching user: %w", err)))
+		return
+	}
+
+	if oldRole == req.Role {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Response{Message: "User already has role " + req.Role})
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE users SET role = ? WHERE user_id = ?`, req.Role, req.UserID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error changing role: %w", err)))
+		return
+	}
+
+	err = recordRoleChange(ctx, tx, req.UserID, oldRole, req.Role, adminID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error recording role change: %w", err)))
+		return
+	}
+
+	err = recordAudit(ctx, tx, r, auditEvent{
+		Action:     auditUserRoleChange,
+		EntityType: entityUser,
+		EntityID:   req.UserID,
+		Before:     map[string]string{"role": oldRole},
+		After:      map[string]string{"role": req.Role},
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Message: "Role changed from " + oldRole + " to " + req.Role})
+}
+
+// SetUserStatus menonaktifkan atau mengaktifkan kembali akun pengguna.
+// RequirePermission memeriksa flag disabled di setiap request sehingga token
+// milik akun yang dinonaktifkan langsung ditolak.
+func SetUserStatus(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var req SetUserStatusRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	// Route REST membawa ID di path, route lama di body
+	if _, err := pathInt(r, "id", &req.UserID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	adminID, err := getUserIDByEmail(ctx, db, middleware.UserEmail(r))
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error identifying admin: %w", err)))
+		return
+	}
+
+	if req.UserID == adminID && req.Disabled {
+		apierror.Write(w, r, apierror.BadRequest("Admins cannot disable their own account"))
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	var wasDisabled bool
+	err = tx.QueryRowContext(ctx, `SELECT disabled FROM users WHERE user_id = ? FOR UPDATE`, req.UserID).Scan(&wasDisabled)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			apierror.Write(w, r, apierror.NotFound("User not found"))
+			return
+		}
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching user: %w", err)))
+		return
+	}
+
+	// Menonaktifkan akun juga mencabut semua sesinya, sehingga token lama tetap
+	// tidak berlaku walaupun akun diaktifkan kembali
+	query := `UPDATE users SET disabled = ? WHERE user_id = ?`
+	if req.Disabled {
+		query = `UPDATE users SET disabled = ?, sessions_valid_after = NOW() WHERE user_id = ?`
+	}
+	_, err = tx.ExecContext(ctx, query, req.Disabled, req.UserID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error updating user status: %w", err)))
+		return
+	}
+
+	err = recordAudit(ctx, tx, r, auditEvent{
+		Action:     auditUserStatusChange,
+		EntityType: entityUser,
+		EntityID:   req.UserID,
+		Before:     map[string]bool{"disabled": wasDisabled},
+		After:      map[string]bool{"disabled": req.Disabled},
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	message := "User enabled successfully"
+	if req.Disabled {
+		message = "User disabled successfully"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Message: message})
+}
+
+// ListUserBookings menampilkan riwayat booking milik seorang pengguna.
+// Sort: -created_at (default), check_in_date.
+func ListUserBookings(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	// /admin/users/{id}/bookings membawa ID di path, route lama di query user_id
+	userID, _ := strconv.Atoi(r.URL.Query().Get("user_id"))
+	_, err := pathInt(r, "id", &userID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if userID <= 0 {
+		apierror.Write(w, r, apierror.BadRequest("Invalid user_id"))
+		return
+	}
+
+	list, err := userBookingListSpec.parse(listOptionsFromQuery(r))
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	var total int
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM bookings WHERE user_id = ?`, userID).Scan(&total)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error counting bookings: %w", err)))
+		return
+	}
+
+	conditions := []string{"b.user_id = ?"}
+	args := []interface{}{userID}
+	if after, afterArgs := list.where(); after != "" {
+		conditions = append(conditions, after)
+		args = append(args, afterArgs...)
+	}
+	orderBy, limitArgs := list.orderBy()
+	query := `
+		SELECT b.booking_id, b.room_id, r.room_name, p.name, b.check_in_date, b.check_out_date,
+		       b.total_price, b.status, b.created_at
+		FROM bookings b
+		JOIN rooms r ON b.room_id = r.room_id
+		JOIN properties p ON r.property_id = p.property_id
+		` + sqlConditions(conditions) + `
+		` + orderBy
+	rows, err := db.QueryContext(ctx, query, append(args, limitArgs...)...)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error listing bookings: %w", err)))
+		return
+	}
+	defer rows.Close()
+
+	bookings := []UserBooking{}
+	fetched := 0
+	for rows.Next() {
+		var booking UserBooking
+		var checkIn, checkOut time.Time
+		err := rows.Scan(&booking.BookingID, &booking.RoomID, &booking.RoomName, &booking.PropertyName,
+			&checkIn, &checkOut, &booking.TotalPrice, &booking.Status, &booking.CreatedAt)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(fmt.Errorf("error reading bookings: %w", err)))
+			return
+		}
+		// Kolom DATE dikirim tanpa jam agar sama dengan format input booking
+		booking.CheckInDate = checkIn.Format(validate.DateLayout)
+		booking.CheckOutDate = checkOut.Format(validate.DateLayout)
+		fetched++
+		if fetched <= list.limit {
+			bookings = append(bookings, booking)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error reading bookings: %w", err)))
+		return
+	}
+
+	page := list.pageInfo(total, fetched, func() (interface{}, int64) {
+		last := bookings[len(bookings)-1]
+		if list.field() == "check_in_date" {
+			return last.CheckInDate, int64(last.BookingID)
+		}
+		return last.CreatedAt, int64(last.BookingID)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(UserBookingListResponse{Bookings: bookings, PageInfo: page})
+}
@@ -0,0 +1,479 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"booking_system_app/apierror"
+	"booking_system_app/validate"
+)
+
+// defaultMaxAdults dipakai jika kamar baru tidak menyebutkan kapasitas,
+// sama dengan default kolom rooms.max_adults
+const defaultMaxAdults = 2
+
+// Nilai enum mengikuti kolom room_beds.bed_type
+var bedTypes = []string{"single", "double", "queen", "king", "sofa_bed", "bunk"}
+
+// Amenity adalah satu fasilitas di katalog
+type Amenity struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// RoomBed adalah jumlah tempat tidur dengan jenis tertentu di satu kamar
+type RoomBed struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
+
+// RoomFeatures adalah kapasitas, ukuran, tempat tidur dan fasilitas kamar.
+// Dipakai sebagai body PUT /rooms/{id}/features (mengganti seluruh nilai
+// lama) dan disertakan di setiap hasil pencarian kamar.
+type RoomFeatures struct {
+	MaxAdults   int       `json:"max_adults"`
+	MaxChildren int       `json:"max_children"`
+	SizeSqm     *float64  `json:"size_sqm"`
+	Beds        []RoomBed `json:"beds"`
+	Amenities   []string  `json:"amenities"` // kode dari katalog GET /amenities
+}
+
+// Struct untuk request penggantian fasilitas properti
+type PropertyAmenitiesRequest struct {
+	Amenities []string `json:"amenities"`
+}
+
+// querier dipenuhi *sql.DB dan *sql.Tx
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// placeholders membuat "?, ?, ?" untuk klausa IN dengan n nilai
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// amenityIDs mengubah kode fasilitas menjadi amenity_id. Kode yang tidak ada
+// di katalog dilaporkan sebagai error validasi pada field.
+func amenityIDs(ctx context.Context, tx *sql.Tx, field string, codes []string) ([]int, error) {
+	if len(codes) == 0 {
+		return nil, nil
+	}
+	args := make([]interface{}, len(codes))
+	for i, code := range codes {
+		args[i] = code
+	}
+	rows, err := tx.QueryContext(ctx, `SELECT amenity_id, code FROM amenities WHERE code IN (`+placeholders(len(codes))+`)`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching amenities: %w", err)
+	}
+	defer rows.Close()
+
+	found := map[string]int{}
+	for rows.Next() {
+		var id int
+		var code string
+		if err := rows.Scan(&id, &code); err != nil {
+			return nil, fmt.Errorf("error reading amenities: %w", err)
+		}
+		found[code] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading amenities: %w", err)
+	}
+
+	ids := make([]int, 0, len(codes))
+	for _, code := range codes {
+		id, ok := found[code]
+		if !ok {
+			return nil, apierror.Validation([]apierror.FieldError{{Field: field, Message: "unknown amenity " + code}})
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// setRoomFeatures mengganti kapasitas, tempat tidur dan fasilitas kamar
+func setRoomFeatures(ctx context.Context, tx *sql.Tx, roomID int, features RoomFeatures) error {
+	ids, err := amenityIDs(ctx, tx, "amenities", features.Amenities)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE rooms SET max_adults = ?, max_children = ?, size_sqm = ? WHERE room_id = ?`,
+		features.MaxAdults, features.MaxChildren, features.SizeSqm, roomID)
+	if err != nil {
+		return fmt.Errorf("error updating room capacity: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM room_beds WHERE room_id = ?`, roomID); err != nil {
+		return fmt.Errorf("error clearing room beds: %w", err)
+	}
+	for _, bed := range features.Beds {
+		_, err := tx.ExecContext(ctx, `INSERT INTO room_beds (room_id, bed_type, quantity) VALUES (?, ?, ?)`, roomID, bed.Type, bed.Count)
+		if err != nil {
+			return fmt.Errorf("error adding room bed: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM room_amenities WHERE room_id = ?`, roomID); err != nil {
+		return fmt.Errorf("error clearing room amenities: %w", err)
+	}
+	for _, id := range ids {
+		_, err := tx.ExecContext(ctx, `INSERT INTO room_amenities (room_id, amenity_id) VALUES (?, ?)`, roomID, id)
+		if err != nil {
+			return fmt.Errorf("error adding room amenity: %w", err)
+		}
+	}
+	return nil
+}
+
+// setPropertyAmenities mengganti fasilitas properti
+func setPropertyAmenities(ctx context.Context, tx *sql.Tx, propertyID int, codes []string) error {
+	ids, err := amenityIDs(ctx, tx, "amenities", codes)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM property_amenities WHERE property_id = ?`, propertyID); err != nil {
+		return fmt.Errorf("error clearing property amenities: %w", err)
+	}
+	for _, id := range ids {
+		_, err := tx.ExecContext(ctx, `INSERT INTO property_amenities (property_id, amenity_id) VALUES (?, ?)`, propertyID, id)
+		if err != nil {
+			return fmt.Errorf("error adding property amenity: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadRoomDetails mengisi Beds dan Amenities untuk setiap kamar di map
+// (key room_id). Kapasitas dan ukuran sudah dibaca pemanggil dari tabel rooms.
+func loadRoomDetails(ctx context.Context, q querier, rooms map[int]*RoomFeatures) error {
+	if len(rooms) == 0 {
+		return nil
+	}
+	args := make([]interface{}, 0, len(rooms))
+	for id, features := range rooms {
+		args = append(args, id)
+		features.Beds = []RoomBed{}
+		features.Amenities = []string{}
+	}
+	in := placeholders(len(args))
+
+	rows, err := q.QueryContext(ctx, `SELECT room_id, bed_type, quantity FROM room_beds WHERE room_id IN (`+in+`) ORDER BY room_id, bed_type`, args...)
+	if err != nil {
+		return fmt.Errorf("error fetching room beds: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var roomID int
+		var bed RoomBed
+		if err := rows.Scan(&roomID, &bed.Type, &bed.Count); err != nil {
+			return fmt.Errorf("error reading room beds: %w", err)
+		}
+		rooms[roomID].Beds = append(rooms[roomID].Beds, bed)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading room beds: %w", err)
+	}
+
+	amenities, err := q.QueryContext(ctx, `
+		SELECT ra.room_id, a.code
+		FROM room_amenities ra
+		JOIN amenities a ON ra.amenity_id = a.amenity_id
+		WHERE ra.room_id IN (`+in+`)
+		ORDER BY ra.room_id, a.code
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("error fetching room amenities: %w", err)
+	}
+	defer amenities.Close()
+	for amenities.Next() {
+		var roomID int
+		var code string
+		if err := amenities.Scan(&roomID, &code); err != nil {
+			return fmt.Errorf("error reading room amenities: %w", err)
+		}
+		rooms[roomID].Amenities = append(rooms[roomID].Amenities, code)
+	}
+	return amenities.Err()
+}
+
+// loadPropertyAmenities mengembalikan kode fasilitas per property_id
+func loadPropertyAmenities(ctx context.Context, q querier, propertyIDs []int) (map[int][]string, error) {
+	result := map[int][]string{}
+	if len(propertyIDs) == 0 {
+		return result, nil
+	}
+	args := make([]interface{}, len(propertyIDs))
+	for i, id := range propertyIDs {
+		args[i] = id
+	}
+	rows, err := q.QueryContext(ctx, `
+		SELECT pa.property_id, a.code
+		FROM property_amenities pa
+		JOIN amenities a ON pa.amenity_id = a.amenity_id
+		WHERE pa.property_id IN (`+placeholders(len(args))+`)
+		ORDER BY pa.property_id, a.code
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching property amenities: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var propertyID int
+		var code string
+		if err := rows.Scan(&propertyID, &code); err != nil {
+			return nil, fmt.Errorf("error reading property amenities: %w", err)
+		}
+		result[propertyID] = append(result[propertyID], code)
+	}
+	return result, rows.Err()
+}
+
+// checkCapacity memeriksa jumlah tamu pada booking_details[i] terhadap
+// kapasitas kamar dikali jumlah kamar yang dipesan. Detail tanpa jumlah tamu
+// (klien lama) dianggap satu dewasa per kamar.
+func checkCapacity(i int, detail BookingDetail, maxAdults, maxChildren int) error {
+	if detail.Adults == 0 && detail.Children == 0 {
+		detail.Adults = detail.Quantity
+	}
+	var v validate.Validator
+	v.Check(detail.Adults <= maxAdults*detail.Quantity, fmt.Sprintf("booking_details[%d].adults", i),
+		fmt.Sprintf("exceeds room capacity of %d adults per room", maxAdults))
+	v.Check(detail.Adults+detail.Children <= (maxAdults+maxChildren)*detail.Quantity, fmt.Sprintf("booking_details[%d].children", i),
+		fmt.Sprintf("exceeds room capacity of %d guests per room", maxAdults+maxChildren))
+	return v.Err()
+}
+
+// amenityConditions membuat satu kondisi per kode: fasilitas harus dimiliki
+// kamar itu sendiri atau propertinya
+func amenityConditions(codes []string) ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	for _, code := range codes {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM amenities a
+			WHERE a.code = ? AND (
+				a.amenity_id IN (SELECT amenity_id FROM room_amenities WHERE room_id = r.room_id) OR
+				a.amenity_id IN (SELECT amenity_id FROM property_amenities WHERE property_id = p.property_id)
+			)
+		)`)
+		args = append(args, code)
+	}
+	return conditions, args
+}
+
+// ListAmenities menampilkan katalog fasilitas untuk filter pencarian dan form kamar/properti
+func ListAmenities(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `SELECT code, name FROM amenities ORDER BY name`)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error listing amenities: %w", err)))
+		return
+	}
+	defer rows.Close()
+
+	amenities := []Amenity{}
+	for rows.Next() {
+		var amenity Amenity
+		if err := rows.Scan(&amenity.Code, &amenity.Name); err != nil {
+			apierror.Write(w, r, apierror.Internal(fmt.Errorf("error reading amenities: %w", err)))
+			return
+		}
+		amenities = append(amenities, amenity)
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error reading amenities: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(amenities)
+}
+
+// UpdateRoomFeatures mengganti kapasitas, ukuran, tempat tidur dan fasilitas kamar
+func UpdateRoomFeatures(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var roomID int
+	if _, err := pathInt(r, "id", &roomID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	var req RoomFeatures
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	propertyID, err := getPropertyIDForRoom(ctx, db, roomID)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.Write(w, r, apierror.NotFound("Room not found"))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching room: %w", err)))
+		return
+	}
+
+	// Staff hanya boleh mengubah kamar pada properti yang ditugaskan kepadanya
+	allowed, err := canManageProperty(ctx, db, r, propertyID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error checking property access: %w", err)))
+		return
+	}
+	if !allowed {
+		apierror.Write(w, r, apierror.Forbidden("Forbidden: You are not assigned to this property"))
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	// Nilai lama dikunci dan dibaca untuk snapshot audit
+	var before RoomFeatures
+	var size sql.NullFloat64
+	err = tx.QueryRowContext(ctx, `SELECT max_adults, max_children, size_sqm FROM rooms WHERE room_id = ? FOR UPDATE`, roomID).
+		Scan(&before.MaxAdults, &before.MaxChildren, &size)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching room: %w", err)))
+		return
+	}
+	before.SizeSqm = nullFloatPtr(size)
+	if err := loadRoomDetails(ctx, tx, map[int]*RoomFeatures{roomID: &before}); err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	if err := setRoomFeatures(ctx, tx, roomID, req); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	err = recordAudit(ctx, tx, r, auditEvent{
+		Action:     auditRoomFeaturesUpdate,
+		EntityType: entityRoom,
+		EntityID:   roomID,
+		Before:     before,
+		After:      req,
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Message: "Room features updated successfully"})
+}
+
+// SetPropertyAmenities mengganti fasilitas properti (misalnya parkir, kolam renang)
+func SetPropertyAmenities(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var propertyID int
+	if _, err := pathInt(r, "id", &propertyID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	var req PropertyAmenitiesRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	// Staff hanya boleh mengubah properti yang ditugaskan kepadanya
+	allowed, err := canManageProperty(ctx, db, r, propertyID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error checking property access: %w", err)))
+		return
+	}
+	if !allowed {
+		apierror.Write(w, r, apierror.Forbidden("Forbidden: You are not assigned to this property"))
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	// Baris properti dikunci agar dua penggantian bersamaan tidak tercampur
+	var exists int
+	err = tx.QueryRowContext(ctx, `SELECT 1 FROM properties WHERE property_id = ? FOR UPDATE`, propertyID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.Write(w, r, apierror.NotFound("Property not found"))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching property: %w", err)))
+		return
+	}
+
+	current, err := loadPropertyAmenities(ctx, tx, []int{propertyID})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	if err := setPropertyAmenities(ctx, tx, propertyID, req.Amenities); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	sort.Strings(req.Amenities)
+	err = recordAudit(ctx, tx, r, auditEvent{
+		Action:     auditPropertyAmenitiesUpdate,
+		EntityType: entityProperty,
+		EntityID:   propertyID,
+		Before:     PropertyAmenitiesRequest{Amenities: current[propertyID]},
+		After:      req,
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Message: "Property amenities updated successfully"})
+}
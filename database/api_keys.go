@@ -0,0 +1,342 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"booking_system_app/apierror"
+	"booking_system_app/middleware"
+	"booking_system_app/validate"
+)
+
+// Struct untuk request penerbitan API key oleh admin
+type CreateAPIKeyRequest struct {
+	UserID    int      `json:"user_id"`
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresAt string   `json:"expires_at,omitempty" format:"date-time"` // RFC 3339, kosong berarti tidak kedaluwarsa
+}
+
+// Struct untuk respons penerbitan API key. Key hanya ditampilkan sekali.
+type CreateAPIKeyResponse struct {
+	APIKeyID int    `json:"api_key_id"`
+	Key      string `json:"key"`
+	Message  string `json:"message"`
+}
+
+// Struct untuk data API key pada daftar admin (tanpa key maupun hash-nya)
+type APIKey struct {
+	APIKeyID   int      `json:"api_key_id"`
+	UserID     int      `json:"user_id"`
+	Name       string   `json:"name"`
+	KeyPrefix  string   `json:"key_prefix"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  *string  `json:"expires_at"`
+	LastUsedAt *string  `json:"last_used_at"`
+	RevokedAt  *string  `json:"revoked_at"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+// Struct untuk respons daftar API key dengan paging
+type APIKeyListResponse struct {
+	APIKeys []APIKey `json:"api_keys"`
+	PageInfo
+}
+
+// apiKeyListSpec: key terbaru lebih dulu
+var apiKeyListSpec = listSpec{
+	sorts:       map[string]sortKey{"id": {"api_key_id", sortNumber}},
+	defaultSort: "-id",
+	idColumn:    "api_key_id",
+}
+
+// Struct untuk request pencabutan API key
+type RevokeAPIKeyRequest struct {
+	APIKeyID int `json:"api_key_id,omitempty"` // diambil dari path pada DELETE /admin/api_keys/{id}
+}
+
+// CreateAPIKey menerbitkan API key baru untuk seorang pengguna (misalnya akun
+// partner OTA atau akun skrip internal). Scope harus dimiliki role pengguna tersebut.
+func CreateAPIKey(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var req CreateAPIKeyRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	if err := req.Validate(time.Now()); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	// expires_at sudah divalidasi sehingga parse tidak mungkin gagal
+	var expiresAt interface{}
+	if req.ExpiresAt != "" {
+		t, _ := time.Parse(time.RFC3339, req.ExpiresAt)
+		expiresAt = t.UTC()
+	}
+
+	var role string
+	err = db.QueryRowContext(ctx, `SELECT role FROM users WHERE user_id = ? AND deleted_at IS NULL`, req.UserID).Scan(&role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			apierror.Write(w, r, apierror.NotFound("User not found"))
+			return
+		}
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching user: %w", err)))
+		return
+	}
+
+	// Scope sudah dikenal sistem (Validate); di sini dicek terhadap role pemilik key
+	var v validate.Validator
+	for i, scope := range req.Scopes {
+		v.Check(middleware.HasPermission(role, scope), fmt.Sprintf("scopes[%d]", i), "is not allowed for role "+role)
+	}
+	if err := v.Err(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	adminID, err := getUserIDByEmail(ctx, db, middleware.UserEmail(r))
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error identifying admin: %w", err)))
+		return
+	}
+
+	secret, _, err := newRandomToken()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error generating API key: %w", err)))
+		return
+	}
+	key := middleware.APIKeyPrefix + secret
+
+	query := `
+		INSERT INTO api_keys (user_id, name, key_prefix, key_hash, scopes, expires_at, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	keyPrefix := key[:len(middleware.APIKeyPrefix)+8]
+	result, err := tx.ExecContext(ctx, query, req.UserID, req.Name, keyPrefix, middleware.HashAPIKey(key),
+		strings.Join(req.Scopes, ","), expiresAt, adminID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error creating API key: %w", err)))
+		return
+	}
+
+	apiKeyID, err := result.LastInsertId()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error retrieving API key ID: %w", err)))
+		return
+	}
+
+	// Hanya prefix yang dicatat, key lengkap tidak pernah disimpan
+	err = recordAudit(ctx, tx, r, auditEvent{
+		Action:     auditAPIKeyCreate,
+		EntityType: entityAPIKey,
+		EntityID:   apiKeyID,
+		After: map[string]interface{}{
+			"user_id":    req.UserID,
+			"name":       req.Name,
+			"key_prefix": keyPrefix,
+			"scopes":     req.Scopes,
+			"expires_at": req.ExpiresAt,
+		},
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateAPIKeyResponse{
+		APIKeyID: int(apiKeyID),
+		Key:      key,
+		Message:  "API key created. Store it now, it will not be shown again",
+	})
+}
+
+// ListAPIKeys menampilkan API key yang sudah diterbitkan, bisa difilter dengan user_id.
+// Sort: -id (default), id.
+func ListAPIKeys(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	list, err := apiKeyListSpec.parse(listOptionsFromQuery(r))
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	var conditions []string
+	var args []interface{}
+	if userID, _ := strconv.Atoi(r.URL.Query().Get("user_id")); userID != 0 {
+		conditions = append(conditions, "user_id = ?")
+		args = append(args, userID)
+	}
+
+	var total int
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM api_keys `+sqlConditions(conditions), args...).Scan(&total)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error counting API keys: %w", err)))
+		return
+	}
+
+	if after, afterArgs := list.where(); after != "" {
+		conditions = append(conditions, after)
+		args = append(args, afterArgs...)
+	}
+	orderBy, limitArgs := list.orderBy()
+	query := `
+		SELECT api_key_id, user_id, name, key_prefix, scopes, expires_at, last_used_at, revoked_at, created_at
+		FROM api_keys
+		` + sqlConditions(conditions) + `
+		` + orderBy
+	rows, err := db.QueryContext(ctx, query, append(args, limitArgs...)...)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error listing API keys: %w", err)))
+		return
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	fetched := 0
+	for rows.Next() {
+		var key APIKey
+		var scopes string
+		var expiresAt, lastUsedAt, revokedAt sql.NullString
+		err := rows.Scan(&key.APIKeyID, &key.UserID, &key.Name, &key.KeyPrefix, &scopes,
+			&expiresAt, &lastUsedAt, &revokedAt, &key.CreatedAt)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(fmt.Errorf("error reading API keys: %w", err)))
+			return
+		}
+		key.Scopes = strings.Split(scopes, ",")
+		key.ExpiresAt = nullStringPtr(expiresAt)
+		key.LastUsedAt = nullStringPtr(lastUsedAt)
+		key.RevokedAt = nullStringPtr(revokedAt)
+		fetched++
+		if fetched <= list.limit {
+			keys = append(keys, key)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error reading API keys: %w", err)))
+		return
+	}
+
+	page := list.pageInfo(total, fetched, func() (interface{}, int64) {
+		last := keys[len(keys)-1]
+		return last.APIKeyID, int64(last.APIKeyID)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIKeyListResponse{APIKeys: keys, PageInfo: page})
+}
+
+// RevokeAPIKey mencabut API key sehingga langsung ditolak oleh middleware
+func RevokeAPIKey(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var req RevokeAPIKeyRequest
+
+	// DELETE /admin/api_keys/{id} tidak punya body, route lama mengirim api_key_id di body
+	fromPath, err := pathInt(r, "id", &req.APIKeyID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if !fromPath {
+		err = decodeJSON(w, r, &req)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE api_keys SET revoked_at = NOW() WHERE api_key_id = ? AND revoked_at IS NULL`, req.APIKeyID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error revoking API key: %w", err)))
+		return
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error revoking API key: %w", err)))
+		return
+	}
+	if affected == 0 {
+		apierror.Write(w, r, apierror.NotFound("API key not found or already revoked"))
+		return
+	}
+
+	// Nilai audit diambil dari database agar sama persis dengan yang tersimpan
+	var revokedAt time.Time
+	err = tx.QueryRowContext(ctx, `SELECT revoked_at FROM api_keys WHERE api_key_id = ?`, req.APIKeyID).Scan(&revokedAt)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching API key: %w", err)))
+		return
+	}
+
+	err = recordAudit(ctx, tx, r, auditEvent{
+		Action:     auditAPIKeyRevoke,
+		EntityType: entityAPIKey,
+		EntityID:   req.APIKeyID,
+		Before:     map[string]interface{}{"revoked_at": nil},
+		After:      map[string]string{"revoked_at": revokedAt.UTC().Format(time.RFC3339)},
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Message: "API key revoked successfully"})
+}
+
+// nullStringPtr mengubah sql.NullString menjadi pointer agar NULL tampil sebagai null di JSON
+func nullStringPtr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
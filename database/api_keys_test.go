@@ -0,0 +1,87 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"booking_system_app/middleware"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+const revokeAPIKeyUpdate = `UPDATE api_keys SET revoked_at = NOW\(\) WHERE api_key_id = \? AND revoked_at IS NULL`
+
+func TestRevokeAPIKey(t *testing.T) {
+	revokedAt := time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+
+	t.Run("audits the stored revocation time", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectAuth(mock, "admin@example.com", RoleAdmin)
+		mock.ExpectBegin()
+		mock.ExpectExec(revokeAPIKeyUpdate).WithArgs(9).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`SELECT revoked_at FROM api_keys WHERE api_key_id = \?`).
+			WithArgs(9).
+			WillReturnRows(sqlmock.NewRows([]string{"revoked_at"}).AddRow(revokedAt))
+		after, err := json.Marshal(map[string]string{"revoked_at": "2026-10-18T09:30:00Z"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		mock.ExpectExec(`INSERT INTO audit_logs`).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, auditAPIKeyRevoke, entityAPIKey, "9",
+				sqlmock.AnyArg(), string(after), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		req := newAuthRequest(t, http.MethodDelete, "/admin/api_keys/9", "", "admin@example.com")
+		req.SetPathValue("id", "9")
+		rec := serveWith(db, middleware.PermUserManage, RevokeAPIKey, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+		}
+	})
+
+	t.Run("already revoked", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectAuth(mock, "admin@example.com", RoleAdmin)
+		mock.ExpectBegin()
+		mock.ExpectExec(revokeAPIKeyUpdate).WithArgs(9).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		req := newAuthRequest(t, http.MethodDelete, "/admin/api_keys", `{"api_key_id": 9}`, "admin@example.com")
+		rec := serveWith(db, middleware.PermUserManage, RevokeAPIKey, req)
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusNotFound, rec.Body)
+		}
+	})
+
+	t.Run("rows affected error", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectAuth(mock, "admin@example.com", RoleAdmin)
+		mock.ExpectBegin()
+		mock.ExpectExec(revokeAPIKeyUpdate).WithArgs(9).WillReturnResult(sqlmock.NewErrorResult(errors.New("driver: RowsAffected not supported")))
+		mock.ExpectRollback()
+
+		req := newAuthRequest(t, http.MethodDelete, "/admin/api_keys", `{"api_key_id": 9}`, "admin@example.com")
+		rec := serveWith(db, middleware.PermUserManage, RevokeAPIKey, req)
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusInternalServerError, rec.Body)
+		}
+	})
+
+	// Route lama membawa ID di body, nilai tidak valid ditolak sebelum menyentuh database
+	for _, body := range []string{`{}`, `{"api_key_id": 0}`, `{"api_key_id": -3}`} {
+		t.Run("legacy body "+body, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			expectAuth(mock, "admin@example.com", RoleAdmin)
+
+			req := newAuthRequest(t, http.MethodDelete, "/admin/api_keys", body, "admin@example.com")
+			rec := serveWith(db, middleware.PermUserManage, RevokeAPIKey, req)
+			if rec.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusUnprocessableEntity, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,349 @@
+package database
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"booking_system_app/apierror"
+	"booking_system_app/logging"
+	"booking_system_app/middleware"
+	"booking_system_app/validate"
+)
+
+// Aksi yang dicatat di audit_logs, dengan format <entitas>.<aksi>
+const (
+	auditPropertyCreate          = "property.create"
+	auditPropertyLocationUpdate  = "property.location_update"
+	auditPropertyAmenitiesUpdate = "property.amenities_update"
+	auditRoomCreate              = "room.create"
+	auditRoomStatusUpdate        = "room.status_update"
+	auditRoomFeaturesUpdate      = "room.features_update"
+	auditPhotoUpload             = "photo.upload"
+	auditPhotoUpdate             = "photo.update"
+	auditPhotoDelete             = "photo.delete"
+	auditBookingCreate           = "booking.create"
+	auditBookingCancel           = "booking.cancel"
+	auditBookingComplete         = "booking.complete"
+	auditReviewCreate            = "review.create"
+	auditReviewReply             = "review.reply"
+	auditReviewModerate          = "review.moderate"
+	auditUserRegister            = "user.register"
+	auditUserCreate              = "user.create"
+	auditUserRoleChange          = "user.role_change"
+	auditUserStatusChange        = "user.status_change"
+	auditStaffAssign             = "staff.assign"
+	auditStaffUnassign           = "staff.unassign"
+	auditAPIKeyCreate            = "api_key.create"
+	auditAPIKeyRevoke            = "api_key.revoke"
+	auditProfileUpdate           = "profile.update"
+	auditPasswordChange          = "password.change"
+	auditPasswordReset           = "password.reset"
+	auditEmailChange             = "email.change"
+	auditEmailVerify             = "email.verify"
+	auditAccountDelete           = "account.delete"
+	auditTwoFactorEnable         = "two_factor.enable"
+	auditTwoFactorDisable        = "two_factor.disable"
+	auditRecoveryCodesRotate     = "two_factor.recovery_codes_regenerate"
+)
+
+// Jenis entitas yang dicatat di audit_logs
+const (
+	entityProperty        = "property"
+	entityRoom            = "room"
+	entityBooking         = "booking"
+	entityUser            = "user"
+	entityStaffAssignment = "staff_assignment"
+	entityAPIKey          = "api_key"
+	entityPhoto           = "photo"
+	entityReview          = "review"
+)
+
+// auditEvent adalah satu perubahan yang akan dicatat. Before/After berupa
+// nilai apa pun yang bisa di-encode ke JSON; nil berarti tidak ada snapshot.
+type auditEvent struct {
+	Action     string
+	EntityType string
+	EntityID   interface{}
+	Before     interface{}
+	After      interface{}
+}
+
+// recordAudit menulis auditEvent di dalam transaksi tx sehingga catatan audit
+// ikut di-rollback jika perubahannya gagal. Aktor diambil dari principal yang
+// disimpan middleware; untuk route publik aktor bisa diisi lewat actorEmail.
+func recordAudit(ctx context.Context, tx *sql.Tx, r *http.Request, event auditEvent) error {
+	return recordAuditAs(ctx, tx, r, middleware.UserEmail(r), event)
+}
+
+// recordAuditAs sama dengan recordAudit tetapi dengan email aktor eksplisit,
+// dipakai pada route publik (registrasi, verifikasi email, reset password)
+func recordAuditAs(ctx context.Context, tx *sql.Tx, r *http.Request, actorEmail string, event auditEvent) error {
+	before, err := auditSnapshot(event.Before)
+	if err != nil {
+		return err
+	}
+	after, err := auditSnapshot(event.After)
+	if err != nil {
+		return err
+	}
+
+	var entityID interface{}
+	if event.EntityID != nil {
+		entityID = fmt.Sprint(event.EntityID)
+	}
+
+	var actor, apiKeyID interface{}
+	if actorEmail != "" {
+		actor = actorEmail
+	}
+	if principal := middleware.CurrentPrincipal(r); principal != nil && principal.APIKeyID != 0 {
+		apiKeyID = principal.APIKeyID
+	}
+
+	query := `
+		INSERT INTO audit_logs (actor_user_id, actor_email, actor_api_key_id, action, entity_type, entity_id,
+		                        before_data, after_data, ip_address, request_id)
+		VALUES ((SELECT user_id FROM users WHERE email = ?), ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err = tx.ExecContext(ctx, query, actor, actor, apiKeyID, event.Action, event.EntityType, entityID,
+		before, after, clientIP(r), middleware.RequestIDFromRequest(r))
+	if err != nil {
+		return fmt.Errorf("error recording audit log: %w", err)
+	}
+	return nil
+}
+
+// recordAuditForUser dipakai pada route publik berbasis token (verifikasi email,
+// reset password): aktornya adalah pemilik akun yang dibuktikan oleh token
+func recordAuditForUser(ctx context.Context, tx *sql.Tx, r *http.Request, userID int, event auditEvent) error {
+	var email string
+	err := tx.QueryRowContext(ctx, `SELECT email FROM users WHERE user_id = ?`, userID).Scan(&email)
+	if err != nil {
+		return fmt.Errorf("error fetching audit actor: %w", err)
+	}
+	return recordAuditAs(ctx, tx, r, email, event)
+}
+
+// auditSnapshot meng-encode nilai ke JSON dengan field sensitif diredaksi
+func auditSnapshot(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(logging.Redact(value))
+	if err != nil {
+		return nil, fmt.Errorf("error encoding audit snapshot: %w", err)
+	}
+	return string(data), nil
+}
+
+// piiDigest menggantikan data pribadi (nama, email, nomor telepon) di snapshot
+// audit. audit_logs bersifat append-only sehingga nilai aslinya tidak bisa
+// dihapus saat akun dihapus; digest HMAC hanya bisa dicocokkan oleh pihak yang
+// sudah mengetahui nilai aslinya dan memegang secret key.
+func piiDigest(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, secretKey)
+	mac.Write([]byte(value))
+	return "hmac:" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// userSnapshot adalah data akun yang dicatat di audit, tanpa password dan
+// dengan data pribadi diganti piiDigest
+func userSnapshot(name, email, phone, role string) map[string]string {
+	return map[string]string{"name": piiDigest(name), "email": piiDigest(email), "phone_number": piiDigest(phone), "role": role}
+}
+
+// profileSnapshot mencatat field profil yang dikirim (bukan nil) sebagai piiDigest
+func profileSnapshot(name, phone *string) map[string]string {
+	snapshot := map[string]string{}
+	if name != nil {
+		snapshot["name"] = piiDigest(*name)
+	}
+	if phone != nil {
+		snapshot["phone_number"] = piiDigest(*phone)
+	}
+	return snapshot
+}
+
+// AuditLog adalah satu baris audit_logs untuk endpoint admin
+type AuditLog struct {
+	AuditID       int64           `json:"audit_id"`
+	ActorUserID   *int            `json:"actor_user_id"`
+	ActorEmail    *string         `json:"actor_email"`
+	ActorAPIKeyID *int            `json:"actor_api_key_id,omitempty"`
+	Action        string          `json:"action"`
+	EntityType    string          `json:"entity_type"`
+	EntityID      *string         `json:"entity_id"`
+	Before        json.RawMessage `json:"before,omitempty"`
+	After         json.RawMessage `json:"after,omitempty"`
+	IPAddress     *string         `json:"ip_address"`
+	RequestID     *string         `json:"request_id"`
+	CreatedAt     string          `json:"created_at"`
+}
+
+// Struct untuk respons daftar audit log dengan paging
+type AuditLogListResponse struct {
+	Logs []AuditLog `json:"logs"`
+	PageInfo
+}
+
+// auditLogListSpec: audit_id naik sesuai urutan penulisan sehingga cukup
+// menjadi satu-satunya kunci sort
+var auditLogListSpec = listSpec{
+	sorts:       map[string]sortKey{"id": {"audit_id", sortNumber}},
+	defaultSort: "-id",
+	idColumn:    "audit_id",
+}
+
+// ListAuditLogs menampilkan audit log terbaru lebih dulu. Filter opsional lewat
+// query string: actor (email), actor_user_id, action, entity_type, entity_id,
+// request_id, from dan to (RFC 3339 atau YYYY-MM-DD). Sort: -id (default), id.
+func ListAuditLogs(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	list, err := auditLogListSpec.parse(listOptionsFromQuery(r))
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	q := r.URL.Query()
+
+	var conditions []string
+	var args []interface{}
+	for _, filter := range []struct{ param, column string }{
+		{"actor", "actor_email"},
+		{"action", "action"},
+		{"entity_type", "entity_type"},
+		{"entity_id", "entity_id"},
+		{"request_id", "request_id"},
+	} {
+		if value := q.Get(filter.param); value != "" {
+			conditions = append(conditions, filter.column+" = ?")
+			args = append(args, value)
+		}
+	}
+	if value := q.Get("actor_user_id"); value != "" {
+		actorID, err := strconv.Atoi(value)
+		if err != nil {
+			apierror.Write(w, r, apierror.BadRequest("Invalid actor_user_id"))
+			return
+		}
+		conditions = append(conditions, "actor_user_id = ?")
+		args = append(args, actorID)
+	}
+	for _, bound := range []struct{ param, op string }{{"from", ">="}, {"to", "<"}} {
+		value := q.Get(bound.param)
+		if value == "" {
+			continue
+		}
+		t, dateOnly, err := parseAuditTime(value)
+		if err != nil {
+			apierror.Write(w, r, apierror.BadRequest("Invalid "+bound.param+", use RFC 3339 or YYYY-MM-DD"))
+			return
+		}
+		// Tanggal pada "to" bersifat inklusif: seluruh hari tersebut ikut disertakan
+		if dateOnly && bound.param == "to" {
+			t = t.AddDate(0, 0, 1)
+		}
+		conditions = append(conditions, "created_at "+bound.op+" ?")
+		args = append(args, t)
+	}
+
+	var total int
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_logs `+sqlConditions(conditions), args...).Scan(&total)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error counting audit logs: %w", err)))
+		return
+	}
+
+	if after, afterArgs := list.where(); after != "" {
+		conditions = append(conditions, after)
+		args = append(args, afterArgs...)
+	}
+	orderBy, limitArgs := list.orderBy()
+	query := `
+		SELECT audit_id, actor_user_id, actor_email, actor_api_key_id, action, entity_type, entity_id,
+		       before_data, after_data, ip_address, request_id, created_at
+		FROM audit_logs ` + sqlConditions(conditions) + `
+		` + orderBy
+	rows, err := db.QueryContext(ctx, query, append(args, limitArgs...)...)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error listing audit logs: %w", err)))
+		return
+	}
+	defer rows.Close()
+
+	logs := []AuditLog{}
+	fetched := 0
+	for rows.Next() {
+		var entry AuditLog
+		var actorUserID, apiKeyID sql.NullInt64
+		var actorEmail, entityID, before, after, ip, requestID sql.NullString
+		err := rows.Scan(&entry.AuditID, &actorUserID, &actorEmail, &apiKeyID, &entry.Action, &entry.EntityType,
+			&entityID, &before, &after, &ip, &requestID, &entry.CreatedAt)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(fmt.Errorf("error reading audit logs: %w", err)))
+			return
+		}
+		entry.ActorUserID = nullIntPtr(actorUserID)
+		entry.ActorAPIKeyID = nullIntPtr(apiKeyID)
+		entry.ActorEmail = nullStringPtr(actorEmail)
+		entry.EntityID = nullStringPtr(entityID)
+		entry.IPAddress = nullStringPtr(ip)
+		entry.RequestID = nullStringPtr(requestID)
+		if before.Valid {
+			entry.Before = json.RawMessage(before.String)
+		}
+		if after.Valid {
+			entry.After = json.RawMessage(after.String)
+		}
+		fetched++
+		if fetched <= list.limit {
+			logs = append(logs, entry)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error reading audit logs: %w", err)))
+		return
+	}
+
+	page := list.pageInfo(total, fetched, func() (interface{}, int64) {
+		last := logs[len(logs)-1]
+		return last.AuditID, last.AuditID
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AuditLogListResponse{Logs: logs, PageInfo: page})
+}
+
+// parseAuditTime menerima timestamp RFC 3339 atau tanggal YYYY-MM-DD.
+// dateOnly bernilai true untuk format tanggal saja.
+func parseAuditTime(value string) (t time.Time, dateOnly bool, err error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err = time.Parse(validate.DateLayout, value)
+	return t, true, err
+}
+
+// nullIntPtr mengubah sql.NullInt64 menjadi *int agar NULL dikirim sebagai null
+func nullIntPtr(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+	v := int(value.Int64)
+	return &v
+}
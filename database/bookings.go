@@ -0,0 +1,300 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"booking_system_app/apierror"
+	"booking_system_app/middleware"
+	"booking_system_app/validate"
+)
+
+// Status booking mengikuti enum kolom bookings.status
+const (
+	BookingPending   = "pending"
+	BookingConfirmed = "confirmed"
+	BookingCancelled = "cancelled"
+	BookingCompleted = "completed"
+)
+
+// Booking adalah detail satu pemesanan kamar
+type Booking struct {
+	BookingID    int     `json:"booking_id"`
+	UserID       int     `json:"user_id"`
+	RoomID       int     `json:"room_id"`
+	RoomName     string  `json:"room_name"`
+	PropertyID   int     `json:"property_id"`
+	PropertyName string  `json:"property_name"`
+	CheckInDate  string  `json:"check_in_date" format:"date"`
+	CheckOutDate string  `json:"check_out_date" format:"date"`
+	TotalPrice   float64 `json:"total_price"`
+	Status       string  `json:"status"`
+	CreatedAt    string  `json:"created_at"`
+}
+
+// rowQuerier dipenuhi *sql.DB dan *sql.Tx
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// queryBooking membaca booking beserta kamar dan propertinya. Di dalam
+// transaksi suffix diisi "FOR UPDATE" untuk mengunci baris booking.
+func queryBooking(ctx context.Context, q rowQuerier, bookingID int, suffix string) (Booking, error) {
+	query := `
+		SELECT b.booking_id, b.user_id, b.room_id, r.room_name, p.property_id, p.name,
+		       b.check_in_date, b.check_out_date, b.total_price, b.status, b.created_at
+		FROM bookings b
+		JOIN rooms r ON b.room_id = r.room_id
+		JOIN properties p ON r.property_id = p.property_id
+		WHERE b.booking_id = ?
+	` + suffix
+	var booking Booking
+	var checkIn, checkOut time.Time
+	err := q.QueryRowContext(ctx, query, bookingID).Scan(&booking.BookingID, &booking.UserID, &booking.RoomID,
+		&booking.RoomName, &booking.PropertyID, &booking.PropertyName, &checkIn, &checkOut,
+		&booking.TotalPrice, &booking.Status, &booking.CreatedAt)
+	if err != nil {
+		return booking, err
+	}
+	booking.CheckInDate = checkIn.Format(validate.DateLayout)
+	booking.CheckOutDate = checkOut.Format(validate.DateLayout)
+	return booking, nil
+}
+
+// errRoomUnavailable menandai kamar yang sudah dipesan pada tanggal yang beririsan
+var errRoomUnavailable = errors.New("room already booked for overlapping dates")
+
+// checkRoomAvailable memastikan tidak ada booking aktif pada kamar yang
+// beririsan dengan [checkIn, checkOut). Baris kamar harus sudah dikunci
+// FOR UPDATE di tx agar dua booking bersamaan tidak sama-sama lolos.
+func checkRoomAvailable(ctx context.Context, tx *sql.Tx, roomID int, checkIn, checkOut string) error {
+	var count int
+	query := `
+		SELECT COUNT(*) FROM bookings
+		WHERE room_id = ? AND status <> ? AND check_in_date < ? AND check_out_date > ?
+	`
+	err := tx.QueryRowContext(ctx, query, roomID, BookingCancelled, checkOut, checkIn).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("error checking room availability: %w", err)
+	}
+	if count > 0 {
+		e := apierror.Conflict(fmt.Sprintf("Room ID %d is not available for the requested dates", roomID))
+		e.Cause = errRoomUnavailable
+		return e
+	}
+	return nil
+}
+
+// canAccessBooking mengizinkan pemilik booking, admin, dan staff yang
+// ditugaskan ke properti kamar tersebut
+func canAccessBooking(ctx context.Context, q rowQuerier, r *http.Request, booking Booking) (bool, error) {
+	userID, err := getUserIDByEmail(ctx, q, middleware.UserEmail(r))
+	if err != nil {
+		return false, err
+	}
+	if booking.UserID == userID {
+		return true, nil
+	}
+	return canManageProperty(ctx, q, r, booking.PropertyID)
+}
+
+// GetBooking menampilkan detail satu booking. Booking milik orang lain
+// dijawab 404 agar ID booking tidak bisa ditebak.
+func GetBooking(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var bookingID int
+	if _, err := pathInt(r, "id", &bookingID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	booking, err := queryBooking(ctx, db, bookingID, "")
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.Write(w, r, apierror.NotFound("Booking not found"))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching booking: %w", err)))
+		return
+	}
+
+	allowed, err := canAccessBooking(ctx, db, r, booking)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error checking booking access: %w", err)))
+		return
+	}
+	if !allowed {
+		apierror.Write(w, r, apierror.NotFound("Booking not found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(booking)
+}
+
+// CancelBooking membatalkan booking yang masih pending/confirmed dan belum
+// melewati tanggal check-in
+func CancelBooking(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var bookingID int
+	if _, err := pathInt(r, "id", &bookingID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	// Baris dikunci agar dua pembatalan bersamaan tidak sama-sama lolos
+	booking, err := queryBooking(ctx, tx, bookingID, "FOR UPDATE")
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.Write(w, r, apierror.NotFound("Booking not found"))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching booking: %w", err)))
+		return
+	}
+
+	allowed, err := canAccessBooking(ctx, tx, r, booking)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error checking booking access: %w", err)))
+		return
+	}
+	if !allowed {
+		apierror.Write(w, r, apierror.NotFound("Booking not found"))
+		return
+	}
+
+	if booking.Status != BookingPending && booking.Status != BookingConfirmed {
+		apierror.Write(w, r, apierror.Conflict("Booking with status "+booking.Status+" cannot be cancelled"))
+		return
+	}
+	checkIn, _ := time.Parse(validate.DateLayout, booking.CheckInDate)
+	now := time.Now()
+	if checkIn.Before(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)) {
+		apierror.Write(w, r, apierror.Conflict("Booking cannot be cancelled after check-in date"))
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE bookings SET status = ? WHERE booking_id = ?`, BookingCancelled, bookingID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error cancelling booking: %w", err)))
+		return
+	}
+
+	err = recordAudit(ctx, tx, r, auditEvent{
+		Action:     auditBookingCancel,
+		EntityType: entityBooking,
+		EntityID:   bookingID,
+		Before:     map[string]string{"status": booking.Status},
+		After:      map[string]string{"status": BookingCancelled},
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+	bookingsTotal.WithLabelValues("cancelled", "").Inc()
+
+	booking.Status = BookingCancelled
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(booking)
+}
+
+// CompleteBooking menandai booking selesai setelah tamu check-out, sehingga
+// tamu bisa menulis ulasan. Hanya untuk staff properti tersebut dan admin.
+func CompleteBooking(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var bookingID int
+	if _, err := pathInt(r, "id", &bookingID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	booking, err := queryBooking(ctx, tx, bookingID, "FOR UPDATE")
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.Write(w, r, apierror.NotFound("Booking not found"))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching booking: %w", err)))
+		return
+	}
+
+	allowed, err := canManageProperty(ctx, tx, r, booking.PropertyID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error checking property access: %w", err)))
+		return
+	}
+	if !allowed {
+		apierror.Write(w, r, apierror.Forbidden("Forbidden: You are not assigned to this property"))
+		return
+	}
+
+	if booking.Status != BookingPending && booking.Status != BookingConfirmed {
+		apierror.Write(w, r, apierror.Conflict("Booking with status "+booking.Status+" cannot be completed"))
+		return
+	}
+	checkOut, _ := time.Parse(validate.DateLayout, booking.CheckOutDate)
+	now := time.Now()
+	if checkOut.After(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)) {
+		apierror.Write(w, r, apierror.Conflict("Booking cannot be completed before check-out date"))
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE bookings SET status = ? WHERE booking_id = ?`, BookingCompleted, bookingID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing booking: %w", err)))
+		return
+	}
+
+	err = recordAudit(ctx, tx, r, auditEvent{
+		Action:     auditBookingComplete,
+		EntityType: entityBooking,
+		EntityID:   bookingID,
+		Before:     map[string]string{"status": booking.Status},
+		After:      map[string]string{"status": BookingCompleted},
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	booking.Status = BookingCompleted
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(booking)
+}
@@ -0,0 +1,177 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"booking_system_app/middleware"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const (
+	bookingCustomerQuery = `SELECT user_id FROM users WHERE email = \?`
+	bookingRoomLock      = `SELECT price_per_night, max_adults, max_children FROM rooms WHERE room_id = \? FOR UPDATE`
+	bookingOverlapQuery  = `SELECT COUNT\(\*\) FROM bookings\s+WHERE room_id = \? AND status <> \? AND check_in_date < \? AND check_out_date > \?`
+)
+
+// bookingBody membuat body POST /bookings untuk satu kamar, dua malam mulai pekan depan
+func bookingBody(roomID, quantity, adults, children int) (body, checkIn, checkOut string) {
+	start := time.Now().AddDate(0, 0, 7)
+	checkIn = start.Format("2006-01-02")
+	checkOut = start.AddDate(0, 0, 2).Format("2006-01-02")
+	body = fmt.Sprintf(`{
+		"check_in_date": %q,
+		"check_out_date": %q,
+		"booking_details": [{"room_id": %d, "quantity": %d, "adults": %d, "children": %d}],
+		"payment_details": {"payment_method": "credit_card", "total_amount": 200}
+	}`, checkIn, checkOut, roomID, quantity, adults, children)
+	return body, checkIn, checkOut
+}
+
+// expectRoomLocked mendaftarkan awal transaksi sampai baris kamar dikunci
+func expectRoomLocked(mock sqlmock.Sqlmock, roomID int, price float64, maxAdults, maxChildren int) {
+	expectAuth(mock, "guest@example.com", RoleCustomer)
+	mock.ExpectBegin()
+	mock.ExpectQuery(bookingCustomerQuery).
+		WithArgs("guest@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(5))
+	mock.ExpectQuery(bookingRoomLock).
+		WithArgs(roomID).
+		WillReturnRows(sqlmock.NewRows([]string{"price_per_night", "max_adults", "max_children"}).AddRow(price, maxAdults, maxChildren))
+}
+
+func TestBookRoom(t *testing.T) {
+	db, mock := newMockDB(t)
+	body, checkIn, checkOut := bookingBody(3, 1, 2, 1)
+	expectRoomLocked(mock, 3, 100, 2, 1)
+	mock.ExpectQuery(bookingOverlapQuery).
+		WithArgs(3, BookingCancelled, checkOut, checkIn).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(`INSERT INTO bookings \(user_id, room_id, check_in_date, check_out_date, total_price\)`).
+		WithArgs(5, 3, checkIn, checkOut, 200.0).
+		WillReturnResult(sqlmock.NewResult(41, 1))
+	mock.ExpectExec(`INSERT INTO audit_logs`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO payments`).
+		WithArgs(41, "credit_card", "completed", sqlmock.AnyArg(), 200.0).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	req := newAuthRequest(t, http.MethodPost, "/bookings", body, "guest@example.com")
+	rec := serveWith(db, middleware.PermBookingCreate, BookRoom, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+	var resp BookingResponse
+	decodeBody(t, rec, &resp)
+	if len(resp.BookingIDs) != 1 || resp.BookingIDs[0] != 41 || resp.TotalPrice != 200 {
+		t.Errorf("response = %+v, want booking 41 totalling 200", resp)
+	}
+}
+
+func TestBookRoomOverlap(t *testing.T) {
+	db, mock := newMockDB(t)
+	body, checkIn, checkOut := bookingBody(3, 1, 0, 0)
+	expectRoomLocked(mock, 3, 100, 2, 0)
+	mock.ExpectQuery(bookingOverlapQuery).
+		WithArgs(3, BookingCancelled, checkOut, checkIn).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectRollback()
+
+	counter := bookingsTotal.WithLabelValues("failed", "overlap")
+	before := testutil.ToFloat64(counter)
+
+	req := newAuthRequest(t, http.MethodPost, "/bookings", body, "guest@example.com")
+	rec := serveWith(db, middleware.PermBookingCreate, BookRoom, req)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body)
+	}
+	if code := errorCode(t, rec); code != "conflict" {
+		t.Errorf("error code = %q, want conflict", code)
+	}
+	if got := testutil.ToFloat64(counter) - before; got != 1 {
+		t.Errorf("failed/overlap counter increased by %v, want 1", got)
+	}
+}
+
+func TestBookRoomCapacity(t *testing.T) {
+	// Kamar untuk 2 dewasa + 1 anak
+	tests := []struct {
+		name                       string
+		quantity, adults, children int
+	}{
+		{"too many adults", 1, 3, 0},
+		{"too many guests", 1, 2, 2},
+		{"capacity multiplies by quantity", 2, 5, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			body, _, _ := bookingBody(3, tt.quantity, tt.adults, tt.children)
+			expectRoomLocked(mock, 3, 100, 2, 1)
+			mock.ExpectRollback()
+
+			req := newAuthRequest(t, http.MethodPost, "/bookings", body, "guest@example.com")
+			rec := serveWith(db, middleware.PermBookingCreate, BookRoom, req)
+			if rec.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusUnprocessableEntity, rec.Body)
+			}
+		})
+	}
+}
+
+func TestBookRoomUnknownRoom(t *testing.T) {
+	db, mock := newMockDB(t)
+	body, _, _ := bookingBody(99, 1, 1, 0)
+	expectAuth(mock, "guest@example.com", RoleCustomer)
+	mock.ExpectBegin()
+	mock.ExpectQuery(bookingCustomerQuery).
+		WithArgs("guest@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(5))
+	mock.ExpectQuery(bookingRoomLock).WithArgs(99).WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	req := newAuthRequest(t, http.MethodPost, "/bookings", body, "guest@example.com")
+	rec := serveWith(db, middleware.PermBookingCreate, BookRoom, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusNotFound, rec.Body)
+	}
+}
+
+func TestBookRoomValidation(t *testing.T) {
+	past := time.Now().AddDate(0, 0, -3).Format("2006-01-02")
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	tests := map[string]string{
+		"check-in in the past": fmt.Sprintf(`{"check_in_date": %q, "check_out_date": %q,
+			"booking_details": [{"room_id": 3, "quantity": 1}],
+			"payment_details": {"payment_method": "cash", "total_amount": 100}}`, past, tomorrow),
+		"no rooms": fmt.Sprintf(`{"check_in_date": %q, "check_out_date": %q,
+			"booking_details": [],
+			"payment_details": {"payment_method": "cash", "total_amount": 100}}`, tomorrow, tomorrow),
+		"unknown payment method": fmt.Sprintf(`{"check_in_date": %q, "check_out_date": %q,
+			"booking_details": [{"room_id": 3, "quantity": 1}],
+			"payment_details": {"payment_method": "barter", "total_amount": 100}}`, tomorrow, tomorrow),
+	}
+
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Request tidak valid ditolak sebelum transaksi dimulai
+			db, mock := newMockDB(t)
+			expectAuth(mock, "guest@example.com", RoleCustomer)
+
+			req := newAuthRequest(t, http.MethodPost, "/bookings", body, "guest@example.com")
+			rec := serveWith(db, middleware.PermBookingCreate, BookRoom, req)
+			if rec.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusUnprocessableEntity, rec.Body)
+			}
+			if code := errorCode(t, rec); code != "validation_failed" {
+				t.Errorf("error code = %q, want validation_failed", code)
+			}
+		})
+	}
+}
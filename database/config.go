@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"booking_system_app/blobstore"
+	"booking_system_app/notify"
+)
+
+// mailer dipakai untuk mengirim email verifikasi dan reset password.
+// Default-nya MemoryMailer sehingga tidak ada email yang keluar sebelum dikonfigurasi.
+var mailer notify.Mailer = notify.NewMemoryMailer()
+
+// requireEmailVerification menentukan apakah akun yang belum verifikasi email boleh login
+var requireEmailVerification = false
+
+// require2FAForElevatedRoles mewajibkan 2FA untuk role staff dan admin.
+// Nonaktif secara default: saat diaktifkan, staff dan admin yang belum
+// mendaftar TOTP harus enrollment pada login berikutnya (lihat /login/2fa/enroll).
+var require2FAForElevatedRoles = false
+
+// SetMailer mengganti mailer yang dipakai oleh handler
+func SetMailer(m notify.Mailer) {
+	mailer = m
+}
+
+// blobs menyimpan file foto. Default-nya MemoryStore sehingga foto hilang
+// saat restart sampai dikonfigurasi dengan SetBlobStore.
+var blobs blobstore.BlobStore = blobstore.NewMemoryStore()
+
+// maxPhotoBytes adalah ukuran maksimum satu file foto yang diunggah
+var maxPhotoBytes int64 = 10 << 20
+
+// SetBlobStore mengganti penyimpanan file foto
+func SetBlobStore(store blobstore.BlobStore) {
+	blobs = store
+}
+
+// SetMaxPhotoBytes mengatur ukuran maksimum foto, nilai <= 0 diabaikan
+func SetMaxPhotoBytes(n int64) {
+	if n > 0 {
+		maxPhotoBytes = n
+	}
+}
+
+// SetRequireEmailVerification mengaktifkan atau menonaktifkan blokir login untuk akun yang belum verifikasi
+func SetRequireEmailVerification(required bool) {
+	requireEmailVerification = required
+}
+
+// SetRequire2FAForElevatedRoles mengatur kebijakan wajib 2FA untuk staff dan admin
+func SetRequire2FAForElevatedRoles(required bool) {
+	require2FAForElevatedRoles = required
+}
+
+// maxSessionAge adalah umur maksimum sesi sejak login; setelah itu token
+// tidak bisa di-refresh dan pengguna harus login ulang
+var maxSessionAge = 7 * 24 * time.Hour
+
+// SetMaxSessionAge mengatur umur maksimum sesi, nilai <= 0 diabaikan
+func SetMaxSessionAge(age time.Duration) {
+	if age > 0 {
+		maxSessionAge = age
+	}
+}
+
+// Batas waktu operasi database per request. queryTimeout dipakai handler biasa,
+// txTimeout untuk handler dengan transaksi panjang seperti BookRoom.
+var (
+	queryTimeout = 5 * time.Second
+	txTimeout    = 15 * time.Second
+)
+
+// SetQueryTimeouts mengatur batas waktu operasi database, nilai <= 0 diabaikan
+func SetQueryTimeouts(query, tx time.Duration) {
+	if query > 0 {
+		queryTimeout = query
+	}
+	if tx > 0 {
+		txTimeout = tx
+	}
+}
+
+// requestContext menurunkan context request dengan deadline timeout. Context ikut
+// dibatalkan jika klien memutus koneksi sehingga query yang berjalan dihentikan.
+func requestContext(r *http.Request, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(r.Context(), timeout)
+}
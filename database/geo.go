@@ -0,0 +1,193 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"booking_system_app/apierror"
+)
+
+// earthRadiusKM adalah jari-jari rata-rata bumi untuk rumus haversine
+const earthRadiusKM = 6371.0
+
+// kmPerDegreeLatitude dipakai untuk menyaring kotak lintang sebelum jarak dihitung
+const kmPerDegreeLatitude = 111.045
+
+// GeoPoint adalah titik pusat pencarian berdasarkan jarak
+type GeoPoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// GeoBounds adalah area peta yang sedang terlihat. West lebih besar dari
+// East berarti area melewati garis bujur 180°.
+type GeoBounds struct {
+	North float64 `json:"north"`
+	South float64 `json:"south"`
+	East  float64 `json:"east"`
+	West  float64 `json:"west"`
+}
+
+// Struct untuk request perubahan koordinat properti. Keduanya null
+// berarti koordinat dihapus.
+type PropertyLocation struct {
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+}
+
+// distanceSQL mengembalikan ekspresi SQL jarak (km) dari properti p ke titik.
+// Koordinat sudah divalidasi sebagai angka sehingga aman disisipkan langsung;
+// dengan begitu ekspresi yang sama bisa dipakai di SELECT, WHERE dan ORDER BY.
+func distanceSQL(point GeoPoint) string {
+	// Dibungkus kurung agar nilai negatif tidak menjadi "--" (komentar SQL)
+	lat := "(" + strconv.FormatFloat(point.Latitude, 'f', -1, 64) + ")"
+	lng := "(" + strconv.FormatFloat(point.Longitude, 'f', -1, 64) + ")"
+	return fmt.Sprintf(
+		"(%g * 2 * ASIN(SQRT(POWER(SIN(RADIANS(p.latitude - %s) / 2), 2) + "+
+			"COS(RADIANS(%s)) * COS(RADIANS(p.latitude)) * POWER(SIN(RADIANS(p.longitude - %s) / 2), 2))))",
+		earthRadiusKM, lat, lat, lng)
+}
+
+// geoConditions mengubah filter near/radius_km dan bounds menjadi kondisi WHERE
+func (c SearchCriteria) geoConditions() ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if c.Near != nil {
+		conditions = append(conditions, "p.latitude IS NOT NULL")
+		if c.RadiusKM > 0 {
+			// Kotak lintang memakai index location, jarak sebenarnya dihitung setelahnya
+			delta := c.RadiusKM / kmPerDegreeLatitude
+			conditions = append(conditions, "p.latitude BETWEEN ? AND ?", distanceSQL(*c.Near)+" <= ?")
+			args = append(args, c.Near.Latitude-delta, c.Near.Latitude+delta, c.RadiusKM)
+		}
+	}
+	if b := c.Bounds; b != nil {
+		conditions = append(conditions, "p.latitude BETWEEN ? AND ?")
+		args = append(args, b.South, b.North)
+		if b.West <= b.East {
+			conditions = append(conditions, "p.longitude BETWEEN ? AND ?")
+		} else {
+			conditions = append(conditions, "(p.longitude >= ? OR p.longitude <= ?)")
+		}
+		args = append(args, b.West, b.East)
+	}
+	return conditions, args
+}
+
+// searchSpec menambahkan sort distance (dan menjadikannya default) saat
+// pencarian memakai titik pusat
+func (c SearchCriteria) searchSpec() listSpec {
+	if c.Near == nil {
+		return roomSearchSpec
+	}
+	spec := roomSearchSpec
+	spec.sorts = map[string]sortKey{"distance": {distanceSQL(*c.Near), sortNumber}}
+	for name, key := range roomSearchSpec.sorts {
+		spec.sorts[name] = key
+	}
+	spec.defaultSort = "distance"
+	return spec
+}
+
+// distanceColumn adalah kolom distance_km pada hasil pencarian
+func (c SearchCriteria) distanceColumn() string {
+	if c.Near == nil {
+		return "NULL"
+	}
+	return distanceSQL(*c.Near)
+}
+
+// UpdatePropertyLocation mengubah atau menghapus koordinat properti
+func UpdatePropertyLocation(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var propertyID int
+	if _, err := pathInt(r, "id", &propertyID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	var req PropertyLocation
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	// Staff hanya boleh mengubah properti yang ditugaskan kepadanya
+	allowed, err := canManageProperty(ctx, db, r, propertyID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error checking property access: %w", err)))
+		return
+	}
+	if !allowed {
+		apierror.Write(w, r, apierror.Forbidden("Forbidden: You are not assigned to this property"))
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	// Koordinat lama dikunci dan dibaca untuk snapshot audit
+	var before PropertyLocation
+	var oldLat, oldLng sql.NullFloat64
+	err = tx.QueryRowContext(ctx, `SELECT latitude, longitude FROM properties WHERE property_id = ? FOR UPDATE`, propertyID).Scan(&oldLat, &oldLng)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.Write(w, r, apierror.NotFound("Property not found"))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching property: %w", err)))
+		return
+	}
+	before.Latitude = nullFloatPtr(oldLat)
+	before.Longitude = nullFloatPtr(oldLng)
+
+	_, err = tx.ExecContext(ctx, `UPDATE properties SET latitude = ?, longitude = ? WHERE property_id = ?`, req.Latitude, req.Longitude, propertyID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error updating property location: %w", err)))
+		return
+	}
+
+	err = recordAudit(ctx, tx, r, auditEvent{
+		Action:     auditPropertyLocationUpdate,
+		EntityType: entityProperty,
+		EntityID:   propertyID,
+		Before:     before,
+		After:      req,
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Message: "Property location updated successfully"})
+}
+
+// nullFloatPtr mengubah sql.NullFloat64 menjadi pointer, nil untuk NULL
+func nullFloatPtr(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"booking_system_app/apierror"
+)
+
+// SchemaVersion adalah versi migrasi terakhir yang dibutuhkan kode ini
+// (nomor file terbesar di folder migrations)
+const SchemaVersion = 15
+
+// readinessTimeout membatasi lama pengecekan database oleh /readyz
+const readinessTimeout = 2 * time.Second
+
+// draining bernilai true setelah server menerima sinyal shutdown
+var draining atomic.Bool
+
+// SetDraining menandai server sedang berhenti sehingga /readyz gagal dan
+// load balancer berhenti mengirim trafik baru
+func SetDraining(value bool) {
+	draining.Store(value)
+}
+
+// HealthResponse adalah hasil pengecekan /healthz dan /readyz
+type HealthResponse struct {
+	Status        string `json:"status"`
+	SchemaVersion int    `json:"schema_version,omitempty"`
+}
+
+// Healthz menjawab liveness probe. Tidak menyentuh database agar proses
+// tidak di-restart hanya karena database sedang bermasalah.
+func Healthz(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(HealthResponse{Status: "ok"})
+}
+
+// Readyz menjawab readiness probe: database bisa di-ping dan versi migrasinya
+// sudah sesuai dengan SchemaVersion
+func Readyz(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	if draining.Load() {
+		apierror.Write(w, r, apierror.Unavailable("Server is shutting down", nil))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	err := db.PingContext(ctx)
+	if err != nil {
+		apierror.Write(w, r, apierror.Unavailable("Database unavailable", fmt.Errorf("error pinging database: %w", err)))
+		return
+	}
+
+	var version int
+	err = db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		apierror.Write(w, r, apierror.Unavailable("Migration version unknown", fmt.Errorf("error reading schema version: %w", err)))
+		return
+	}
+	if version < SchemaVersion {
+		apierror.Write(w, r, apierror.Unavailable(fmt.Sprintf("Database schema version %d is older than required %d", version, SchemaVersion), nil))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(HealthResponse{Status: "ready", SchemaVersion: version})
+}
+
+// PoolStatsResponse adalah ringkasan sql.DBStats untuk monitoring
+type PoolStatsResponse struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMs     int64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+}
+
+// PoolStats menampilkan statistik connection pool database. WaitCount yang terus
+// naik menandakan DB_MAX_OPEN_CONNS terlalu kecil untuk beban saat ini.
+func PoolStats(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	stats := db.Stats()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PoolStatsResponse{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMs:     stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	})
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"booking_system_app/middleware"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// newMockDB membuat *sql.DB tiruan yang mencocokkan query dengan regexp.
+// Semua ekspektasi harus terpenuhi saat test selesai.
+func newMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	return db, mock
+}
+
+// expectAuth mendaftarkan query role yang dijalankan middleware untuk token sesi
+func expectAuth(mock sqlmock.Sqlmock, email, role string) {
+	mock.ExpectQuery(`SELECT role, disabled, sessions_valid_after FROM users WHERE email = \?`).
+		WithArgs(email).
+		WillReturnRows(sqlmock.NewRows([]string{"role", "disabled", "sessions_valid_after"}).AddRow(role, false, nil))
+}
+
+// newAuthRequest membuat request dengan token sesi milik email
+func newAuthRequest(t *testing.T, method, target, body, email string) *http.Request {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if email != "" {
+		token, err := signSessionToken(email, false, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+// serveWith menjalankan handler di belakang RequirePermission seperti di routes.go
+func serveWith(db *sql.DB, permission string, handler func(*sql.DB, http.ResponseWriter, *http.Request), req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	middleware.RequirePermission(permission, db, func(w http.ResponseWriter, r *http.Request) {
+		handler(db, w, r)
+	})(rec, req)
+	return rec
+}
+
+// serve menjalankan handler publik tanpa middleware autentikasi
+func serve(db *sql.DB, handler func(*sql.DB, http.ResponseWriter, *http.Request), req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler(db, rec, req)
+	return rec
+}
+
+// decodeBody membaca body JSON respons ke v
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+		t.Fatalf("decoding response %q: %v", rec.Body.String(), err)
+	}
+}
+
+// errorCode mengambil kode error dari envelope apierror
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	decodeBody(t, rec, &body)
+	return body.Error.Code
+}
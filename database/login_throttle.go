@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"booking_system_app/logging"
+)
+
+// Pengaturan pembatasan percobaan login. Setelah jumlah kegagalan mencapai
+// batas, kunci sementara diberikan dan durasinya berlipat dua pada setiap
+// kegagalan berikutnya (exponential backoff) hingga lockoutMax.
+var (
+	emailFailureLimit = 5
+	ipFailureLimit    = 20
+	lockoutBase       = time.Minute
+	lockoutMax        = time.Hour
+	failureWindow     = 24 * time.Hour
+)
+
+// Alasan yang dicatat di login_attempts
+const (
+	loginResultSuccess         = "success"
+	loginResultLocked          = "locked"
+	loginResultUnknownEmail    = "unknown_email"
+	loginResultInvalidPassword = "invalid_password"
+	loginResultDisabled        = "disabled"
+	loginResultUnverified      = "unverified"
+	loginResultMFAPending      = "mfa_pending"
+	loginResultInvalidMFA      = "invalid_mfa_code"
+)
+
+// emailThrottleKey dan ipThrottleKey membentuk kunci baris di tabel login_throttles
+func emailThrottleKey(email string) string {
+	return "email:" + strings.ToLower(email)
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+func mfaThrottleKey(userID int) string {
+	return "mfa:" + strconv.Itoa(userID)
+}
+
+// clientIP mengambil alamat IP klien dari koneksi
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// loginLockRemaining mengembalikan sisa waktu kunci terlama untuk email dan IP.
+// Nilai nol berarti login boleh dicoba.
+func loginLockRemaining(ctx context.Context, db *sql.DB, emailKey, ipKey string) (time.Duration, error) {
+	var remaining sql.NullInt64
+	query := `
+		SELECT MAX(TIMESTAMPDIFF(SECOND, NOW(), locked_until)) FROM login_throttles
+		WHERE throttle_key IN (?, ?) AND locked_until > NOW()
+	`
+	err := db.QueryRowContext(ctx, query, emailKey, ipKey).Scan(&remaining)
+	if err != nil {
+		return 0, err
+	}
+	if !remaining.Valid {
+		return 0, nil
+	}
+	// Pembulatan ke atas agar Retry-After tidak pernah nol saat masih terkunci
+	return time.Duration(remaining.Int64+1) * time.Second, nil
+}
+
+// recordLoginFailure menambah hitungan kegagalan untuk kunci dan memasang kunci
+// sementara jika batas sudah terlampaui. Hitungan direset bila kegagalan terakhir
+// sudah lebih lama dari failureWindow.
+func recordLoginFailure(ctx context.Context, db *sql.DB, key string, limit int) error {
+	query := `
+		INSERT INTO login_throttles (throttle_key, failed_count, last_failed_at) VALUES (?, 1, NOW())
+		ON DUPLICATE KEY UPDATE
+			failed_count = IF(last_failed_at < DATE_SUB(NOW(), INTERVAL ? SECOND), 1, failed_count + 1),
+			last_failed_at = NOW()
+	`
+	_, err := db.ExecContext(ctx, query, key, int(failureWindow.Seconds()))
+	if err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE login_throttles
+		SET locked_until = DATE_ADD(NOW(), INTERVAL LEAST(? * POW(2, failed_count - ?), ?) SECOND)
+		WHERE throttle_key = ? AND failed_count >= ?
+	`
+	_, err = db.ExecContext(ctx, query, int(lockoutBase.Seconds()), limit, int(lockoutMax.Seconds()), key, limit)
+	return err
+}
+
+// clearLoginFailures menghapus hitungan kegagalan dan kunci untuk sebuah kunci
+func clearLoginFailures(ctx context.Context, db *sql.DB, key string) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM login_throttles WHERE throttle_key = ?`, key)
+	return err
+}
+
+// recordLoginAttempt mencatat setiap percobaan login sebagai audit event.
+// Kegagalan menulis audit hanya dicatat di log agar tidak memblokir login.
+func recordLoginAttempt(ctx context.Context, db *sql.DB, email, ip string, userID int, result string) {
+	// Audit dan hitungan kegagalan tetap ditulis walaupun klien memutus koneksi,
+	// agar percobaan brute force tidak bisa lolos dari throttling
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), queryTimeout)
+	defer cancel()
+
+	var user interface{}
+	if userID > 0 {
+		user = userID
+	}
+
+	loginAttemptsTotal.WithLabelValues(result).Inc()
+
+	query := `INSERT INTO login_attempts (email, ip_address, user_id, success, result) VALUES (?, ?, ?, ?, ?)`
+	_, err := db.ExecContext(ctx, query, email, ip, user, result == loginResultSuccess, result)
+	if err != nil {
+		logging.FromContext(ctx).Error("error recording login attempt", "result", result, "error", err)
+	}
+}
+
+// handleLoginFailure mencatat kegagalan untuk email dan IP sekaligus
+func handleLoginFailure(ctx context.Context, db *sql.DB, email, ip string, userID int, result string) {
+	// Audit dan hitungan kegagalan tetap ditulis walaupun klien memutus koneksi,
+	// agar percobaan brute force tidak bisa lolos dari throttling
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), queryTimeout)
+	defer cancel()
+
+	recordLoginAttempt(ctx, db, email, ip, userID, result)
+
+	if err := recordLoginFailure(ctx, db, emailThrottleKey(email), emailFailureLimit); err != nil {
+		logging.FromContext(ctx).Error("error recording login failure", "throttle", "email", "error", err)
+	}
+	if err := recordLoginFailure(ctx, db, ipThrottleKey(ip), ipFailureLimit); err != nil {
+		logging.FromContext(ctx).Error("error recording login failure", "throttle", "ip", "error", err)
+	}
+}
@@ -0,0 +1,161 @@
+package database
+
+import (
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	lockRemainingQuery = `SELECT MAX\(TIMESTAMPDIFF\(SECOND, NOW\(\), locked_until\)\) FROM login_throttles`
+	loginUserQuery     = `SELECT user_id, name, role, password_hash, .* FROM users WHERE email = \?`
+	loginAttemptInsert = `INSERT INTO login_attempts`
+	throttleInsert     = `INSERT INTO login_throttles`
+	throttleLock       = `UPDATE login_throttles\s+SET locked_until`
+	throttleClear      = `DELETE FROM login_throttles WHERE throttle_key = \?`
+)
+
+// httptest.NewRequest memakai RemoteAddr 192.0.2.1:1234
+const testClientIP = "192.0.2.1"
+
+func newLoginRequest(t *testing.T, body string) *http.Request {
+	return newAuthRequest(t, http.MethodPost, "/login", body, "")
+}
+
+// expectLoginUser mendaftarkan query akun pada langkah password
+func expectLoginUser(t *testing.T, mock sqlmock.Sqlmock, email, password, role string, disabled, totpEnabled bool) {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(loginUserQuery).
+		WithArgs(email).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "name", "role", "password_hash", "verified", "disabled", "totp_enabled"}).
+			AddRow(42, "Test User", role, string(hash), true, disabled, totpEnabled))
+}
+
+func expectNotLocked(mock sqlmock.Sqlmock, email string) {
+	mock.ExpectQuery(lockRemainingQuery).
+		WithArgs(emailThrottleKey(email), ipThrottleKey(testClientIP)).
+		WillReturnRows(sqlmock.NewRows([]string{"remaining"}).AddRow(nil))
+}
+
+// expectFailureRecorded mendaftarkan audit percobaan dan penambahan hitungan
+// kegagalan untuk email lalu IP
+func expectFailureRecorded(mock sqlmock.Sqlmock, email string, userID interface{}, result string) {
+	mock.ExpectExec(loginAttemptInsert).
+		WithArgs(email, testClientIP, userID, false, result).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	for _, throttle := range []struct {
+		key   string
+		limit int
+	}{{emailThrottleKey(email), emailFailureLimit}, {ipThrottleKey(testClientIP), ipFailureLimit}} {
+		mock.ExpectExec(throttleInsert).
+			WithArgs(throttle.key, int(failureWindow.Seconds())).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(throttleLock).
+			WithArgs(int(lockoutBase.Seconds()), throttle.limit, int(lockoutMax.Seconds()), throttle.key, throttle.limit).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+}
+
+func TestLoginLockedOut(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectQuery(lockRemainingQuery).
+		WithArgs("email:user@example.com", ipThrottleKey(testClientIP)).
+		WillReturnRows(sqlmock.NewRows([]string{"remaining"}).AddRow(29))
+	mock.ExpectExec(loginAttemptInsert).
+		WithArgs("User@Example.com", testClientIP, nil, false, loginResultLocked).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Password tidak diperiksa sama sekali selama terkunci
+	rec := serve(db, LoginUser, newLoginRequest(t, `{"email": "User@Example.com", "password": "Secret123!"}`))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want 30", got)
+	}
+}
+
+func TestLoginFailuresAreThrottled(t *testing.T) {
+	t.Run("unknown email", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectNotLocked(mock, "nobody@example.com")
+		mock.ExpectQuery(loginUserQuery).WithArgs("nobody@example.com").WillReturnError(sql.ErrNoRows)
+		expectFailureRecorded(mock, "nobody@example.com", nil, loginResultUnknownEmail)
+
+		rec := serve(db, LoginUser, newLoginRequest(t, `{"email": "nobody@example.com", "password": "Secret123!"}`))
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectNotLocked(mock, "user@example.com")
+		expectLoginUser(t, mock, "user@example.com", "Secret123!", RoleCustomer, false, false)
+		expectFailureRecorded(mock, "user@example.com", 42, loginResultInvalidPassword)
+
+		rec := serve(db, LoginUser, newLoginRequest(t, `{"email": "user@example.com", "password": "wrong"}`))
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+		}
+		var body Response
+		decodeBody(t, rec, &body)
+		if body.Token != "" {
+			t.Error("token issued for wrong password")
+		}
+	})
+
+	t.Run("disabled account does not count as a failure", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectNotLocked(mock, "user@example.com")
+		expectLoginUser(t, mock, "user@example.com", "Secret123!", RoleCustomer, true, false)
+		mock.ExpectExec(loginAttemptInsert).
+			WithArgs("user@example.com", testClientIP, 42, false, loginResultDisabled).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		rec := serve(db, LoginUser, newLoginRequest(t, `{"email": "user@example.com", "password": "Secret123!"}`))
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+	})
+}
+
+func TestLoginSuccessClearsEmailFailures(t *testing.T) {
+	db, mock := newMockDB(t)
+	expectNotLocked(mock, "user@example.com")
+	expectLoginUser(t, mock, "user@example.com", "Secret123!", RoleCustomer, false, false)
+	// Hanya hitungan email yang direset, hitungan IP tetap berjalan
+	mock.ExpectExec(throttleClear).WithArgs(emailThrottleKey("user@example.com")).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(loginAttemptInsert).
+		WithArgs("user@example.com", testClientIP, 42, true, loginResultSuccess).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	rec := serve(db, LoginUser, newLoginRequest(t, `{"email": "user@example.com", "password": "Secret123!"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var body Response
+	decodeBody(t, rec, &body)
+	if body.Token == "" || body.MFARequired {
+		t.Errorf("response = %+v, want a full session token", body)
+	}
+}
+
+func TestThrottleKeys(t *testing.T) {
+	if got := emailThrottleKey("User@Example.COM"); got != "email:user@example.com" {
+		t.Errorf("emailThrottleKey = %q", got)
+	}
+	if got := ipThrottleKey("203.0.113.9"); got != "ip:203.0.113.9" {
+		t.Errorf("ipThrottleKey = %q", got)
+	}
+	if got := mfaThrottleKey(42); got != "mfa:42" {
+		t.Errorf("mfaThrottleKey = %q", got)
+	}
+}
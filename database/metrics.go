@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrik bisnis yang diekspos di /metrics
+var (
+	bookingsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "booking_bookings_total",
+		Help: "Bookings by outcome (created, failed, cancelled) and failure reason.",
+	}, []string{"outcome", "reason"})
+	loginAttemptsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "booking_login_attempts_total",
+		Help: "Login attempts by result.",
+	}, []string{"result"})
+	paymentsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "booking_payments_total",
+		Help: "Payments by method and status.",
+	}, []string{"method", "status"})
+)
+
+// bookingFailed mencatat booking yang gagal beserta alasannya
+func bookingFailed(reason string) {
+	bookingsTotal.WithLabelValues("failed", reason).Inc()
+}
+
+// failureReason mengelompokkan error booking menjadi label metrik
+func failureReason(err error) string {
+	switch {
+	case errors.Is(err, errRoomUnavailable):
+		return "overlap"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	default:
+		return "db_error"
+	}
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"booking_system_app/apierror"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestFailureReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"overlap", &apierror.Error{Status: http.StatusConflict, Cause: errRoomUnavailable}, "overlap"},
+		{"wrapped overlap", fmt.Errorf("booking room 3: %w", errRoomUnavailable), "overlap"},
+		{"timeout", fmt.Errorf("error locking room: %w", context.DeadlineExceeded), "timeout"},
+		{"canceled", context.Canceled, "canceled"},
+		{"other", errors.New("connection refused"), "db_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := failureReason(tt.err); got != tt.want {
+				t.Errorf("failureReason = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBookingFailedCountsReason(t *testing.T) {
+	counter := bookingsTotal.WithLabelValues("failed", "overlap")
+	before := testutil.ToFloat64(counter)
+	bookingFailed(failureReason(errRoomUnavailable))
+	if got := testutil.ToFloat64(counter) - before; got != 1 {
+		t.Errorf("failed/overlap counter increased by %v, want 1", got)
+	}
+}
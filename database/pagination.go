@@ -0,0 +1,233 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"booking_system_app/apierror"
+	"booking_system_app/validate"
+)
+
+// Konvensi semua endpoint daftar:
+//
+//   - limit: jumlah item per halaman (default 20, maksimum 100)
+//   - sort: nama field, awalan "-" untuk urutan menurun (misalnya "-created_at")
+//   - cursor: next_cursor dari respons sebelumnya untuk mengambil halaman berikutnya
+//
+// Pagination memakai keyset (WHERE kolom > nilai terakhir), bukan OFFSET,
+// sehingga halaman tetap cepat dan stabil meskipun data bertambah.
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// ListOptions adalah parameter pagination dan sorting. Endpoint GET
+// membacanya dari query string, SearchRooms dari body JSON.
+type ListOptions struct {
+	Limit  int    `json:"limit,omitempty"`
+	Cursor string `json:"cursor,omitempty"`
+	Sort   string `json:"sort,omitempty"`
+}
+
+// PageInfo disertakan di setiap respons daftar. NextCursor kosong berarti
+// tidak ada halaman berikutnya; Total adalah jumlah item yang cocok dengan filter.
+type PageInfo struct {
+	Total      int    `json:"total"`
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// listOptionsFromQuery membaca limit, cursor dan sort dari query string
+func listOptionsFromQuery(r *http.Request) ListOptions {
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	return ListOptions{Limit: limit, Cursor: q.Get("cursor"), Sort: q.Get("sort")}
+}
+
+// Jenis nilai kolom sort, menentukan cara nilai cursor di-decode
+const (
+	sortNumber = iota
+	sortString
+	sortTime
+)
+
+// sortKey adalah kolom SQL di balik nama sort yang boleh dipakai klien
+type sortKey struct {
+	column string
+	kind   int
+}
+
+// listSpec mendefinisikan sort yang didukung sebuah endpoint. idColumn harus
+// unik dan dipakai sebagai pemecah seri agar urutan selalu deterministik.
+type listSpec struct {
+	sorts       map[string]sortKey
+	defaultSort string
+	idColumn    string
+}
+
+// listQuery adalah ListOptions yang sudah divalidasi terhadap listSpec
+type listQuery struct {
+	spec  listSpec
+	sort  string // nama sort termasuk awalan "-"
+	key   sortKey
+	desc  bool
+	limit int
+	after *pageCursor
+}
+
+// pageCursor adalah isi cursor: sort yang dipakai dan kunci item terakhir
+type pageCursor struct {
+	Sort  string      `json:"s"`
+	Value interface{} `json:"v"`
+	ID    int64       `json:"id"`
+}
+
+// parse memvalidasi opsi dari klien. Cursor hanya berlaku untuk sort yang
+// sama dengan saat cursor dibuat.
+func (spec listSpec) parse(opts ListOptions) (listQuery, error) {
+	q := listQuery{spec: spec, sort: opts.Sort, limit: opts.Limit}
+	if q.sort == "" {
+		q.sort = spec.defaultSort
+	}
+	key, ok := spec.sorts[strings.TrimPrefix(q.sort, "-")]
+	if !ok {
+		var v validate.Validator
+		v.OneOf("sort", strings.TrimPrefix(q.sort, "-"), spec.sortNames()...)
+		return q, v.Err()
+	}
+	q.key = key
+	q.desc = strings.HasPrefix(q.sort, "-")
+
+	switch {
+	case q.limit <= 0:
+		q.limit = defaultPageSize
+	case q.limit > maxPageSize:
+		q.limit = maxPageSize
+	}
+
+	if opts.Cursor != "" {
+		cursor, err := decodeCursor(opts.Cursor, key.kind)
+		if err != nil || cursor.Sort != q.sort {
+			return q, apierror.BadRequest("Invalid cursor")
+		}
+		q.after = cursor
+	}
+	return q, nil
+}
+
+func (spec listSpec) sortNames() []string {
+	var names []string
+	for name := range spec.sorts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func decodeCursor(raw string, kind int) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, err
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+
+	// Nilai dikembalikan ke tipe kolom agar perbandingan di SQL tepat
+	switch value := cursor.Value.(type) {
+	case float64:
+		if kind != sortNumber {
+			return nil, fmt.Errorf("unexpected number in cursor")
+		}
+	case string:
+		switch kind {
+		case sortString:
+		case sortTime:
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return nil, err
+			}
+			cursor.Value = t
+		default:
+			return nil, fmt.Errorf("unexpected string in cursor")
+		}
+	default:
+		return nil, fmt.Errorf("unexpected cursor value %T", value)
+	}
+	return &cursor, nil
+}
+
+// where mengembalikan kondisi keyset untuk halaman setelah cursor, atau
+// string kosong untuk halaman pertama. Kondisi siap digabung dengan AND.
+func (q listQuery) where() (string, []interface{}) {
+	if q.after == nil {
+		return "", nil
+	}
+	op := ">"
+	if q.desc {
+		op = "<"
+	}
+	condition := fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", q.key.column, op, q.key.column, q.spec.idColumn, op)
+	return condition, []interface{}{q.after.Value, q.after.Value, q.after.ID}
+}
+
+// field mengembalikan nama sort tanpa awalan "-", misalnya "created_at"
+func (q listQuery) field() string {
+	return strings.TrimPrefix(q.sort, "-")
+}
+
+// order mengembalikan klausa ORDER BY tanpa LIMIT
+func (q listQuery) order() string {
+	dir := "ASC"
+	if q.desc {
+		dir = "DESC"
+	}
+	return fmt.Sprintf("ORDER BY %s %s, %s %s", q.key.column, dir, q.spec.idColumn, dir)
+}
+
+// orderBy mengembalikan ORDER BY dan LIMIT. Satu baris ekstra diambil untuk
+// mengetahui apakah masih ada halaman berikutnya.
+func (q listQuery) orderBy() (string, []interface{}) {
+	return q.order() + " LIMIT ?", []interface{}{q.limit + 1}
+}
+
+// pageInfo membuat PageInfo dari jumlah baris yang terbaca (termasuk baris
+// ekstra). last dipanggil untuk item terakhir di halaman ini dan mengembalikan
+// nilai kolom sort serta ID-nya.
+func (q listQuery) pageInfo(total, fetched int, last func() (interface{}, int64)) PageInfo {
+	info := PageInfo{Total: total, Limit: q.limit}
+	if fetched <= q.limit {
+		return info
+	}
+
+	value, id := last()
+	if t, ok := value.(time.Time); ok {
+		value = t.Format(time.RFC3339Nano)
+	}
+	data, _ := json.Marshal(pageCursor{Sort: q.sort, Value: value, ID: id})
+	info.NextCursor = base64.RawURLEncoding.EncodeToString(data)
+	return info
+}
+
+// sqlConditions menggabungkan kondisi filter menjadi klausa WHERE
+func sqlConditions(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conditions, " AND ")
+}
+
+// likeEscaper meng-escape karakter wildcard LIKE agar dicocokkan apa adanya
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// containsPattern membuat pola LIKE "mengandung s", dipakai bersama ESCAPE '\\'
+func containsPattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"booking_system_app/apierror"
+)
+
+var testListSpec = listSpec{
+	sorts: map[string]sortKey{
+		"id":         {"t.id", sortNumber},
+		"name":       {"t.name", sortString},
+		"created_at": {"t.created_at", sortTime},
+	},
+	defaultSort: "-id",
+	idColumn:    "t.id",
+}
+
+// nextCursor membuat cursor seperti yang dikirim di PageInfo.NextCursor
+func nextCursor(t *testing.T, sort string, value interface{}, id int64) string {
+	t.Helper()
+	q, err := testListSpec.parse(ListOptions{Sort: sort, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := q.pageInfo(10, 2, func() (interface{}, int64) { return value, id })
+	if page.NextCursor == "" {
+		t.Fatal("expected next cursor")
+	}
+	return page.NextCursor
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	created := time.Date(2024, 5, 1, 10, 30, 0, 123456789, time.UTC)
+	tests := []struct {
+		sort  string
+		value interface{}
+		want  interface{}
+	}{
+		// JSON mengembalikan angka sebagai float64
+		{"id", int64(42), float64(42)},
+		{"-name", "Budi", "Budi"},
+		{"created_at", created, created},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			cursor := nextCursor(t, tt.sort, tt.value, 7)
+			q, err := testListSpec.parse(ListOptions{Sort: tt.sort, Cursor: cursor})
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if q.after == nil {
+				t.Fatal("cursor not decoded")
+			}
+			if !reflect.DeepEqual(q.after.Value, tt.want) || q.after.ID != 7 {
+				t.Errorf("after = (%#v, %d), want (%#v, 7)", q.after.Value, q.after.ID, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRejectsInvalidCursor(t *testing.T) {
+	tests := []struct {
+		name   string
+		sort   string
+		cursor string
+	}{
+		{"not base64", "id", "!!!"},
+		{"not json", "id", "bm90IGpzb24"},
+		{"other sort", "-id", nextCursor(t, "id", int64(1), 1)},
+		{"string for number", "id", encodeTestCursor(t, "id", "x")},
+		{"number for time", "created_at", encodeTestCursor(t, "created_at", 1)},
+		{"invalid time", "created_at", encodeTestCursor(t, "created_at", "yesterday")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := testListSpec.parse(ListOptions{Sort: tt.sort, Cursor: tt.cursor})
+			var apiErr *apierror.Error
+			if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadRequest {
+				t.Errorf("err = %v, want 400 invalid cursor", err)
+			}
+		})
+	}
+}
+
+// encodeTestCursor membuat cursor dengan nilai apa adanya, tanpa konversi waktu
+func encodeTestCursor(t *testing.T, sort string, value interface{}) string {
+	t.Helper()
+	q := listQuery{sort: sort, limit: 1}
+	return q.pageInfo(0, 2, func() (interface{}, int64) { return value, 1 }).NextCursor
+}
+
+func TestParseLimitAndSort(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      ListOptions
+		wantSort  string
+		wantLimit int
+		wantOrder string
+		wantErr   bool
+	}{
+		{"defaults", ListOptions{}, "-id", defaultPageSize, "ORDER BY t.id DESC, t.id DESC", false},
+		{"ascending", ListOptions{Sort: "name", Limit: 5}, "name", 5, "ORDER BY t.name ASC, t.id ASC", false},
+		{"limit capped", ListOptions{Limit: 1000}, "-id", maxPageSize, "ORDER BY t.id DESC, t.id DESC", false},
+		{"negative limit", ListOptions{Limit: -1}, "-id", defaultPageSize, "ORDER BY t.id DESC, t.id DESC", false},
+		{"unknown sort", ListOptions{Sort: "price"}, "", 0, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := testListSpec.parse(tt.opts)
+			if tt.wantErr {
+				var apiErr *apierror.Error
+				if !errors.As(err, &apiErr) || apiErr.Code != apierror.CodeValidation {
+					t.Fatalf("err = %v, want validation error", err)
+				}
+				want := []apierror.FieldError{{Field: "sort", Message: "must be one of: created_at, id, name"}}
+				if !reflect.DeepEqual(apiErr.Details, want) {
+					t.Errorf("details = %v, want %v", apiErr.Details, want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q.sort != tt.wantSort || q.limit != tt.wantLimit || q.order() != tt.wantOrder {
+				t.Errorf("got (%s, %d, %q), want (%s, %d, %q)", q.sort, q.limit, q.order(), tt.wantSort, tt.wantLimit, tt.wantOrder)
+			}
+		})
+	}
+}
+
+func TestWhere(t *testing.T) {
+	q, err := testListSpec.parse(ListOptions{Sort: "-id", Cursor: nextCursor(t, "-id", int64(9), 9)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	condition, args := q.where()
+	if want := "(t.id < ? OR (t.id = ? AND t.id < ?))"; condition != want {
+		t.Errorf("condition = %s, want %s", condition, want)
+	}
+	if want := []interface{}{float64(9), float64(9), int64(9)}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %v, want %v", args, want)
+	}
+
+	first, _ := testListSpec.parse(ListOptions{})
+	if condition, args := first.where(); condition != "" || args != nil {
+		t.Errorf("first page where = (%q, %v), want empty", condition, args)
+	}
+}
+
+func TestPageInfo(t *testing.T) {
+	q, _ := testListSpec.parse(ListOptions{Limit: 2})
+	last := func() (interface{}, int64) { return int64(5), 5 }
+
+	if page := q.pageInfo(2, 2, last); page.NextCursor != "" || page.Total != 2 || page.Limit != 2 {
+		t.Errorf("last page = %+v, want no cursor", page)
+	}
+	if page := q.pageInfo(3, 3, last); page.NextCursor == "" {
+		t.Error("expected next cursor when an extra row was fetched")
+	}
+}
+
+func TestContainsPattern(t *testing.T) {
+	tests := map[string]string{
+		"suite":     "%suite%",
+		"100%":      `%100\%%`,
+		"a_b":       `%a\_b%`,
+		`back\path`: `%back\\path%`,
+		"":          "%%",
+	}
+	for in, want := range tests {
+		if got := containsPattern(in); got != want {
+			t.Errorf("containsPattern(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
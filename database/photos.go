@@ -0,0 +1,677 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"booking_system_app/apierror"
+	"booking_system_app/blobstore"
+	"booking_system_app/logging"
+	"booking_system_app/middleware"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// thumbnailSize adalah sisi terpanjang thumbnail dalam piksel
+	thumbnailSize = 480
+	// maxPhotoPixels menolak gambar yang kecil di disk tetapi sangat besar saat di-decode
+	maxPhotoPixels = 40_000_000
+	// maxPhotosPerListing membatasi jumlah foto per properti atau per kamar
+	maxPhotosPerListing = 50
+	// Key blob unik untuk setiap unggahan sehingga file tidak pernah berubah
+	// dan boleh di-cache selamanya
+	mediaCacheControl = "public, max-age=31536000, immutable"
+	// mediaPrefix adalah path publik file di BlobStore
+	mediaPrefix = "/media/"
+)
+
+// photoTypes memetakan content type hasil sniffing ke ekstensi file
+var photoTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// Photo adalah satu foto properti atau kamar
+type Photo struct {
+	PhotoID      int    `json:"photo_id"`
+	PropertyID   int    `json:"property_id"`
+	RoomID       *int   `json:"room_id"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	ContentType  string `json:"content_type"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	SizeBytes    int64  `json:"size_bytes"`
+	Position     int    `json:"position"`
+	IsCover      bool   `json:"is_cover"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// PhotoUpload mendokumentasikan body multipart/form-data unggahan foto
+type PhotoUpload struct {
+	Photo string `json:"photo" format:"binary"` // JPEG, PNG atau WebP
+}
+
+// Struct untuk request perubahan urutan atau cover foto
+type UpdatePhotoRequest struct {
+	Position *int  `json:"position,omitempty"` // urutan baru, dimulai dari 1
+	Cover    *bool `json:"cover,omitempty"`
+}
+
+// mediaURL mengubah key blob menjadi URL yang dilayani ServeMedia
+func mediaURL(key string) string {
+	return mediaPrefix + key
+}
+
+// photoScope mengembalikan kondisi SQL untuk kelompok foto yang sama:
+// foto properti itu sendiri atau foto satu kamar
+func photoScope(propertyID int, roomID *int) (string, []interface{}) {
+	if roomID != nil {
+		return "room_id = ?", []interface{}{*roomID}
+	}
+	return "property_id = ? AND room_id IS NULL", []interface{}{propertyID}
+}
+
+const photoColumns = `photo_id, property_id, room_id, storage_key, thumbnail_key, content_type, width, height,
+	size_bytes, position, is_cover, created_at`
+
+func scanPhoto(scan func(dest ...interface{}) error) (Photo, error) {
+	var photo Photo
+	var roomID sql.NullInt64
+	var storageKey, thumbnailKey string
+	err := scan(&photo.PhotoID, &photo.PropertyID, &roomID, &storageKey, &thumbnailKey, &photo.ContentType,
+		&photo.Width, &photo.Height, &photo.SizeBytes, &photo.Position, &photo.IsCover, &photo.CreatedAt)
+	if err != nil {
+		return photo, err
+	}
+	photo.RoomID = nullIntPtr(roomID)
+	photo.URL = mediaURL(storageKey)
+	photo.ThumbnailURL = mediaURL(thumbnailKey)
+	return photo, nil
+}
+
+// listPhotos membaca foto dalam satu kelompok sesuai urutan
+func listPhotos(ctx context.Context, q querier, propertyID int, roomID *int) ([]Photo, error) {
+	scope, args := photoScope(propertyID, roomID)
+	rows, err := q.QueryContext(ctx, `SELECT `+photoColumns+` FROM photos WHERE `+scope+` ORDER BY position`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing photos: %w", err)
+	}
+	defer rows.Close()
+
+	photos := []Photo{}
+	for rows.Next() {
+		photo, err := scanPhoto(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("error reading photos: %w", err)
+		}
+		photos = append(photos, photo)
+	}
+	return photos, rows.Err()
+}
+
+// processedPhoto adalah hasil pemeriksaan dan pembuatan thumbnail sebuah unggahan
+type processedPhoto struct {
+	data        []byte
+	thumbnail   []byte
+	contentType string
+	width       int
+	height      int
+}
+
+// readPhoto membaca field "photo" dari body multipart, memastikan isinya
+// benar-benar gambar yang didukung (bukan sekadar header Content-Type dari
+// klien) lalu membuat thumbnail JPEG
+func readPhoto(w http.ResponseWriter, r *http.Request) (*processedPhoto, error) {
+	// Ruang tambahan untuk boundary dan header bagian multipart
+	r.Body = http.MaxBytesReader(w, r.Body, maxPhotoBytes+64<<10)
+	file, header, err := r.FormFile("photo")
+	if r.MultipartForm != nil {
+		defer r.MultipartForm.RemoveAll()
+	}
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return nil, apierror.BodyTooLarge()
+	case errors.Is(err, http.ErrMissingFile):
+		return nil, apierror.Validation([]apierror.FieldError{{Field: "photo", Message: "is required"}})
+	case err != nil:
+		return nil, apierror.BadRequest("Request body must be multipart/form-data with a photo field")
+	}
+	defer file.Close()
+	if header.Size > maxPhotoBytes {
+		return nil, apierror.BodyTooLarge()
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, maxPhotoBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading upload: %w", err)
+	}
+	if int64(len(data)) > maxPhotoBytes {
+		return nil, apierror.BodyTooLarge()
+	}
+
+	contentType := http.DetectContentType(data)
+	if _, ok := photoTypes[contentType]; !ok {
+		return nil, apierror.Validation([]apierror.FieldError{{Field: "photo", Message: "must be a JPEG, PNG or WebP image"}})
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, apierror.Validation([]apierror.FieldError{{Field: "photo", Message: "is not a valid image"}})
+	}
+	if config.Width*config.Height > maxPhotoPixels {
+		return nil, apierror.Validation([]apierror.FieldError{{Field: "photo", Message: fmt.Sprintf("must be at most %d megapixels", maxPhotoPixels/1_000_000)}})
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, apierror.Validation([]apierror.FieldError{{Field: "photo", Message: "is not a valid image"}})
+	}
+
+	thumbnail, err := makeThumbnail(img)
+	if err != nil {
+		return nil, fmt.Errorf("error creating thumbnail: %w", err)
+	}
+	return &processedPhoto{
+		data:        data,
+		thumbnail:   thumbnail,
+		contentType: contentType,
+		width:       config.Width,
+		height:      config.Height,
+	}, nil
+}
+
+// makeThumbnail mengecilkan gambar agar sisi terpanjangnya thumbnailSize
+// dan menyimpannya sebagai JPEG. Area transparan diberi latar putih.
+func makeThumbnail(img image.Image) ([]byte, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > thumbnailSize || height > thumbnailSize {
+		if width >= height {
+			height = max(1, height*thumbnailSize/width)
+			width = thumbnailSize
+		} else {
+			width = max(1, width*thumbnailSize/height)
+			height = thumbnailSize
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// newPhotoKeys membuat key blob acak untuk file asli dan thumbnail-nya
+func newPhotoKeys(contentType string) (string, string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	name := "photos/" + hex.EncodeToString(b)
+	return name + photoTypes[contentType], name + "_thumb.jpg", nil
+}
+
+// UploadPropertyPhoto mengunggah foto properti (multipart, field "photo")
+func UploadPropertyPhoto(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, txTimeout)
+	defer cancel()
+
+	var propertyID int
+	if _, err := pathInt(r, "id", &propertyID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	uploadPhoto(ctx, db, w, r, propertyID, nil)
+}
+
+// UploadRoomPhoto mengunggah foto kamar (multipart, field "photo")
+func UploadRoomPhoto(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, txTimeout)
+	defer cancel()
+
+	var roomID int
+	if _, err := pathInt(r, "id", &roomID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	propertyID, err := getPropertyIDForRoom(ctx, db, roomID)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.Write(w, r, apierror.NotFound("Room not found"))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching room: %w", err)))
+		return
+	}
+	uploadPhoto(ctx, db, w, r, propertyID, &roomID)
+}
+
+// uploadPhoto menyimpan file ke BlobStore lalu mencatatnya di tabel photos.
+// Foto pertama dalam kelompoknya otomatis menjadi cover.
+func uploadPhoto(ctx context.Context, db *sql.DB, w http.ResponseWriter, r *http.Request, propertyID int, roomID *int) {
+	// Staff hanya boleh mengunggah foto untuk properti yang ditugaskan kepadanya
+	allowed, err := canManageProperty(ctx, db, r, propertyID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error checking property access: %w", err)))
+		return
+	}
+	if !allowed {
+		apierror.Write(w, r, apierror.Forbidden("Forbidden: You are not assigned to this property"))
+		return
+	}
+
+	upload, err := readPhoto(w, r)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	storageKey, thumbnailKey, err := newPhotoKeys(upload.contentType)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error generating photo key: %w", err)))
+		return
+	}
+
+	// File ditulis sebelum transaksi; jika transaksi gagal, file dihapus lagi
+	committed := false
+	defer func() {
+		if !committed {
+			blobs.Delete(context.WithoutCancel(ctx), storageKey)
+			blobs.Delete(context.WithoutCancel(ctx), thumbnailKey)
+		}
+	}()
+	if err := blobs.Put(ctx, storageKey, bytes.NewReader(upload.data)); err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error storing photo: %w", err)))
+		return
+	}
+	if err := blobs.Put(ctx, thumbnailKey, bytes.NewReader(upload.thumbnail)); err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error storing thumbnail: %w", err)))
+		return
+	}
+
+	uploaderID, err := getUserIDByEmail(ctx, db, middleware.UserEmail(r))
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching uploader: %w", err)))
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	// Baris properti dikunci agar posisi foto dari unggahan bersamaan tidak bentrok
+	if err := lockProperty(ctx, tx, propertyID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	scope, args := photoScope(propertyID, roomID)
+	var count int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM photos WHERE `+scope, args...).Scan(&count)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error counting photos: %w", err)))
+		return
+	}
+	if count >= maxPhotosPerListing {
+		apierror.Write(w, r, apierror.Conflict(fmt.Sprintf("At most %d photos are allowed", maxPhotosPerListing)))
+		return
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO photos (property_id, room_id, storage_key, thumbnail_key, content_type, size_bytes,
+		                    width, height, position, is_cover, uploaded_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, propertyID, roomID, storageKey, thumbnailKey, upload.contentType, len(upload.data),
+		upload.width, upload.height, count+1, count == 0, uploaderID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error adding photo: %w", err)))
+		return
+	}
+	photoID, err := result.LastInsertId()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error retrieving photo ID: %w", err)))
+		return
+	}
+
+	photo, err := scanPhoto(tx.QueryRowContext(ctx, `SELECT `+photoColumns+` FROM photos WHERE photo_id = ?`, photoID).Scan)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching photo: %w", err)))
+		return
+	}
+
+	err = recordAudit(ctx, tx, r, auditEvent{Action: auditPhotoUpload, EntityType: entityPhoto, EntityID: photoID, After: photo})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+	committed = true
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(photo)
+}
+
+// lockProperty mengunci baris properti di dalam transaksi, 404 jika tidak ada
+func lockProperty(ctx context.Context, tx *sql.Tx, propertyID int) error {
+	var id int
+	err := tx.QueryRowContext(ctx, `SELECT property_id FROM properties WHERE property_id = ? FOR UPDATE`, propertyID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apierror.NotFound("Property not found")
+	}
+	if err != nil {
+		return apierror.Internal(fmt.Errorf("error fetching property: %w", err))
+	}
+	return nil
+}
+
+// ListPropertyPhotos menampilkan foto properti sesuai urutan
+func ListPropertyPhotos(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var propertyID int
+	if _, err := pathInt(r, "id", &propertyID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	photos, err := listPhotos(ctx, db, propertyID, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(photos)
+}
+
+// ListRoomPhotos menampilkan foto kamar sesuai urutan
+func ListRoomPhotos(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var roomID int
+	if _, err := pathInt(r, "id", &roomID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	photos, err := listPhotos(ctx, db, 0, &roomID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(photos)
+}
+
+// getPhoto membaca satu foto dan memastikan pengguna boleh mengelola propertinya
+func getPhoto(ctx context.Context, db *sql.DB, r *http.Request) (Photo, error) {
+	var photoID int
+	if _, err := pathInt(r, "id", &photoID); err != nil {
+		return Photo{}, err
+	}
+	photo, err := scanPhoto(db.QueryRowContext(ctx, `SELECT `+photoColumns+` FROM photos WHERE photo_id = ?`, photoID).Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return photo, apierror.NotFound("Photo not found")
+	}
+	if err != nil {
+		return photo, apierror.Internal(fmt.Errorf("error fetching photo: %w", err))
+	}
+
+	allowed, err := canManageProperty(ctx, db, r, photo.PropertyID)
+	if err != nil {
+		return photo, apierror.Internal(fmt.Errorf("error checking property access: %w", err))
+	}
+	if !allowed {
+		return photo, apierror.Forbidden("Forbidden: You are not assigned to this property")
+	}
+	return photo, nil
+}
+
+// UpdatePhoto memindahkan foto ke urutan lain dan/atau menjadikannya cover
+func UpdatePhoto(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var req UpdatePhotoRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	photo, err := getPhoto(ctx, db, r)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	if err := lockProperty(ctx, tx, photo.PropertyID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	photos, err := listPhotos(ctx, tx, photo.PropertyID, photo.RoomID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	// Urutan dibangun ulang: foto dikeluarkan lalu disisipkan di posisi baru
+	order := make([]int, 0, len(photos))
+	found := false
+	for _, p := range photos {
+		if p.PhotoID != photo.PhotoID {
+			order = append(order, p.PhotoID)
+		} else {
+			photo, found = p, true
+		}
+	}
+	if !found {
+		// Foto terhapus oleh request lain sebelum baris properti terkunci
+		apierror.Write(w, r, apierror.NotFound("Photo not found"))
+		return
+	}
+	before := UpdatePhotoRequest{Position: &photo.Position, Cover: &photo.IsCover}
+	if req.Position != nil {
+		index := min(*req.Position, len(photos)) - 1
+		order = append(order[:index], append([]int{photo.PhotoID}, order[index:]...)...)
+		for i, id := range order {
+			if _, err := tx.ExecContext(ctx, `UPDATE photos SET position = ? WHERE photo_id = ?`, i+1, id); err != nil {
+				apierror.Write(w, r, apierror.Internal(fmt.Errorf("error reordering photos: %w", err)))
+				return
+			}
+		}
+		photo.Position = index + 1
+	}
+
+	if req.Cover != nil {
+		scope, args := photoScope(photo.PropertyID, photo.RoomID)
+		query := `UPDATE photos SET is_cover = (photo_id = ?) WHERE ` + scope
+		if !*req.Cover {
+			query = `UPDATE photos SET is_cover = 0 WHERE photo_id = ? AND ` + scope
+		}
+		if _, err := tx.ExecContext(ctx, query, append([]interface{}{photo.PhotoID}, args...)...); err != nil {
+			apierror.Write(w, r, apierror.Internal(fmt.Errorf("error updating cover photo: %w", err)))
+			return
+		}
+		photo.IsCover = *req.Cover
+	}
+
+	err = recordAudit(ctx, tx, r, auditEvent{
+		Action:     auditPhotoUpdate,
+		EntityType: entityPhoto,
+		EntityID:   photo.PhotoID,
+		Before:     before,
+		After:      UpdatePhotoRequest{Position: &photo.Position, Cover: &photo.IsCover},
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(photo)
+}
+
+// DeletePhoto menghapus foto beserta file-nya. Jika foto tersebut cover,
+// foto pertama yang tersisa menjadi cover baru.
+func DeletePhoto(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	photo, err := getPhoto(ctx, db, r)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	if err := lockProperty(ctx, tx, photo.PropertyID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	var storageKey, thumbnailKey string
+	var position int
+	var isCover bool
+	err = tx.QueryRowContext(ctx, `SELECT storage_key, thumbnail_key, position, is_cover FROM photos WHERE photo_id = ?`, photo.PhotoID).
+		Scan(&storageKey, &thumbnailKey, &position, &isCover)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.Write(w, r, apierror.NotFound("Photo not found"))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching photo: %w", err)))
+		return
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM photos WHERE photo_id = ?`, photo.PhotoID); err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error deleting photo: %w", err)))
+		return
+	}
+	scope, args := photoScope(photo.PropertyID, photo.RoomID)
+	_, err = tx.ExecContext(ctx, `UPDATE photos SET position = position - 1 WHERE position > ? AND `+scope, append([]interface{}{position}, args...)...)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error reordering photos: %w", err)))
+		return
+	}
+	if isCover {
+		_, err = tx.ExecContext(ctx, `UPDATE photos SET is_cover = 1 WHERE position = 1 AND `+scope, args...)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(fmt.Errorf("error updating cover photo: %w", err)))
+			return
+		}
+	}
+
+	err = recordAudit(ctx, tx, r, auditEvent{Action: auditPhotoDelete, EntityType: entityPhoto, EntityID: photo.PhotoID, Before: photo})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	// File dihapus setelah commit; kegagalan di sini hanya menyisakan file yatim
+	for _, key := range []string{storageKey, thumbnailKey} {
+		if err := blobs.Delete(ctx, key); err != nil {
+			logging.FromContext(r.Context()).Warn("error deleting photo file", "key", key, "error", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Message: "Photo deleted successfully"})
+}
+
+// ServeMedia melayani file dari BlobStore. Key selalu unik per unggahan
+// sehingga respons boleh di-cache browser dan CDN tanpa batas waktu.
+func ServeMedia(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	if !blobstore.ValidKey(key) {
+		apierror.Write(w, r, apierror.NotFound("File not found"))
+		return
+	}
+
+	f, info, err := blobs.Open(r.Context(), key)
+	if errors.Is(err, blobstore.ErrNotFound) {
+		apierror.Write(w, r, apierror.NotFound("File not found"))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+	defer f.Close()
+
+	contentType := "application/octet-stream"
+	for typ, ext := range photoTypes {
+		if strings.HasSuffix(key, ext) {
+			contentType = typ
+		}
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", mediaCacheControl)
+	w.Header().Set("ETag", `"`+strings.TrimSuffix(path.Base(key), path.Ext(key))+`"`)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	// ServeContent menangani If-None-Match, If-Modified-Since dan Range
+	http.ServeContent(w, r, "", info.ModTime, f)
+}
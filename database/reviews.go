@@ -0,0 +1,602 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"booking_system_app/apierror"
+	"booking_system_app/middleware"
+	"booking_system_app/validate"
+)
+
+// Status ulasan mengikuti enum kolom reviews.status
+const (
+	ReviewPublished = "published"
+	ReviewHidden    = "hidden"
+)
+
+// maxReviewText adalah panjang maksimum komentar tamu dan balasan staff
+const maxReviewText = 2000
+
+// ReviewRatings adalah nilai 1-5 untuk setiap kategori
+type ReviewRatings struct {
+	Cleanliness int `json:"cleanliness"`
+	Service     int `json:"service"`
+	Location    int `json:"location"`
+}
+
+// overall adalah rata-rata ketiga kategori, dibulatkan dua desimal
+func (r ReviewRatings) overall() float64 {
+	return math.Round(float64(r.Cleanliness+r.Service+r.Location)/3*100) / 100
+}
+
+// Struct untuk request ulasan baru
+type ReviewRequest struct {
+	ReviewRatings
+	Comment string `json:"comment,omitempty"`
+}
+
+// ReviewReply adalah balasan staff properti atas sebuah ulasan
+type ReviewReply struct {
+	Text      string `json:"text"`
+	RepliedAt string `json:"replied_at"`
+}
+
+// Review adalah satu ulasan tamu atas sebuah menginap
+type Review struct {
+	ReviewID     int     `json:"review_id"`
+	BookingID    int     `json:"booking_id"`
+	PropertyID   int     `json:"property_id"`
+	ReviewerName string  `json:"reviewer_name"`
+	Rating       float64 `json:"rating"` // rata-rata ketiga kategori
+	ReviewRatings
+	Comment  string `json:"comment"`
+	StayedAt string `json:"stayed_at" format:"date"` // tanggal check-out
+	Status   string `json:"status"`
+	// Hanya terlihat di daftar moderasi; ulasan hidden tidak tampil publik
+	ModerationReason *string      `json:"moderation_reason,omitempty"`
+	Reply            *ReviewReply `json:"reply"`
+	CreatedAt        string       `json:"created_at"`
+}
+
+// PropertyRating adalah agregat ulasan published sebuah properti
+type PropertyRating struct {
+	Average     float64 `json:"average"`
+	Count       int     `json:"count"`
+	Cleanliness float64 `json:"cleanliness"`
+	Service     float64 `json:"service"`
+	Location    float64 `json:"location"`
+}
+
+// Struct untuk respons daftar ulasan dengan paging
+type ReviewListResponse struct {
+	// Agregat properti, hanya pada GET /properties/{id}/reviews; null jika belum ada ulasan
+	Rating  *PropertyRating `json:"rating,omitempty"`
+	Reviews []Review        `json:"reviews"`
+	PageInfo
+}
+
+// Struct untuk request balasan staff
+type ReviewReplyRequest struct {
+	Reply string `json:"reply"`
+}
+
+// Struct untuk request moderasi ulasan
+type ModerateReviewRequest struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"` // hanya disimpan untuk status hidden
+}
+
+// reviewListSpec: sort berdasarkan waktu ulasan (default terbaru) atau rating
+var reviewListSpec = listSpec{
+	sorts: map[string]sortKey{
+		"created_at": {"rv.created_at", sortTime},
+		"rating":     {"rv.rating", sortNumber},
+	},
+	defaultSort: "-created_at",
+	idColumn:    "rv.review_id",
+}
+
+const reviewSelect = `
+	SELECT rv.review_id, rv.booking_id, rv.property_id, COALESCE(u.name, ''), rv.rating,
+	       rv.cleanliness, rv.service, rv.location, COALESCE(rv.comment, ''), b.check_out_date,
+	       rv.status, rv.moderation_reason, rv.reply, rv.replied_at, rv.created_at
+	FROM reviews rv
+	JOIN bookings b ON rv.booking_id = b.booking_id
+	LEFT JOIN users u ON rv.user_id = u.user_id`
+
+func scanReview(scan func(dest ...interface{}) error) (Review, error) {
+	var review Review
+	var stayedAt time.Time
+	var reason, reply, repliedAt sql.NullString
+	err := scan(&review.ReviewID, &review.BookingID, &review.PropertyID, &review.ReviewerName, &review.Rating,
+		&review.Cleanliness, &review.Service, &review.Location, &review.Comment, &stayedAt,
+		&review.Status, &reason, &reply, &repliedAt, &review.CreatedAt)
+	if err != nil {
+		return review, err
+	}
+	review.StayedAt = stayedAt.Format(validate.DateLayout)
+	review.ModerationReason = nullStringPtr(reason)
+	if reply.Valid {
+		review.Reply = &ReviewReply{Text: reply.String, RepliedAt: repliedAt.String}
+	}
+	return review, nil
+}
+
+// queryReview membaca satu ulasan berdasarkan ID
+func queryReview(ctx context.Context, q rowQuerier, reviewID int) (Review, error) {
+	return scanReview(q.QueryRowContext(ctx, reviewSelect+` WHERE rv.review_id = ?`, reviewID).Scan)
+}
+
+// refreshPropertyRating menghitung ulang agregat rating properti dari ulasan
+// published. Baris properti harus sudah dikunci (lockProperty) agar dua
+// perubahan bersamaan tidak saling menimpa agregat.
+func refreshPropertyRating(ctx context.Context, tx *sql.Tx, propertyID int) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE properties p
+		LEFT JOIN (
+			SELECT property_id, COUNT(*) AS n, AVG(rating) AS average,
+			       AVG(cleanliness) AS cleanliness, AVG(service) AS service, AVG(location) AS location
+			FROM reviews
+			WHERE property_id = ? AND status = ?
+			GROUP BY property_id
+		) s ON s.property_id = p.property_id
+		SET p.rating_count = COALESCE(s.n, 0), p.rating_average = s.average,
+		    p.rating_cleanliness = s.cleanliness, p.rating_service = s.service, p.rating_location = s.location
+		WHERE p.property_id = ?
+	`, propertyID, ReviewPublished, propertyID)
+	if err != nil {
+		return fmt.Errorf("error updating property rating: %w", err)
+	}
+	return nil
+}
+
+// propertyRating mengubah kolom agregat properti menjadi PropertyRating,
+// nil jika properti belum punya ulasan
+func propertyRating(count int, average, cleanliness, service, location sql.NullFloat64) *PropertyRating {
+	if count == 0 || !average.Valid {
+		return nil
+	}
+	return &PropertyRating{
+		Average:     average.Float64,
+		Count:       count,
+		Cleanliness: cleanliness.Float64,
+		Service:     service.Float64,
+		Location:    location.Float64,
+	}
+}
+
+// CreateReview menulis ulasan untuk booking milik pengguna yang sedang login.
+// Hanya booking berstatus completed yang bisa diulas, masing-masing sekali.
+func CreateReview(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var bookingID int
+	if _, err := pathInt(r, "id", &bookingID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	var req ReviewRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	userID, err := getUserIDByEmail(ctx, db, middleware.UserEmail(r))
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching reviewer: %w", err)))
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	// Booking milik orang lain dijawab 404 seperti GetBooking
+	booking, err := queryBooking(ctx, tx, bookingID, "FOR UPDATE")
+	if errors.Is(err, sql.ErrNoRows) || err == nil && booking.UserID != userID {
+		apierror.Write(w, r, apierror.NotFound("Booking not found"))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching booking: %w", err)))
+		return
+	}
+	if booking.Status != BookingCompleted {
+		apierror.Write(w, r, apierror.Conflict("Only completed stays can be reviewed"))
+		return
+	}
+
+	if err := lockProperty(ctx, tx, booking.PropertyID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	var comment *string
+	if req.Comment != "" {
+		comment = &req.Comment
+	}
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO reviews (booking_id, user_id, property_id, cleanliness, service, location, rating, comment)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, bookingID, userID, booking.PropertyID, req.Cleanliness, req.Service, req.Location, req.overall(), comment)
+	if isDuplicateEntry(err) {
+		apierror.Write(w, r, apierror.Conflict("This booking has already been reviewed"))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error adding review: %w", err)))
+		return
+	}
+	reviewID, err := result.LastInsertId()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error retrieving review ID: %w", err)))
+		return
+	}
+
+	if err := refreshPropertyRating(ctx, tx, booking.PropertyID); err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	review, err := queryReview(ctx, tx, int(reviewID))
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching review: %w", err)))
+		return
+	}
+
+	// Nama pengulas adalah data pribadi dan tidak ikut dicatat di audit
+	snapshot := review
+	snapshot.ReviewerName = ""
+	err = recordAudit(ctx, tx, r, auditEvent{Action: auditReviewCreate, EntityType: entityReview, EntityID: reviewID, After: snapshot})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(review)
+}
+
+// listReviews membaca satu halaman ulasan yang cocok dengan conditions
+func listReviews(ctx context.Context, db *sql.DB, r *http.Request, conditions []string, args []interface{}) (ReviewListResponse, error) {
+	var resp ReviewListResponse
+	list, err := reviewListSpec.parse(listOptionsFromQuery(r))
+	if err != nil {
+		return resp, err
+	}
+
+	var total int
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM reviews rv `+sqlConditions(conditions), args...).Scan(&total)
+	if err != nil {
+		return resp, apierror.Internal(fmt.Errorf("error counting reviews: %w", err))
+	}
+
+	if after, afterArgs := list.where(); after != "" {
+		conditions = append(conditions, after)
+		args = append(args, afterArgs...)
+	}
+	orderBy, limitArgs := list.orderBy()
+	rows, err := db.QueryContext(ctx, reviewSelect+" "+sqlConditions(conditions)+" "+orderBy, append(args, limitArgs...)...)
+	if err != nil {
+		return resp, apierror.Internal(fmt.Errorf("error listing reviews: %w", err))
+	}
+	defer rows.Close()
+
+	resp.Reviews = []Review{}
+	fetched := 0
+	for rows.Next() {
+		review, err := scanReview(rows.Scan)
+		if err != nil {
+			return resp, apierror.Internal(fmt.Errorf("error reading reviews: %w", err))
+		}
+		fetched++
+		if fetched <= list.limit {
+			resp.Reviews = append(resp.Reviews, review)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return resp, apierror.Internal(fmt.Errorf("error reading reviews: %w", err))
+	}
+
+	resp.PageInfo = list.pageInfo(total, fetched, func() (interface{}, int64) {
+		last := resp.Reviews[len(resp.Reviews)-1]
+		if list.field() == "rating" {
+			return last.Rating, int64(last.ReviewID)
+		}
+		return last.CreatedAt, int64(last.ReviewID)
+	})
+	return resp, nil
+}
+
+// ListPropertyReviews menampilkan ulasan published sebuah properti beserta
+// agregat ratingnya. Sort: -created_at (default), rating.
+func ListPropertyReviews(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var propertyID int
+	if _, err := pathInt(r, "id", &propertyID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	var count int
+	var average, cleanliness, service, location sql.NullFloat64
+	err := db.QueryRowContext(ctx, `
+		SELECT rating_count, rating_average, rating_cleanliness, rating_service, rating_location
+		FROM properties WHERE property_id = ?
+	`, propertyID).Scan(&count, &average, &cleanliness, &service, &location)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.Write(w, r, apierror.NotFound("Property not found"))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching property: %w", err)))
+		return
+	}
+
+	resp, err := listReviews(ctx, db, r, []string{"rv.property_id = ?", "rv.status = ?"}, []interface{}{propertyID, ReviewPublished})
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	resp.Rating = propertyRating(count, average, cleanliness, service, location)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ListReviews menampilkan semua ulasan untuk moderasi, bisa difilter dengan
+// status dan property_id. Sort: -created_at (default), rating.
+func ListReviews(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var conditions []string
+	var args []interface{}
+	if status := r.URL.Query().Get("status"); status != "" {
+		var v validate.Validator
+		v.OneOf("status", status, ReviewPublished, ReviewHidden)
+		if err := v.Err(); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		conditions = append(conditions, "rv.status = ?")
+		args = append(args, status)
+	}
+	if propertyID, _ := strconv.Atoi(r.URL.Query().Get("property_id")); propertyID != 0 {
+		conditions = append(conditions, "rv.property_id = ?")
+		args = append(args, propertyID)
+	}
+
+	resp, err := listReviews(ctx, db, r, conditions, args)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ReplyToReview menulis atau mengganti balasan staff atas sebuah ulasan.
+// Staff hanya bisa membalas ulasan properti yang ditugaskan kepadanya.
+func ReplyToReview(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var reviewID int
+	if _, err := pathInt(r, "id", &reviewID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	var req ReviewReplyRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	review, err := queryReview(ctx, db, reviewID)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.Write(w, r, apierror.NotFound("Review not found"))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching review: %w", err)))
+		return
+	}
+
+	allowed, err := canManageProperty(ctx, db, r, review.PropertyID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error checking property access: %w", err)))
+		return
+	}
+	if !allowed {
+		apierror.Write(w, r, apierror.Forbidden("Forbidden: You are not assigned to this property"))
+		return
+	}
+
+	replierID, err := getUserIDByEmail(ctx, db, middleware.UserEmail(r))
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching replier: %w", err)))
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE reviews SET reply = ?, replied_by = ?, replied_at = CURRENT_TIMESTAMP WHERE review_id = ?
+	`, req.Reply, replierID, reviewID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error saving reply: %w", err)))
+		return
+	}
+
+	updated, err := queryReview(ctx, tx, reviewID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching review: %w", err)))
+		return
+	}
+
+	err = recordAudit(ctx, tx, r, auditEvent{
+		Action:     auditReviewReply,
+		EntityType: entityReview,
+		EntityID:   reviewID,
+		Before:     review.Reply,
+		After:      updated.Reply,
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// ModerateReview menyembunyikan atau menampilkan kembali ulasan. Ulasan
+// hidden tidak tampil publik dan tidak dihitung di rating properti.
+func ModerateReview(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var reviewID int
+	if _, err := pathInt(r, "id", &reviewID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	var req ModerateReviewRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	moderatorID, err := getUserIDByEmail(ctx, db, middleware.UserEmail(r))
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching moderator: %w", err)))
+		return
+	}
+
+	review, err := queryReview(ctx, db, reviewID)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.Write(w, r, apierror.NotFound("Review not found"))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching review: %w", err)))
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	if err := lockProperty(ctx, tx, review.PropertyID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	// Alasan moderasi dihapus saat ulasan diterbitkan kembali agar tidak ikut
+	// tampil di daftar ulasan publik
+	if req.Status == ReviewPublished {
+		req.Reason = ""
+	}
+	var reason *string
+	if req.Reason != "" {
+		reason = &req.Reason
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE reviews SET status = ?, moderation_reason = ?, moderated_by = ?, moderated_at = CURRENT_TIMESTAMP
+		WHERE review_id = ?
+	`, req.Status, reason, moderatorID, reviewID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error moderating review: %w", err)))
+		return
+	}
+	if err := refreshPropertyRating(ctx, tx, review.PropertyID); err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	updated, err := queryReview(ctx, tx, reviewID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching review: %w", err)))
+		return
+	}
+
+	before := ModerateReviewRequest{Status: review.Status}
+	if review.ModerationReason != nil {
+		before.Reason = *review.ModerationReason
+	}
+	err = recordAudit(ctx, tx, r, auditEvent{
+		Action:     auditReviewModerate,
+		EntityType: entityReview,
+		EntityID:   reviewID,
+		Before:     before,
+		After:      req,
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"net/http"
+	"testing"
+
+	"booking_system_app/middleware"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSearchRoomsLegacyMatchesRoomTypeSubstring(t *testing.T) {
+	db, mock := newMockDB(t)
+	expectAuth(mock, "guest@example.com", RoleCustomer)
+	// Nilai di luar daftar tipe kamar tetap diterima dan wildcard di-escape
+	mock.ExpectQuery(`FROM rooms r\s+JOIN properties p ON r.property_id = p.property_id\s+WHERE r.price_per_night >= \? AND r.room_type LIKE \? ESCAPE`).
+		WithArgs(0.0, `%sui\_%`).
+		WillReturnRows(sqlmock.NewRows([]string{"room_id"}))
+
+	req := newAuthRequest(t, http.MethodPost, "/search_rooms", `{"room_type": "sui_"}`, "guest@example.com")
+	rec := serveWith(db, middleware.PermRoomSearch, SearchRoomsLegacy, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var results []RoomSearchResult
+	decodeBody(t, rec, &results)
+	if results == nil || len(results) != 0 {
+		t.Errorf("results = %v, want empty array", results)
+	}
+}
+
+func TestSearchRoomsRequiresKnownRoomType(t *testing.T) {
+	db, mock := newMockDB(t)
+	expectAuth(mock, "guest@example.com", RoleCustomer)
+
+	req := newAuthRequest(t, http.MethodPost, "/rooms/search", `{"room_type": "sui"}`, "guest@example.com")
+	rec := serveWith(db, middleware.PermRoomSearch, SearchRooms, req)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusUnprocessableEntity, rec.Body)
+	}
+	if code := errorCode(t, rec); code != "validation_failed" {
+		t.Errorf("error code = %q, want validation_failed", code)
+	}
+}
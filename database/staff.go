@@ -0,0 +1,296 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"booking_system_app/apierror"
+	"booking_system_app/middleware"
+)
+
+// Struct untuk request penugasan staff ke properti
+type StaffAssignmentRequest struct {
+	UserID     int `json:"user_id"`
+	PropertyID int `json:"property_id"`
+}
+
+// Struct untuk data penugasan staff
+type StaffAssignment struct {
+	UserID       int    `json:"user_id"`
+	StaffName    string `json:"staff_name"`
+	PropertyID   int    `json:"property_id"`
+	PropertyName string `json:"property_name"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// Struct untuk respons daftar penugasan staff dengan paging
+type StaffAssignmentListResponse struct {
+	Assignments []StaffAssignment `json:"assignments"`
+	PageInfo
+}
+
+// staffAssignmentListSpec: (property_id, user_id) unik sehingga user_id cukup
+// sebagai pemecah seri di dalam satu properti
+var staffAssignmentListSpec = listSpec{
+	sorts:       map[string]sortKey{"property": {"a.property_id", sortNumber}},
+	defaultSort: "property",
+	idColumn:    "a.user_id",
+}
+
+// staffAssignmentID adalah ID entitas penugasan di audit log: "<property_id>:<user_id>"
+func staffAssignmentID(req StaffAssignmentRequest) string {
+	return fmt.Sprintf("%d:%d", req.PropertyID, req.UserID)
+}
+
+// canManageProperty memeriksa apakah pengguna yang sedang login boleh mengelola properti.
+// Admin boleh mengelola semua properti, staff hanya properti yang ditugaskan kepadanya.
+// q bisa berupa tx agar pemeriksaan ikut transaksi yang sedang memegang lock.
+func canManageProperty(ctx context.Context, q rowQuerier, r *http.Request, propertyID int) (bool, error) {
+	switch middleware.UserRole(r) {
+	case RoleAdmin:
+		return true, nil
+	case RoleStaff:
+		var count int
+		query := `
+			SELECT COUNT(*) FROM staff_property_assignments a
+			JOIN users u ON a.user_id = u.user_id
+			WHERE u.email = ? AND a.property_id = ?
+		`
+		err := q.QueryRowContext(ctx, query, middleware.UserEmail(r), propertyID).Scan(&count)
+		if err != nil {
+			return false, err
+		}
+		return count > 0, nil
+	}
+	return false, nil
+}
+
+// getPropertyIDForRoom mengambil property_id pemilik kamar
+func getPropertyIDForRoom(ctx context.Context, db *sql.DB, roomID int) (int, error) {
+	var propertyID int
+	err := db.QueryRowContext(ctx, `SELECT property_id FROM rooms WHERE room_id = ?`, roomID).Scan(&propertyID)
+	return propertyID, err
+}
+
+// AssignStaff menugaskan seorang staff ke properti. Penugasan yang sudah ada
+// dijawab 200 tanpa perubahan, properti yang tidak ada dijawab 404.
+func AssignStaff(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var req StaffAssignmentRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	var role string
+	err = db.QueryRowContext(ctx, `SELECT role FROM users WHERE user_id = ?`, req.UserID).Scan(&role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			apierror.Write(w, r, apierror.NotFound("User not found"))
+			return
+		}
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching user: %w", err)))
+		return
+	}
+	if role != RoleStaff {
+		apierror.Write(w, r, apierror.BadRequest("Only staff users can be assigned to a property"))
+		return
+	}
+
+	adminID, err := getUserIDByEmail(ctx, db, middleware.UserEmail(r))
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error identifying admin: %w", err)))
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	// Properti dikunci agar tidak terhapus sebelum penugasan tersimpan
+	if err := lockProperty(ctx, tx, req.PropertyID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	query := `INSERT INTO staff_property_assignments (user_id, property_id, assigned_by) VALUES (?, ?, ?)`
+	_, err = tx.ExecContext(ctx, query, req.UserID, req.PropertyID, adminID)
+	if isDuplicateEntry(err) {
+		// Penugasan yang sudah ada dianggap berhasil dan tidak dicatat ulang di audit log
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Response{Message: "Staff is already assigned to property"})
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error assigning staff: %w", err)))
+		return
+	}
+
+	err = recordAudit(ctx, tx, r, auditEvent{
+		Action:     auditStaffAssign,
+		EntityType: entityStaffAssignment,
+		EntityID:   staffAssignmentID(req),
+		After:      req,
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Response{Message: "Staff assigned to property successfully"})
+}
+
+// UnassignStaff mencabut penugasan staff dari properti
+func UnassignStaff(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var req StaffAssignmentRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM staff_property_assignments WHERE user_id = ? AND property_id = ?`, req.UserID, req.PropertyID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error removing assignment: %w", err)))
+		return
+	}
+
+	affected, err := result.RowsAffected()
+	if err == nil && affected == 0 {
+		apierror.Write(w, r, apierror.NotFound("Assignment not found"))
+		return
+	}
+
+	err = recordAudit(ctx, tx, r, auditEvent{
+		Action:     auditStaffUnassign,
+		EntityType: entityStaffAssignment,
+		EntityID:   staffAssignmentID(req),
+		Before:     req,
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Message: "Staff removed from property successfully"})
+}
+
+// ListStaffAssignments menampilkan penugasan staff, bisa difilter dengan user_id atau property_id.
+// Sort: property (default), -property.
+func ListStaffAssignments(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	list, err := staffAssignmentListSpec.parse(listOptionsFromQuery(r))
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	var conditions []string
+	var args []interface{}
+	if userID, _ := strconv.Atoi(r.URL.Query().Get("user_id")); userID != 0 {
+		conditions = append(conditions, "a.user_id = ?")
+		args = append(args, userID)
+	}
+	if propertyID, _ := strconv.Atoi(r.URL.Query().Get("property_id")); propertyID != 0 {
+		conditions = append(conditions, "a.property_id = ?")
+		args = append(args, propertyID)
+	}
+
+	var total int
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM staff_property_assignments a `+sqlConditions(conditions), args...).Scan(&total)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error counting assignments: %w", err)))
+		return
+	}
+
+	if after, afterArgs := list.where(); after != "" {
+		conditions = append(conditions, after)
+		args = append(args, afterArgs...)
+	}
+	orderBy, limitArgs := list.orderBy()
+	query := `
+		SELECT a.user_id, u.name, a.property_id, p.name, a.created_at
+		FROM staff_property_assignments a
+		JOIN users u ON a.user_id = u.user_id
+		JOIN properties p ON a.property_id = p.property_id
+		` + sqlConditions(conditions) + `
+		` + orderBy
+	rows, err := db.QueryContext(ctx, query, append(args, limitArgs...)...)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error listing assignments: %w", err)))
+		return
+	}
+	defer rows.Close()
+
+	assignments := []StaffAssignment{}
+	fetched := 0
+	for rows.Next() {
+		var assignment StaffAssignment
+		var staffName, propertyName sql.NullString
+		err := rows.Scan(&assignment.UserID, &staffName, &assignment.PropertyID, &propertyName, &assignment.CreatedAt)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(fmt.Errorf("error reading assignments: %w", err)))
+			return
+		}
+		assignment.StaffName = staffName.String
+		assignment.PropertyName = propertyName.String
+		fetched++
+		if fetched <= list.limit {
+			assignments = append(assignments, assignment)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error reading assignments: %w", err)))
+		return
+	}
+
+	page := list.pageInfo(total, fetched, func() (interface{}, int64) {
+		last := assignments[len(assignments)-1]
+		return last.PropertyID, int64(last.UserID)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(StaffAssignmentListResponse{Assignments: assignments, PageInfo: page})
+}
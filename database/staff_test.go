@@ -0,0 +1,192 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"booking_system_app/middleware"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+)
+
+const staffAssignmentQuery = `SELECT COUNT\(\*\) FROM staff_property_assignments a`
+
+func TestCanManageProperty(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     string
+		assigned int // -1 berarti query penugasan tidak diharapkan
+		want     bool
+	}{
+		{"admin manages every property", RoleAdmin, -1, true},
+		{"assigned staff", RoleStaff, 1, true},
+		{"unassigned staff", RoleStaff, 0, false},
+		{"customer", RoleCustomer, -1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			expectAuth(mock, "user@example.com", tt.role)
+			if tt.assigned >= 0 {
+				mock.ExpectQuery(staffAssignmentQuery).
+					WithArgs("user@example.com", 7).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.assigned))
+			}
+
+			var got bool
+			var err error
+			handler := func(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+				got, err = canManageProperty(context.Background(), db, r, 7)
+			}
+			req := newAuthRequest(t, http.MethodGet, "/", "", "user@example.com")
+			serveWith(db, middleware.PermProfileManage, handler, req)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("canManageProperty = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdatePropertyLocationStaffScope(t *testing.T) {
+	body := `{"latitude": -6.2, "longitude": 106.8}`
+
+	t.Run("customer lacks permission", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectAuth(mock, "guest@example.com", RoleCustomer)
+
+		req := newAuthRequest(t, http.MethodPut, "/properties/7/location", body, "guest@example.com")
+		req.SetPathValue("id", "7")
+		rec := serveWith(db, middleware.PermPropertyWrite, UpdatePropertyLocation, req)
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+	})
+
+	t.Run("staff of another property", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectAuth(mock, "staff@example.com", RoleStaff)
+		mock.ExpectQuery(staffAssignmentQuery).
+			WithArgs("staff@example.com", 7).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+		req := newAuthRequest(t, http.MethodPut, "/properties/7/location", body, "staff@example.com")
+		req.SetPathValue("id", "7")
+		rec := serveWith(db, middleware.PermPropertyWrite, UpdatePropertyLocation, req)
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+		if code := errorCode(t, rec); code != "forbidden" {
+			t.Errorf("code = %q, want forbidden", code)
+		}
+	})
+
+	t.Run("assigned staff reaches the update", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectAuth(mock, "staff@example.com", RoleStaff)
+		mock.ExpectQuery(staffAssignmentQuery).
+			WithArgs("staff@example.com", 7).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT latitude, longitude FROM properties WHERE property_id = \? FOR UPDATE`).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"latitude", "longitude"}))
+		mock.ExpectRollback()
+
+		req := newAuthRequest(t, http.MethodPut, "/properties/7/location", body, "staff@example.com")
+		req.SetPathValue("id", "7")
+		rec := serveWith(db, middleware.PermPropertyWrite, UpdatePropertyLocation, req)
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestAssignStaff(t *testing.T) {
+	// expectAssignment mendaftarkan query sampai sebelum INSERT penugasan
+	expectAssignment := func(mock sqlmock.Sqlmock, propertyRows *sqlmock.Rows) {
+		expectAuth(mock, "admin@example.com", RoleAdmin)
+		mock.ExpectQuery(`SELECT role FROM users WHERE user_id = \?`).
+			WithArgs(5).
+			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(RoleStaff))
+		mock.ExpectQuery(`SELECT user_id FROM users WHERE email = \?`).
+			WithArgs("admin@example.com").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT property_id FROM properties WHERE property_id = \? FOR UPDATE`).
+			WithArgs(7).
+			WillReturnRows(propertyRows)
+	}
+	const insert = `INSERT INTO staff_property_assignments \(user_id, property_id, assigned_by\)`
+	body := `{"user_id": 5, "property_id": 7}`
+
+	t.Run("new assignment is audited", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectAssignment(mock, sqlmock.NewRows([]string{"property_id"}).AddRow(7))
+		mock.ExpectExec(insert).WithArgs(5, 7, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO audit_logs`).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		req := newAuthRequest(t, http.MethodPost, "/admin/staff_assignments", body, "admin@example.com")
+		rec := serveWith(db, middleware.PermUserManage, AssignStaff, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+		}
+	})
+
+	t.Run("existing assignment is idempotent", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectAssignment(mock, sqlmock.NewRows([]string{"property_id"}).AddRow(7))
+		mock.ExpectExec(insert).WithArgs(5, 7, 1).WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+		mock.ExpectRollback()
+
+		req := newAuthRequest(t, http.MethodPost, "/admin/staff_assignments", body, "admin@example.com")
+		rec := serveWith(db, middleware.PermUserManage, AssignStaff, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+		}
+	})
+
+	t.Run("unknown property", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectAssignment(mock, sqlmock.NewRows([]string{"property_id"}))
+		mock.ExpectRollback()
+
+		req := newAuthRequest(t, http.MethodPost, "/admin/staff_assignments", body, "admin@example.com")
+		rec := serveWith(db, middleware.PermUserManage, AssignStaff, req)
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusNotFound, rec.Body)
+		}
+	})
+
+	t.Run("foreign key errors are not hidden", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectAssignment(mock, sqlmock.NewRows([]string{"property_id"}).AddRow(7))
+		mock.ExpectExec(insert).WithArgs(5, 7, 1).WillReturnError(&mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row"})
+		mock.ExpectRollback()
+
+		req := newAuthRequest(t, http.MethodPost, "/admin/staff_assignments", body, "admin@example.com")
+		rec := serveWith(db, middleware.PermUserManage, AssignStaff, req)
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusInternalServerError, rec.Body)
+		}
+	})
+
+	t.Run("field errors", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectAuth(mock, "admin@example.com", RoleAdmin)
+
+		req := newAuthRequest(t, http.MethodPost, "/admin/staff_assignments", `{"user_id": 0, "property_id": -1}`, "admin@example.com")
+		rec := serveWith(db, middleware.PermUserManage, AssignStaff, req)
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusUnprocessableEntity, rec.Body)
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tujuan token yang disimpan di tabel user_tokens
+const (
+	tokenPurposeEmailChange       = "email_change"
+	tokenPurposeEmailVerification = "email_verification"
+	tokenPurposePasswordReset     = "password_reset"
+)
+
+// Masa berlaku token berdasarkan tujuannya
+var tokenTTL = map[string]time.Duration{
+	tokenPurposeEmailChange:       24 * time.Hour,
+	tokenPurposeEmailVerification: 48 * time.Hour,
+	tokenPurposePasswordReset:     time.Hour,
+}
+
+var errInvalidToken = errors.New("invalid or expired token")
+
+// issueToken membuat token bertanda tangan HMAC dengan format
+// base64(purpose|user_id|expires|nonce).base64(signature).
+// Hash token disimpan di user_tokens agar token hanya bisa dipakai sekali.
+func issueToken(ctx context.Context, db *sql.DB, userID int, purpose, payload string) (string, error) {
+	nonce, _, err := newRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	ttl := tokenTTL[purpose]
+	expiresAt := time.Now().Add(ttl)
+	body := strings.Join([]string{purpose, strconv.Itoa(userID), strconv.FormatInt(expiresAt.Unix(), 10), nonce}, "|")
+	token := base64.RawURLEncoding.EncodeToString([]byte(body)) + "." + base64.RawURLEncoding.EncodeToString(signToken(body))
+
+	var payloadValue interface{}
+	if payload != "" {
+		payloadValue = payload
+	}
+
+	query := `INSERT INTO user_tokens (user_id, purpose, token_hash, payload, expires_at)
+	          VALUES (?, ?, ?, ?, DATE_ADD(NOW(), INTERVAL ? SECOND))`
+	_, err = db.ExecContext(ctx, query, userID, purpose, hashToken(token), payloadValue, int(ttl.Seconds()))
+	if err != nil {
+		return "", fmt.Errorf("error storing token: %v", err)
+	}
+	return token, nil
+}
+
+// parseToken memeriksa tanda tangan, tujuan dan masa berlaku token tanpa menyentuh database
+func parseToken(token, purpose string) (int, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return 0, errInvalidToken
+	}
+
+	body, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return 0, errInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(signature, signToken(string(body))) {
+		return 0, errInvalidToken
+	}
+
+	fields := strings.Split(string(body), "|")
+	if len(fields) != 4 || fields[0] != purpose {
+		return 0, errInvalidToken
+	}
+
+	userID, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, errInvalidToken
+	}
+	expires, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return 0, errInvalidToken
+	}
+	return userID, nil
+}
+
+// consumeToken memvalidasi token lalu menandainya sudah dipakai di dalam transaksi tx.
+// Mengembalikan user_id pemilik token dan payload yang disimpan saat token dibuat.
+func consumeToken(ctx context.Context, tx *sql.Tx, token, purpose string) (int, string, error) {
+	userID, err := parseToken(token, purpose)
+	if err != nil {
+		return 0, "", err
+	}
+
+	var tokenID int
+	var payload sql.NullString
+	query := `
+		SELECT user_token_id, payload FROM user_tokens
+		WHERE user_id = ? AND purpose = ? AND token_hash = ? AND used_at IS NULL AND expires_at > NOW()
+		FOR UPDATE
+	`
+	err = tx.QueryRowContext(ctx, query, userID, purpose, hashToken(token)).Scan(&tokenID, &payload)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, "", errInvalidToken
+		}
+		return 0, "", fmt.Errorf("error verifying token: %v", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE user_tokens SET used_at = NOW() WHERE user_token_id = ?`, tokenID)
+	if err != nil {
+		return 0, "", fmt.Errorf("error consuming token: %v", err)
+	}
+	return userID, payload.String, nil
+}
+
+// signToken menghitung tanda tangan HMAC-SHA256 dari isi token
+func signToken(body string) []byte {
+	mac := hmac.New(sha256.New, secretKey)
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signTestToken membuat token dengan format yang sama seperti issueToken
+func signTestToken(purpose, userID string, expires time.Time) string {
+	body := strings.Join([]string{purpose, userID, strconv.FormatInt(expires.Unix(), 10), "nonce"}, "|")
+	return base64.RawURLEncoding.EncodeToString([]byte(body)) + "." + base64.RawURLEncoding.EncodeToString(signToken(body))
+}
+
+func TestParseToken(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	valid := signTestToken(tokenPurposePasswordReset, "42", future)
+	body, _, _ := strings.Cut(valid, ".")
+	forgedBody := base64.RawURLEncoding.EncodeToString([]byte(tokenPurposePasswordReset + "|1|" + strconv.FormatInt(future.Unix(), 10) + "|nonce"))
+	_, signature, _ := strings.Cut(valid, ".")
+
+	tests := []struct {
+		name    string
+		token   string
+		purpose string
+		wantID  int
+		wantErr bool
+	}{
+		{"valid", valid, tokenPurposePasswordReset, 42, false},
+		{"other purpose", valid, tokenPurposeEmailVerification, 0, true},
+		{"expired", signTestToken(tokenPurposePasswordReset, "42", time.Now().Add(-time.Minute)), tokenPurposePasswordReset, 0, true},
+		{"body swapped", forgedBody + "." + signature, tokenPurposePasswordReset, 0, true},
+		{"signature tampered", body + ".AAAA", tokenPurposePasswordReset, 0, true},
+		{"signature not base64", body + ".!!!", tokenPurposePasswordReset, 0, true},
+		{"missing signature", body, tokenPurposePasswordReset, 0, true},
+		{"non-numeric user", signTestToken(tokenPurposePasswordReset, "abc", future), tokenPurposePasswordReset, 0, true},
+		{"empty", "", tokenPurposePasswordReset, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID, err := parseToken(tt.token, tt.purpose)
+			if tt.wantErr {
+				if !errors.Is(err, errInvalidToken) {
+					t.Errorf("err = %v, want errInvalidToken", err)
+				}
+				return
+			}
+			if err != nil || userID != tt.wantID {
+				t.Errorf("parseToken = (%d, %v), want (%d, nil)", userID, err, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestSignTokenDependsOnSecretKey(t *testing.T) {
+	original := signToken("body")
+	defer func(key []byte) { secretKey = key }(secretKey)
+	secretKey = []byte("another_secret_key")
+	if string(signToken("body")) == string(original) {
+		t.Error("signature does not change with the secret key")
+	}
+}
@@ -0,0 +1,653 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"booking_system_app/apierror"
+	"booking_system_app/logging"
+	"booking_system_app/middleware"
+	"booking_system_app/totp"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Nama penerbit yang tampil di aplikasi authenticator
+const totpIssuer = "Booking System"
+
+// Masa berlaku token parsial antara langkah password dan langkah 2FA
+const mfaPendingTokenTTL = 5 * time.Minute
+
+// Jumlah recovery code yang dibuat setiap kali 2FA diaktifkan
+const recoveryCodeCount = 10
+
+var (
+	errInvalidMFACode  = errors.New("invalid two-factor code")
+	errTOTPAlreadyOn   = errors.New("two-factor authentication is already enabled")
+	errTOTPNotEnrolled = errors.New("two-factor enrollment has not been started")
+	errInvalidMFAToken = errors.New("invalid or expired two-factor session")
+	errAccountDisabled = errors.New("account has been disabled")
+)
+
+// Struct untuk respons awal pendaftaran TOTP. provisioning_uri bisa langsung
+// diubah menjadi QR code oleh frontend.
+type TOTPSetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// Struct untuk request langkah kedua login
+type TwoFactorLoginRequest struct {
+	Token string `json:"token"`
+	Code  string `json:"code"`
+}
+
+// Struct untuk request yang hanya berisi kode TOTP
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// Struct untuk request menonaktifkan 2FA
+type DisableTwoFactorRequest struct {
+	CurrentPassword string `json:"current_password"`
+	Code            string `json:"code"`
+}
+
+// Struct untuk respons yang membawa recovery code baru
+type RecoveryCodesResponse struct {
+	Message       string   `json:"message"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// requires2FA memeriksa apakah kebijakan mewajibkan 2FA untuk role
+func requires2FA(role string) bool {
+	return require2FAForElevatedRoles && (role == RoleStaff || role == RoleAdmin)
+}
+
+// parseMFAPendingToken memvalidasi token parsial dari langkah password dan mengembalikan email pemiliknya
+func parseMFAPendingToken(tokenString string) (string, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return secretKey, nil
+	})
+	if err != nil || !token.Valid || !claims.MFAPending {
+		return "", errInvalidMFAToken
+	}
+	return claims.Email, nil
+}
+
+// startTOTPEnrollment membuat secret baru yang belum aktif sampai dikonfirmasi dengan kode
+func startTOTPEnrollment(ctx context.Context, db *sql.DB, userID int, email string) (TOTPSetupResponse, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return TOTPSetupResponse{}, err
+	}
+
+	result, err := db.ExecContext(ctx, `UPDATE users SET totp_secret = ?, totp_last_step = NULL WHERE user_id = ? AND totp_enabled = 0`, secret, userID)
+	if err != nil {
+		return TOTPSetupResponse{}, err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return TOTPSetupResponse{}, errTOTPAlreadyOn
+	}
+
+	return TOTPSetupResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(totpIssuer, email, secret),
+	}, nil
+}
+
+// generateRecoveryCodes mengganti semua recovery code milik pengguna dengan yang baru
+func generateRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int) ([]string, error) {
+	_, err := tx.ExecContext(ctx, `DELETE FROM totp_recovery_codes WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		raw := hex.EncodeToString(buf)
+		code := raw[:5] + "-" + raw[5:]
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO totp_recovery_codes (user_id, code_hash) VALUES (?, ?)`, userID, hashToken(code))
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// twoFactorState adalah data 2FA pengguna yang dibaca dengan baris terkunci
+type twoFactorState struct {
+	secret   sql.NullString
+	enabled  bool
+	lastStep sql.NullInt64
+	disabled bool
+	deleted  bool
+}
+
+// secondFactor adalah kode yang lolos checkSecondFactor: langkah waktu TOTP,
+// atau hash recovery code jika recoveryHash tidak kosong
+type secondFactor struct {
+	totpStep     int64
+	recoveryHash string
+}
+
+// lockTwoFactorState membaca data 2FA pengguna dan mengunci barisnya (FOR UPDATE)
+// sampai tx selesai, sehingga dua permintaan dengan kode yang sama tidak bisa
+// sama-sama lolos
+func lockTwoFactorState(ctx context.Context, tx *sql.Tx, userID int) (twoFactorState, error) {
+	var state twoFactorState
+	query := `
+		SELECT totp_secret, totp_enabled, totp_last_step, disabled, deleted_at IS NOT NULL
+		FROM users WHERE user_id = ? FOR UPDATE
+	`
+	err := tx.QueryRowContext(ctx, query, userID).Scan(&state.secret, &state.enabled, &state.lastStep, &state.disabled, &state.deleted)
+	return state, err
+}
+
+// checkSecondFactor hanya memeriksa kode, tanpa mengubah data apa pun. Kode
+// TOTP yang langkah waktunya sudah pernah dipakai ditolak. Recovery code hanya
+// diterima jika allowRecovery dan 2FA sudah aktif. Kode yang lolos baru
+// ditandai terpakai lewat consumeSecondFactor di jalur sukses pemanggil.
+func checkSecondFactor(ctx context.Context, tx *sql.Tx, userID int, state twoFactorState, code string, allowRecovery bool) (secondFactor, error) {
+	if !state.secret.Valid {
+		return secondFactor{}, errTOTPNotEnrolled
+	}
+
+	step, ok := totp.Validate(state.secret.String, code, time.Now())
+	if ok && !(state.lastStep.Valid && step <= state.lastStep.Int64) {
+		return secondFactor{totpStep: step}, nil
+	}
+
+	if allowRecovery && state.enabled {
+		hash := hashToken(strings.ToLower(strings.TrimSpace(code)))
+		var exists int
+		query := `SELECT 1 FROM totp_recovery_codes WHERE user_id = ? AND code_hash = ? AND used_at IS NULL FOR UPDATE`
+		err := tx.QueryRowContext(ctx, query, userID, hash).Scan(&exists)
+		if err == nil {
+			return secondFactor{recoveryHash: hash}, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return secondFactor{}, err
+		}
+	}
+	return secondFactor{}, errInvalidMFACode
+}
+
+// consumeSecondFactor menandai kode dari checkSecondFactor sebagai terpakai:
+// recovery code diberi used_at, langkah waktu TOTP disimpan di totp_last_step
+func consumeSecondFactor(ctx context.Context, tx *sql.Tx, userID int, factor secondFactor) error {
+	if factor.recoveryHash != "" {
+		_, err := tx.ExecContext(ctx, `UPDATE totp_recovery_codes SET used_at = NOW() WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`,
+			userID, factor.recoveryHash)
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `UPDATE users SET totp_last_step = ? WHERE user_id = ?`, factor.totpStep, userID)
+	return err
+}
+
+// activateTwoFactor menyelesaikan pendaftaran yang tertunda dan mengembalikan
+// recovery code baru. Aktivasi bisa terjadi saat login (enrollment wajib),
+// jadi aktor audit dicari dari userID.
+func activateTwoFactor(ctx context.Context, tx *sql.Tx, r *http.Request, userID int) ([]string, error) {
+	_, err := tx.ExecContext(ctx, `UPDATE users SET totp_enabled = 1 WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	recoveryCodes, err := generateRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	err = recordAuditForUser(ctx, tx, r, userID, auditEvent{
+		Action:     auditTwoFactorEnable,
+		EntityType: entityUser,
+		EntityID:   userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return recoveryCodes, nil
+}
+
+// completeTwoFactorLogin memeriksa kode langkah kedua login. Kode baru ditandai
+// terpakai dan pendaftaran yang tertunda baru diaktifkan jika akun masih aktif
+// dan kode benar; recovery code baru dikembalikan jika 2FA baru saja diaktifkan.
+func completeTwoFactorLogin(ctx context.Context, db *sql.DB, r *http.Request, userID int, code string) ([]string, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	state, err := lockTwoFactorState(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// Akun bisa dinonaktifkan atau dihapus setelah langkah password
+	if state.deleted {
+		return nil, errInvalidMFAToken
+	}
+	if state.disabled {
+		return nil, errAccountDisabled
+	}
+
+	factor, err := checkSecondFactor(ctx, tx, userID, state, code, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := consumeSecondFactor(ctx, tx, userID, factor); err != nil {
+		return nil, err
+	}
+
+	var recoveryCodes []string
+	if !state.enabled {
+		recoveryCodes, err = activateTwoFactor(ctx, tx, r, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return recoveryCodes, tx.Commit()
+}
+
+// EnrollTwoFactorLogin memulai pendaftaran TOTP saat login untuk akun yang
+// diwajibkan 2FA tetapi belum mendaftar. Menggunakan token parsial dari /login.
+func EnrollTwoFactorLogin(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var req TwoFactorLoginRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	email, err := parseMFAPendingToken(req.Token)
+	if err != nil {
+		apierror.Write(w, r, apierror.Unauthorized(err.Error()))
+		return
+	}
+
+	userID, err := getUserIDByEmail(ctx, db, email)
+	if err != nil {
+		apierror.Write(w, r, apierror.Unauthorized("User not found"))
+		return
+	}
+
+	setup, err := startTOTPEnrollment(ctx, db, userID, email)
+	if err != nil {
+		if err == errTOTPAlreadyOn {
+			apierror.Write(w, r, apierror.Conflict(err.Error()))
+			return
+		}
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting two-factor enrollment: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(setup)
+}
+
+// VerifyTwoFactorLogin menyelesaikan login dua langkah: token parsial dan kode
+// TOTP (atau recovery code) ditukar dengan JWT penuh
+func VerifyTwoFactorLogin(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var req TwoFactorLoginRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	email, err := parseMFAPendingToken(req.Token)
+	if err != nil {
+		apierror.Write(w, r, apierror.Unauthorized(err.Error()))
+		return
+	}
+
+	userID, err := getUserIDByEmail(ctx, db, email)
+	if err != nil {
+		apierror.Write(w, r, apierror.Unauthorized("User not found"))
+		return
+	}
+
+	// Kode 6 digit mudah ditebak, jadi langkah ini ikut dibatasi seperti login
+	ip := clientIP(r)
+	remaining, err := loginLockRemaining(ctx, db, mfaThrottleKey(userID), ipThrottleKey(ip))
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error verifying code: %w", err)))
+		return
+	}
+	if remaining > 0 {
+		recordLoginAttempt(ctx, db, email, ip, userID, loginResultLocked)
+		w.Header().Set("Retry-After", fmt.Sprint(int(remaining.Seconds())))
+		apierror.Write(w, r, apierror.TooManyRequests("Too many failed attempts, try again later"))
+		return
+	}
+
+	recoveryCodes, err := completeTwoFactorLogin(ctx, db, r, userID, req.Code)
+	if err != nil {
+		switch err {
+		case errAccountDisabled:
+			recordLoginAttempt(ctx, db, email, ip, userID, loginResultDisabled)
+			apierror.Write(w, r, apierror.Forbidden("Account has been disabled"))
+		case errInvalidMFAToken:
+			apierror.Write(w, r, apierror.Unauthorized(err.Error()))
+		case errInvalidMFACode:
+			recordLoginAttempt(ctx, db, email, ip, userID, loginResultInvalidMFA)
+			if err := recordLoginFailure(ctx, db, mfaThrottleKey(userID), emailFailureLimit); err != nil {
+				logging.FromContext(r.Context()).Error("error recording two-factor failure", "user_id", userID, "error", err)
+			}
+			apierror.Write(w, r, apierror.Unauthorized(err.Error()))
+		case errTOTPNotEnrolled:
+			apierror.Write(w, r, apierror.BadRequest(err.Error()))
+		default:
+			apierror.Write(w, r, apierror.Internal(fmt.Errorf("error verifying code: %w", err)))
+		}
+		return
+	}
+
+	if err := clearLoginFailures(ctx, db, mfaThrottleKey(userID)); err != nil {
+		logging.FromContext(r.Context()).Error("error clearing two-factor failures", "user_id", userID, "error", err)
+	}
+
+	tokenString, err := issueSessionToken(email, false)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("could not create JWT token: %w", err)))
+		return
+	}
+	recordLoginAttempt(ctx, db, email, ip, userID, loginResultSuccess)
+
+	resp := Response{Message: "Login successful", Token: tokenString, RecoveryCodes: recoveryCodes}
+	if recoveryCodes != nil {
+		resp.Message = "Two-factor authentication enabled. Store these recovery codes safely"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// SetupTwoFactor memulai pendaftaran TOTP secara sukarela untuk pengguna yang sudah login
+func SetupTwoFactor(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	email := middleware.UserEmail(r)
+	userID, err := getUserIDByEmail(ctx, db, email)
+	if err != nil {
+		apierror.Write(w, r, apierror.NotFound("User not found"))
+		return
+	}
+
+	setup, err := startTOTPEnrollment(ctx, db, userID, email)
+	if err != nil {
+		if err == errTOTPAlreadyOn {
+			apierror.Write(w, r, apierror.Conflict(err.Error()))
+			return
+		}
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting two-factor enrollment: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(setup)
+}
+
+// EnableTwoFactor mengaktifkan TOTP setelah kode pertama dari authenticator dikonfirmasi
+func EnableTwoFactor(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var req TwoFactorCodeRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	userID, err := getUserIDByEmail(ctx, db, middleware.UserEmail(r))
+	if err != nil {
+		apierror.Write(w, r, apierror.NotFound("User not found"))
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	state, err := lockTwoFactorState(ctx, tx, userID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching user: %w", err)))
+		return
+	}
+	// Diperiksa sebelum kode agar kode tidak terpakai untuk permintaan yang pasti ditolak
+	if state.enabled {
+		apierror.Write(w, r, apierror.Conflict(errTOTPAlreadyOn.Error()))
+		return
+	}
+
+	// Pendaftaran hanya bisa dikonfirmasi dengan kode TOTP, bukan recovery code
+	factor, err := checkSecondFactor(ctx, tx, userID, state, req.Code, false)
+	if err != nil {
+		if err == errInvalidMFACode || err == errTOTPNotEnrolled {
+			apierror.Write(w, r, apierror.BadRequest(err.Error()))
+			return
+		}
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error verifying code: %w", err)))
+		return
+	}
+
+	err = consumeSecondFactor(ctx, tx, userID, factor)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error verifying code: %w", err)))
+		return
+	}
+	recoveryCodes, err := activateTwoFactor(ctx, tx, r, userID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error enabling two-factor authentication: %w", err)))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RecoveryCodesResponse{
+		Message:       "Two-factor authentication enabled. Store these recovery codes safely",
+		RecoveryCodes: recoveryCodes,
+	})
+}
+
+// DisableTwoFactor menonaktifkan TOTP. Tidak diizinkan untuk role yang diwajibkan 2FA.
+func DisableTwoFactor(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var req DisableTwoFactorRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	if requires2FA(middleware.UserRole(r)) {
+		apierror.Write(w, r, apierror.Forbidden("Two-factor authentication is required for your role"))
+		return
+	}
+
+	userID, err := getUserIDByEmail(ctx, db, middleware.UserEmail(r))
+	if err != nil {
+		apierror.Write(w, r, apierror.NotFound("User not found"))
+		return
+	}
+
+	err = checkPassword(ctx, db, userID, req.CurrentPassword)
+	if err != nil {
+		apierror.Write(w, r, apierror.Unauthorized("Current password is incorrect"))
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	state, err := lockTwoFactorState(ctx, tx, userID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching user: %w", err)))
+		return
+	}
+	if !state.enabled {
+		apierror.Write(w, r, apierror.BadRequest("Two-factor authentication is not enabled"))
+		return
+	}
+
+	// Kode hanya diperiksa: secret dan semua recovery code dihapus di bawah,
+	// sehingga tidak ada yang perlu ditandai terpakai
+	_, err = checkSecondFactor(ctx, tx, userID, state, req.Code, true)
+	if err != nil {
+		if err == errInvalidMFACode {
+			apierror.Write(w, r, apierror.BadRequest(err.Error()))
+			return
+		}
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error verifying code: %w", err)))
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE users SET totp_enabled = 0, totp_secret = NULL, totp_last_step = NULL WHERE user_id = ?`, userID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error disabling two-factor authentication: %w", err)))
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM totp_recovery_codes WHERE user_id = ?`, userID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error removing recovery codes: %w", err)))
+		return
+	}
+
+	err = recordAudit(ctx, tx, r, auditEvent{
+		Action:     auditTwoFactorDisable,
+		EntityType: entityUser,
+		EntityID:   userID,
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Message: "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes membuat recovery code baru setelah kode TOTP dikonfirmasi
+func RegenerateRecoveryCodes(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var req TwoFactorCodeRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	userID, err := getUserIDByEmail(ctx, db, middleware.UserEmail(r))
+	if err != nil {
+		apierror.Write(w, r, apierror.NotFound("User not found"))
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	state, err := lockTwoFactorState(ctx, tx, userID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching user: %w", err)))
+		return
+	}
+	if !state.enabled {
+		apierror.Write(w, r, apierror.BadRequest("Two-factor authentication is not enabled"))
+		return
+	}
+
+	// Hanya kode TOTP yang diterima di sini, bukan recovery code. Kode yang
+	// sudah dipakai (misalnya untuk login) ditolak.
+	factor, err := checkSecondFactor(ctx, tx, userID, state, req.Code, false)
+	if err == errInvalidMFACode {
+		apierror.Write(w, r, apierror.BadRequest(err.Error()))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error verifying code: %w", err)))
+		return
+	}
+	err = consumeSecondFactor(ctx, tx, userID, factor)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error verifying code: %w", err)))
+		return
+	}
+
+	codes, err := generateRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error generating recovery codes: %w", err)))
+		return
+	}
+
+	err = recordAudit(ctx, tx, r, auditEvent{
+		Action:     auditRecoveryCodesRotate,
+		EntityType: entityUser,
+		EntityID:   userID,
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RecoveryCodesResponse{
+		Message:       "New recovery codes generated, old codes no longer work",
+		RecoveryCodes: codes,
+	})
+}
@@ -0,0 +1,349 @@
+package database
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"booking_system_app/middleware"
+	"booking_system_app/totp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	twoFactorStateQuery = `SELECT totp_secret, totp_enabled, totp_last_step, disabled, deleted_at IS NOT NULL\s+FROM users WHERE user_id = \? FOR UPDATE`
+	recoveryLookup      = `SELECT 1 FROM totp_recovery_codes WHERE user_id = \? AND code_hash = \? AND used_at IS NULL FOR UPDATE`
+	recoveryConsume     = `UPDATE totp_recovery_codes SET used_at = NOW\(\)`
+	totpStepUpdate      = `UPDATE users SET totp_last_step = \? WHERE user_id = \?`
+	totpEnable          = `UPDATE users SET totp_enabled = 1 WHERE user_id = \?`
+)
+
+// twoFactorRow adalah baris lockTwoFactorState untuk pengguna 42
+type twoFactorRow struct {
+	secret   interface{}
+	enabled  bool
+	lastStep interface{}
+	disabled bool
+	deleted  bool
+}
+
+func expectTwoFactorState(mock sqlmock.Sqlmock, row twoFactorRow) {
+	mock.ExpectQuery(twoFactorStateQuery).
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"totp_secret", "totp_enabled", "totp_last_step", "disabled", "deleted"}).
+			AddRow(row.secret, row.enabled, row.lastStep, row.disabled, row.deleted))
+}
+
+func newTestSecret(t *testing.T) string {
+	t.Helper()
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return secret
+}
+
+func currentCode(t *testing.T, secret string) string {
+	t.Helper()
+	code, err := totp.CodeAt(secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// expectSecondStepStart mendaftarkan query sebelum transaksi langkah kedua login
+func expectSecondStepStart(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(`SELECT user_id FROM users WHERE email = \?`).
+		WithArgs("staff@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(42))
+	mock.ExpectQuery(lockRemainingQuery).
+		WithArgs(mfaThrottleKey(42), ipThrottleKey(testClientIP)).
+		WillReturnRows(sqlmock.NewRows([]string{"remaining"}).AddRow(nil))
+	mock.ExpectBegin()
+}
+
+// expectSecondStepSuccess mendaftarkan query setelah transaksi langkah kedua di-commit
+func expectSecondStepSuccess(mock sqlmock.Sqlmock) {
+	mock.ExpectCommit()
+	mock.ExpectExec(throttleClear).WithArgs(mfaThrottleKey(42)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(loginAttemptInsert).
+		WithArgs("staff@example.com", testClientIP, 42, true, loginResultSuccess).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+// expectPassword mendaftarkan query checkPassword untuk pengguna 42
+func expectPassword(t *testing.T, mock sqlmock.Sqlmock, password string) {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`SELECT password_hash FROM users WHERE user_id = \?`).
+		WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"password_hash"}).AddRow(string(hash)))
+}
+
+func newSecondStepRequest(t *testing.T, code string) *http.Request {
+	t.Helper()
+	pending, err := issueSessionToken("staff@example.com", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newAuthRequest(t, http.MethodPost, "/login/2fa", `{"token": "`+pending+`", "code": "`+code+`"}`, "")
+}
+
+// assertFullSession memastikan respons membawa token sesi penuh, bukan token parsial
+func assertFullSession(t *testing.T, body Response) {
+	t.Helper()
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(body.Token, claims, func(*jwt.Token) (interface{}, error) { return secretKey, nil })
+	if err != nil {
+		t.Fatalf("parsing token: %v", err)
+	}
+	if claims.Email != "staff@example.com" || claims.MFAPending {
+		t.Errorf("claims = %+v, want a full session for staff@example.com", claims)
+	}
+}
+
+func TestVerifyTwoFactorLogin(t *testing.T) {
+	secret := newTestSecret(t)
+
+	t.Run("totp code", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectSecondStepStart(mock)
+		expectTwoFactorState(mock, twoFactorRow{secret: secret, enabled: true})
+		mock.ExpectExec(totpStepUpdate).WithArgs(sqlmock.AnyArg(), 42).WillReturnResult(sqlmock.NewResult(0, 1))
+		expectSecondStepSuccess(mock)
+
+		rec := serve(db, VerifyTwoFactorLogin, newSecondStepRequest(t, currentCode(t, secret)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+		}
+		var body Response
+		decodeBody(t, rec, &body)
+		assertFullSession(t, body)
+		if body.RecoveryCodes != nil {
+			t.Errorf("recovery codes returned for an already enabled account")
+		}
+	})
+
+	t.Run("recovery code", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectSecondStepStart(mock)
+		expectTwoFactorState(mock, twoFactorRow{secret: secret, enabled: true})
+		mock.ExpectQuery(recoveryLookup).
+			WithArgs(42, hashToken("abcde-12345")).
+			WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+		mock.ExpectExec(recoveryConsume).WithArgs(42, hashToken("abcde-12345")).WillReturnResult(sqlmock.NewResult(0, 1))
+		expectSecondStepSuccess(mock)
+
+		rec := serve(db, VerifyTwoFactorLogin, newSecondStepRequest(t, " ABCDE-12345 "))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+		}
+		var body Response
+		decodeBody(t, rec, &body)
+		assertFullSession(t, body)
+	})
+
+	t.Run("replayed totp code", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectSecondStepStart(mock)
+		// Langkah waktu terakhir sudah di depan sehingga kode saat ini dianggap terpakai
+		expectTwoFactorState(mock, twoFactorRow{secret: secret, enabled: true, lastStep: totp.Step(time.Now()) + 1})
+		mock.ExpectQuery(recoveryLookup).WillReturnRows(sqlmock.NewRows([]string{"1"}))
+		mock.ExpectRollback()
+		mock.ExpectExec(loginAttemptInsert).
+			WithArgs("staff@example.com", testClientIP, 42, false, loginResultInvalidMFA).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(throttleInsert).WithArgs(mfaThrottleKey(42), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(throttleLock).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		rec := serve(db, VerifyTwoFactorLogin, newSecondStepRequest(t, currentCode(t, secret)))
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusUnauthorized, rec.Body)
+		}
+	})
+
+	t.Run("pending enrollment is activated", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectSecondStepStart(mock)
+		expectTwoFactorState(mock, twoFactorRow{secret: secret})
+		mock.ExpectExec(totpStepUpdate).WithArgs(sqlmock.AnyArg(), 42).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(totpEnable).WithArgs(42).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`DELETE FROM totp_recovery_codes WHERE user_id = \?`).WithArgs(42).WillReturnResult(sqlmock.NewResult(0, 0))
+		for i := 0; i < recoveryCodeCount; i++ {
+			mock.ExpectExec(`INSERT INTO totp_recovery_codes`).WithArgs(42, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+		}
+		mock.ExpectQuery(`SELECT email FROM users WHERE user_id = \?`).
+			WithArgs(42).
+			WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("staff@example.com"))
+		mock.ExpectExec(`INSERT INTO audit_logs`).WillReturnResult(sqlmock.NewResult(1, 1))
+		expectSecondStepSuccess(mock)
+
+		rec := serve(db, VerifyTwoFactorLogin, newSecondStepRequest(t, currentCode(t, secret)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+		}
+		var body Response
+		decodeBody(t, rec, &body)
+		assertFullSession(t, body)
+		if len(body.RecoveryCodes) != recoveryCodeCount {
+			t.Errorf("got %d recovery codes, want %d", len(body.RecoveryCodes), recoveryCodeCount)
+		}
+	})
+
+	t.Run("account disabled after the password step", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectSecondStepStart(mock)
+		expectTwoFactorState(mock, twoFactorRow{secret: secret, enabled: true, disabled: true})
+		mock.ExpectRollback()
+		mock.ExpectExec(loginAttemptInsert).
+			WithArgs("staff@example.com", testClientIP, 42, false, loginResultDisabled).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		// Kode yang benar tidak ikut terpakai
+		rec := serve(db, VerifyTwoFactorLogin, newSecondStepRequest(t, currentCode(t, secret)))
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusForbidden, rec.Body)
+		}
+	})
+
+	t.Run("account deleted after the password step", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectSecondStepStart(mock)
+		expectTwoFactorState(mock, twoFactorRow{secret: secret, enabled: true, deleted: true})
+		mock.ExpectRollback()
+
+		rec := serve(db, VerifyTwoFactorLogin, newSecondStepRequest(t, currentCode(t, secret)))
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusUnauthorized, rec.Body)
+		}
+	})
+}
+
+func TestVerifyTwoFactorLoginRejectsTokens(t *testing.T) {
+	full, err := issueSessionToken("staff@example.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+		Email:      "staff@example.com",
+		MFAPending: true,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		},
+	}).SignedString(secretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"full session token", full},
+		{"expired partial token", expired},
+		{"garbage", "not-a-token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Token ditolak sebelum menyentuh database
+			db, _ := newMockDB(t)
+			req := newAuthRequest(t, http.MethodPost, "/login/2fa", `{"token": "`+tt.token+`", "code": "123456"}`, "")
+			rec := serve(db, VerifyTwoFactorLogin, req)
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+func TestPartialTokenRejectedByMiddleware(t *testing.T) {
+	db, _ := newMockDB(t)
+	pending, err := issueSessionToken("staff@example.com", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := newAuthRequest(t, http.MethodGet, "/me", "", "")
+	req.Header.Set("Authorization", "Bearer "+pending)
+
+	rec := serveWith(db, middleware.PermProfileManage, GetProfile, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestEnableTwoFactorAlreadyEnabledKeepsCode(t *testing.T) {
+	secret := newTestSecret(t)
+	db, mock := newMockDB(t)
+	expectAuth(mock, "staff@example.com", RoleStaff)
+	mock.ExpectQuery(`SELECT user_id FROM users WHERE email = \?`).
+		WithArgs("staff@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(42))
+	mock.ExpectBegin()
+	expectTwoFactorState(mock, twoFactorRow{secret: secret, enabled: true})
+	// Tidak ada UPDATE: kode tidak boleh terpakai untuk permintaan yang ditolak
+	mock.ExpectRollback()
+
+	req := newAuthRequest(t, http.MethodPost, "/me/2fa/enable", `{"code": "`+currentCode(t, secret)+`"}`, "staff@example.com")
+	rec := serveWith(db, middleware.PermProfileManage, EnableTwoFactor, req)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body)
+	}
+}
+
+func TestDisableTwoFactorPendingEnrollmentNotActivated(t *testing.T) {
+	secret := newTestSecret(t)
+	expectUser := func(mock sqlmock.Sqlmock) {
+		expectAuth(mock, "guest@example.com", RoleCustomer)
+		mock.ExpectQuery(`SELECT user_id FROM users WHERE email = \?`).
+			WithArgs("guest@example.com").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(42))
+	}
+
+	t.Run("pending enrollment", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectUser(mock)
+		expectPassword(t, mock, "Secret123!")
+		mock.ExpectBegin()
+		expectTwoFactorState(mock, twoFactorRow{secret: secret})
+		// Tidak ada aktivasi atau recovery code untuk enrollment yang belum selesai
+		mock.ExpectRollback()
+
+		req := newAuthRequest(t, http.MethodPost, "/me/2fa/disable",
+			`{"current_password": "Secret123!", "code": "`+currentCode(t, secret)+`"}`, "guest@example.com")
+		rec := serveWith(db, middleware.PermProfileManage, DisableTwoFactor, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusBadRequest, rec.Body)
+		}
+	})
+
+	t.Run("enabled with recovery code", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectUser(mock)
+		expectPassword(t, mock, "Secret123!")
+		mock.ExpectBegin()
+		expectTwoFactorState(mock, twoFactorRow{secret: secret, enabled: true})
+		mock.ExpectQuery(recoveryLookup).
+			WithArgs(42, hashToken("abcde-12345")).
+			WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+		mock.ExpectExec(`UPDATE users SET totp_enabled = 0, totp_secret = NULL, totp_last_step = NULL WHERE user_id = \?`).
+			WithArgs(42).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`DELETE FROM totp_recovery_codes WHERE user_id = \?`).WithArgs(42).WillReturnResult(sqlmock.NewResult(0, 9))
+		mock.ExpectExec(`INSERT INTO audit_logs`).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		req := newAuthRequest(t, http.MethodPost, "/me/2fa/disable",
+			`{"current_password": "Secret123!", "code": "abcde-12345"}`, "guest@example.com")
+		rec := serveWith(db, middleware.PermProfileManage, DisableTwoFactor, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+		}
+	})
+}
@@ -1,12 +1,17 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
-	"log"
+	"booking_system_app/apierror"
+	"booking_system_app/logging"
+	"booking_system_app/middleware"
+	"booking_system_app/validate"
 	"golang.org/x/crypto/bcrypt"
 	"github.com/golang-jwt/jwt/v4"
 )
@@ -16,8 +21,8 @@ type RegisterRequest struct {
 	Name        string `json:"name"`
 	Email       string `json:"email"`
 	Password    string `json:"password"`
-	PhoneNumber string `json:"phone_number"`
-	Role        string `json:"role"`
+	PhoneNumber string `json:"phone_number,omitempty"`
+	Role        string `json:"role,omitempty"` // hanya dipakai admin saat membuat user
 }
 
 // Struct untuk request login
@@ -30,34 +35,145 @@ type LoginRequest struct {
 type Property struct {
 	Name         string `json:"name"`
 	Address      string `json:"address"`
-	Description  string `json:"description"`
-	ContactNumber string `json:"contact_number"`
+	Description  string `json:"description,omitempty"`
+	ContactNumber string `json:"contact_number,omitempty"`
+	Latitude     *float64 `json:"latitude,omitempty"`
+	Longitude    *float64 `json:"longitude,omitempty"`
+	Amenities    []string `json:"amenities,omitempty"` // kode dari katalog GET /amenities
 }
 
 // Struct untuk response login dan register
 type Response struct {
-	Message string `json:"message"`
-	Token   string `json:"token,omitempty"`
+	Message               string `json:"message"`
+	Token                 string `json:"token,omitempty"`
+	MFARequired           bool   `json:"mfa_required,omitempty"`
+	MFAEnrollmentRequired bool   `json:"mfa_enrollment_required,omitempty"`
+	// Hanya diisi jika 2FA baru diaktifkan pada langkah kedua login
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
 }
 
 // Struct untuk JWT claims
+// MFAPending menandai token parsial yang hanya bisa dipakai untuk langkah 2FA
 type Claims struct {
-	Email string `json:"email"`
+	Email      string `json:"email"`
+	MFAPending bool   `json:"mfa_pending,omitempty"`
+	// Waktu login awal, dibawa ke setiap token hasil refresh
+	OrigIssuedAt *jwt.NumericDate `json:"orig_iat,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Struct untuk kamar baru
+type Room struct {
+	PropertyID    int     `json:"property_id,omitempty"` // diambil dari path pada POST /properties/{id}/rooms
+	RoomName      string  `json:"room_name"`
+	RoomType      string  `json:"room_type"`
+	PricePerNight float64 `json:"price_per_night"`
+	Status        string  `json:"status"`
+
+	// Opsional; max_adults default 2 jika tidak diisi
+	MaxAdults   int       `json:"max_adults,omitempty"`
+	MaxChildren int       `json:"max_children,omitempty"`
+	SizeSqm     *float64  `json:"size_sqm,omitempty"`
+	Beds        []RoomBed `json:"beds,omitempty"`
+	Amenities   []string  `json:"amenities,omitempty"`
+}
+
+// features mengembalikan kapasitas dan fasilitas kamar baru
+func (room Room) features() RoomFeatures {
+	return RoomFeatures{
+		MaxAdults:   room.MaxAdults,
+		MaxChildren: room.MaxChildren,
+		SizeSqm:     room.SizeSqm,
+		Beds:        room.Beds,
+		Amenities:   room.Amenities,
+	}
+}
+
+// Struct untuk request perubahan status kamar
+type UpdateStatusRequest struct {
+	RoomID int    `json:"room_id,omitempty"` // diambil dari path pada PUT /rooms/{id}/status
+	Status string `json:"status"` // tersedia, dipesan, atau dalam perawatan
+}
+
+// Struktur untuk menerima kriteria pencarian. Limit, cursor dan sort ikut
+// di body yang sama (lihat ListOptions); max_price 0 berarti tanpa batas atas.
+type SearchCriteria struct {
+	PropertyName string  `json:"property_name,omitempty"`
+	Location     string  `json:"location,omitempty"` // dicocokkan dengan alamat properti
+	RoomType     string  `json:"room_type,omitempty"`
+	MinPrice     float64 `json:"min_price,omitempty"`
+	MaxPrice     float64 `json:"max_price,omitempty"`
+
+	// Pencarian berdasarkan jarak dari near, opsional dibatasi radius_km.
+	// Jika near diisi, hasil diurutkan dari yang terdekat (sort "distance").
+	Near     *GeoPoint  `json:"near,omitempty"`
+	RadiusKM float64    `json:"radius_km,omitempty"`
+	Bounds   *GeoBounds `json:"bounds,omitempty"` // area peta yang terlihat
+
+	// Jumlah tamu per kamar; anak boleh menempati slot dewasa
+	Adults   int `json:"adults,omitempty"`
+	Children int `json:"children,omitempty"`
+	// Kode fasilitas yang semuanya harus dimiliki kamar atau propertinya
+	Amenities []string `json:"amenities,omitempty"`
+	// Rating rata-rata properti minimal (1-5); properti tanpa ulasan tidak ikut
+	MinRating float64 `json:"min_rating,omitempty"`
+	ListOptions
+}
+
+// Struktur untuk hasil pencarian kamar
+type RoomSearchResult struct {
+	ID            int     `json:"id"`
+	RoomName      string  `json:"room_name"`
+	RoomType      string  `json:"room_type"`
+	PricePerNight float64 `json:"price_per_night"`
+	Status        string  `json:"status"`
+	PropertyName  string  `json:"property_name"`
+	Latitude      *float64 `json:"latitude"`
+	Longitude     *float64 `json:"longitude"`
+	DistanceKM    *float64 `json:"distance_km,omitempty"` // hanya jika near diisi
+	RoomFeatures
+	PropertyAmenities []string `json:"property_amenities"`
+	// Thumbnail cover kamar, atau cover properti jika kamar belum punya foto
+	CoverThumbnailURL *string `json:"cover_thumbnail_url"`
+	// Agregat ulasan properti, null jika belum ada ulasan
+	Rating *PropertyRating `json:"rating"`
+}
+
+// Struktur untuk respons pencarian kamar dengan paging
+type RoomSearchResponse struct {
+	Rooms []RoomSearchResult `json:"rooms"`
+	PageInfo
+}
+
+// roomSearchSpec: sort berdasarkan harga (default), nama kamar atau rating
+// properti ("-rating" untuk yang terbaik lebih dulu; properti tanpa ulasan
+// dianggap 0). Pencarian dengan near juga bisa diurutkan berdasarkan jarak
+// (lihat SearchCriteria.searchSpec).
+var roomSearchSpec = listSpec{
+	sorts: map[string]sortKey{
+		"price":  {"r.price_per_night", sortNumber},
+		"name":   {"r.room_name", sortString},
+		"rating": {"COALESCE(p.rating_average, 0)", sortNumber},
+	},
+	defaultSort: "price",
+	idColumn:    "r.room_id",
+}
+
 type BookingDetail struct {
     RoomID         int      `json:"room_id"`
     Quantity      int     `json:"quantity"`
-    PricePerNight float64 `json:"price_per_night"`
+    PricePerNight float64 `json:"price_per_night,omitempty"`
+    // Jumlah tamu untuk seluruh kamar pada detail ini, dicek terhadap kapasitas.
+    // Jika keduanya kosong dianggap satu dewasa per kamar.
+    Adults        int     `json:"adults,omitempty"`
+    Children      int     `json:"children,omitempty"`
 }
 
 type BookingRequest struct {
-    CustomerID       int                `json:"customer_id"`
-    CheckInDate      string             `json:"check_in_date"`
-    CheckOutDate     string             `json:"check_out_date"`
+    CheckInDate      string             `json:"check_in_date" format:"date"`
+    CheckOutDate     string             `json:"check_out_date" format:"date"`
     BookingDetails   []BookingDetail    `json:"booking_details"`
-    AdditionalServices []int            `json:"additional_services"`
+    AdditionalServices []int            `json:"additional_services,omitempty"`
     PaymentDetails   PaymentDetails     `json:"payment_details"`
 }
 
@@ -82,86 +198,192 @@ var secretKey = []byte("your_secret_key") // Gantilah dengan kunci yang aman
 
 // RegisterUser menangani registrasi user baru
 func RegisterUser(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
 	var req RegisterRequest
 
 	// Decode data JSON dari body request
-	err := json.NewDecoder(r.Body).Decode(&req)
+	err := decodeJSON(w, r, &req)
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apierror.Write(w, r, err)
+		return
+	}
+
+	// Validasi email, kekuatan password dan nomor telepon
+	if err := req.Validate(); err != nil {
+		apierror.Write(w, r, err)
 		return
 	}
 
 	// Hash password menggunakan bcrypt
 	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		http.Error(w, "Error hashing password", http.StatusInternalServerError)
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error hashing password: %w", err)))
+		return
+	}
+
+	// Registrasi publik selalu menjadi customer, role yang dikirim client diabaikan.
+	// Akun staff dan admin hanya bisa dibuat lewat CreateUser (khusus admin).
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
 		return
 	}
+	defer tx.Rollback()
 
-	// SQL untuk memasukkan user baru
 	query := `INSERT INTO users (name, email, password_hash, phone_number, role) VALUES (?, ?, ?, ?, ?)`
-	_, err = db.Exec(query, req.Name, req.Email, hash, req.PhoneNumber, req.Role)
+	result, err := tx.ExecContext(ctx, query, req.Name, req.Email, hash, req.PhoneNumber, RoleCustomer)
+	if err != nil {
+		if isDuplicateEntry(err) {
+			apierror.Write(w, r, apierror.Conflict("Email is already registered"))
+			return
+		}
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error registering user: %w", err)))
+		return
+	}
+
+	userID, err := result.LastInsertId()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error retrieving user ID: %w", err)))
+		return
+	}
+
+	// Route publik, aktornya adalah pengguna yang baru mendaftar
+	err = recordAuditAs(ctx, tx, r, req.Email, auditEvent{
+		Action:     auditUserRegister,
+		EntityType: entityUser,
+		EntityID:   userID,
+		After:      userSnapshot(req.Name, req.Email, req.PhoneNumber, RoleCustomer),
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error registering user: %v", err), http.StatusInternalServerError)
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
 		return
 	}
 
+	// Kirim email verifikasi, kegagalan pengiriman tidak membatalkan registrasi
+	// karena pengguna bisa meminta ulang lewat /resend_verification
+	err = sendVerificationEmail(ctx, db, int(userID), req.Email)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("error sending verification email", "user_id", userID, "error", err)
+	}
+
 	// Response sukses
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(Response{Message: "User registered successfully"})
+	json.NewEncoder(w).Encode(Response{Message: "User registered successfully, please check your email to verify your account"})
 }
 
 // LoginUser menangani proses login user
 func LoginUser(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
 	var req LoginRequest
 
 	// Decode data JSON dari body request
-	err := json.NewDecoder(r.Body).Decode(&req)
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	// Tolak percobaan login selama email atau IP masih dikunci
+	ip := clientIP(r)
+	remaining, err := loginLockRemaining(ctx, db, emailThrottleKey(req.Email), ipThrottleKey(ip))
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error logging in: %w", err)))
+		return
+	}
+	if remaining > 0 {
+		recordLoginAttempt(ctx, db, req.Email, ip, 0, loginResultLocked)
+		w.Header().Set("Retry-After", strconv.Itoa(int(remaining.Seconds())))
+		apierror.Write(w, r, apierror.TooManyRequests("Too many failed login attempts, try again later"))
 		return
 	}
 
 	// SQL untuk memvalidasi user
-	query := `SELECT user_id, name, role, password_hash FROM users WHERE email = ?`
-	row := db.QueryRow(query, req.Email)
+	query := `SELECT user_id, name, role, password_hash, email_verified_at IS NOT NULL, disabled, totp_enabled FROM users WHERE email = ?`
+	row := db.QueryRowContext(ctx, query, req.Email)
 
 	var userID int
 	var name, role, storedHash string
-	err = row.Scan(&userID, &name, &role, &storedHash)
+	var emailVerified, disabled, totpEnabled bool
+	err = row.Scan(&userID, &name, &role, &storedHash, &emailVerified, &disabled, &totpEnabled)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			http.Error(w, "Invalid email or password", http.StatusUnauthorized)
+			handleLoginFailure(ctx, db, req.Email, ip, 0, loginResultUnknownEmail)
+			apierror.Write(w, r, apierror.Unauthorized("Invalid email or password"))
 			return
 		}
-		http.Error(w, "Error logging in", http.StatusInternalServerError)
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error logging in: %w", err)))
 		return
 	}
 
 	// Verifikasi password menggunakan bcrypt
 	err = bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(req.Password))
 	if err != nil {
-		http.Error(w, "Invalid email or password", http.StatusUnauthorized)
+		handleLoginFailure(ctx, db, req.Email, ip, userID, loginResultInvalidPassword)
+		apierror.Write(w, r, apierror.Unauthorized("Invalid email or password"))
 		return
 	}
 
-	// Generate JWT token
-	expirationTime := time.Now().Add(24 * time.Hour)
-	claims := &Claims{
-		Email: req.Email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-		},
+	// Akun yang dinonaktifkan admin tidak boleh login
+	if disabled {
+		recordLoginAttempt(ctx, db, req.Email, ip, userID, loginResultDisabled)
+		apierror.Write(w, r, apierror.Forbidden("Account has been disabled"))
+		return
+	}
+
+	// Blokir login untuk akun yang belum verifikasi email jika diwajibkan
+	if requireEmailVerification && !emailVerified {
+		recordLoginAttempt(ctx, db, req.Email, ip, userID, loginResultUnverified)
+		apierror.Write(w, r, apierror.Forbidden("Email address has not been verified"))
+		return
+	}
+
+	// Password benar, hitungan kegagalan untuk email ini direset
+	if err := clearLoginFailures(ctx, db, emailThrottleKey(req.Email)); err != nil {
+		logging.FromContext(r.Context()).Error("error clearing login failures", "user_id", userID, "error", err)
+	}
+
+	// Akun dengan 2FA aktif atau role yang diwajibkan 2FA menerima token parsial
+	// dan harus menyelesaikan langkah kedua di /login/2fa
+	if totpEnabled || requires2FA(role) {
+		partialToken, err := issueSessionToken(req.Email, true)
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(fmt.Errorf("could not create JWT token: %w", err)))
+			return
+		}
+		recordLoginAttempt(ctx, db, req.Email, ip, userID, loginResultMFAPending)
+
+		message := "Two-factor authentication required"
+		if !totpEnabled {
+			message = "Two-factor authentication enrollment required for role " + role
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Response{
+			Message:               message,
+			Token:                 partialToken,
+			MFARequired:           true,
+			MFAEnrollmentRequired: !totpEnabled,
+		})
+		return
 	}
 
 	// Membuat token JWT
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(secretKey)
+	tokenString, err := issueSessionToken(req.Email, false)
 	if err != nil {
-		http.Error(w, "Could not create JWT token", http.StatusInternalServerError)
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("could not create JWT token: %w", err)))
 		return
 	}
+	recordLoginAttempt(ctx, db, req.Email, ip, userID, loginResultSuccess)
 
 	// Kirimkan token sebagai respons
 	w.Header().Set("Content-Type", "application/json")
@@ -172,20 +394,105 @@ func LoginUser(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// issueSessionToken membuat JWT untuk email. Token parsial (mfaPending) hanya
+// berlaku singkat dan ditolak oleh middleware untuk semua route selain langkah 2FA.
+func issueSessionToken(email string, mfaPending bool) (string, error) {
+	return signSessionToken(email, mfaPending, time.Now())
+}
+
+// signSessionToken membuat JWT untuk sesi yang dimulai pada sessionStart.
+// Masa berlaku token tidak pernah melewati sessionStart + maxSessionAge.
+func signSessionToken(email string, mfaPending bool, sessionStart time.Time) (string, error) {
+	now := time.Now()
+	expirationTime := now.Add(24 * time.Hour)
+	if mfaPending {
+		expirationTime = now.Add(mfaPendingTokenTTL)
+	}
+	if sessionEnd := sessionStart.Add(maxSessionAge); sessionEnd.Before(expirationTime) {
+		expirationTime = sessionEnd
+	}
+
+	claims := &Claims{
+		Email:        email,
+		MFAPending:   mfaPending,
+		OrigIssuedAt: jwt.NewNumericDate(sessionStart),
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(secretKey)
+}
+
 // AddProperty menangani penambahan properti baru
 func AddProperty(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
 	var property Property
 
-	err := json.NewDecoder(r.Body).Decode(&property)
+	err := decodeJSON(w, r, &property)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	if err := property.Validate(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
 		return
 	}
+	defer tx.Rollback()
 
-	query := `INSERT INTO properties (name, address, description, contact_number) VALUES (?, ?, ?, ?)`
-	_, err = db.Exec(query, property.Name, property.Address, property.Description, property.ContactNumber)
+	query := `INSERT INTO properties (name, address, description, contact_number, latitude, longitude) VALUES (?, ?, ?, ?, ?, ?)`
+	result, err := tx.ExecContext(ctx, query, property.Name, property.Address, property.Description, property.ContactNumber,
+		property.Latitude, property.Longitude)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error adding property: %v", err), http.StatusInternalServerError)
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error adding property: %w", err)))
+		return
+	}
+
+	propertyID, err := result.LastInsertId()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error retrieving property ID: %w", err)))
+		return
+	}
+
+	if err := setPropertyAmenities(ctx, tx, int(propertyID), property.Amenities); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	err = recordAudit(ctx, tx, r, auditEvent{Action: auditPropertyCreate, EntityType: entityProperty, EntityID: propertyID, After: property})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	// Staff yang membuat properti otomatis ditugaskan ke properti tersebut
+	// agar bisa langsung menambahkan kamar
+	if middleware.UserRole(r) == RoleStaff {
+		query = `
+			INSERT INTO staff_property_assignments (user_id, property_id, assigned_by)
+			SELECT user_id, ?, user_id FROM users WHERE email = ?
+		`
+		_, err = tx.ExecContext(ctx, query, propertyID, middleware.UserEmail(r))
+		if err != nil {
+			apierror.Write(w, r, apierror.Internal(fmt.Errorf("error assigning staff to property: %w", err)))
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
 		return
 	}
 
@@ -196,41 +503,44 @@ func AddProperty(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 
 // AddRoom menangani penambahan kamar baru
 func AddRoom(db *sql.DB, w http.ResponseWriter, r *http.Request) {
-	type Room struct {
-		PropertyID    int     `json:"property_id"`
-		RoomName      string  `json:"room_name"`
-		RoomType      string  `json:"room_type"`
-		PricePerNight float64 `json:"price_per_night"`
-		Status        string  `json:"status"`
-	}
-
-	type Response struct {
-		Message string `json:"message"`
-	}
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
 
 	var room Room
 
 	// Decode body request
-	err := json.NewDecoder(r.Body).Decode(&room)
+	err := decodeJSON(w, r, &room)
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apierror.Write(w, r, err)
 		return
 	}
 
-	// Log nilai yang diterima untuk debugging
-	fmt.Println("Received Room Data:", room)
+	// Route REST membawa ID di path, route lama di body
+	if _, err := pathInt(r, "id", &room.PropertyID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
 
-	// Validasi input (opsional)
-	if room.PropertyID <= 0 || room.RoomName == "" || room.RoomType == "" || room.PricePerNight <= 0 || room.Status == "" {
-		// Log jika ada input yang tidak valid
-		fmt.Println("Invalid input data:", room)
-		http.Error(w, "Invalid input data", http.StatusBadRequest)
+	if room.MaxAdults == 0 {
+		room.MaxAdults = defaultMaxAdults
+	}
+
+	// Validasi input
+	if err := room.Validate(); err != nil {
+		apierror.Write(w, r, err)
 		return
 	}
 
-	// Log sebelum query dieksekusi
-	fmt.Printf("Prepared to insert room with PropertyID: %d, RoomName: %s, RoomType: %s, PricePerNight: %.2f, Status: %s\n",
-		room.PropertyID, room.RoomName, room.RoomType, room.PricePerNight, room.Status)
+	// Staff hanya boleh menambahkan kamar pada properti yang ditugaskan kepadanya
+	allowed, err := canManageProperty(ctx, db, r, room.PropertyID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error checking property access: %w", err)))
+		return
+	}
+	if !allowed {
+		apierror.Write(w, r, apierror.Forbidden("Forbidden: You are not assigned to this property"))
+		return
+	}
 
 	// Query untuk menambahkan kamar
 	query := `
@@ -238,27 +548,48 @@ func AddRoom(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 		VALUES (?, ?, ?, ?, ?)
 	`
 
+	// Kamar dan catatan auditnya ditulis dalam satu transaksi
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
 	// Eksekusi query dan tangkap error jika ada
-	fmt.Println("room.RoomType",room.RoomType)
-	result, err := db.Exec(query, room.PropertyID, room.RoomName, room.RoomType, room.PricePerNight, room.Status)
+	result, err := tx.ExecContext(ctx, query, room.PropertyID, room.RoomName, room.RoomType, room.PricePerNight, room.Status)
 	if err != nil {
-		// Log error eksekusi query
-		fmt.Println("Error executing query:", err)
-		http.Error(w, fmt.Sprintf("Error adding room: %v", err), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error("error inserting room", "property_id", room.PropertyID, "error", err)
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error adding room: %w", err)))
 		return
 	}
 
 	// Log hasil eksekusi query
 	lastInsertID, err := result.LastInsertId()
 	if err != nil {
-		// Log error untuk mendapatkan ID terakhir
-		fmt.Println("Error getting last insert ID:", err)
-		http.Error(w, "Error retrieving last insert ID", http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error("error getting room insert ID", "error", err)
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error retrieving last insert ID: %w", err)))
 		return
 	}
 
-	// Log jika data berhasil ditambahkan
-	fmt.Printf("Room added successfully with ID: %d\n", lastInsertID)
+	if err := setRoomFeatures(ctx, tx, int(lastInsertID), room.features()); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	err = recordAudit(ctx, tx, r, auditEvent{Action: auditRoomCreate, EntityType: entityRoom, EntityID: lastInsertID, After: room})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("room added", "room_id", lastInsertID, "property_id", room.PropertyID)
 
 	// Respons sukses
 	w.Header().Set("Content-Type", "application/json")
@@ -268,22 +599,85 @@ func AddRoom(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 
 // UpdateRoomStatus menangani pembaruan status kamar
 func UpdateRoomStatus(db *sql.DB, w http.ResponseWriter, r *http.Request) {
-	type UpdateStatusRequest struct {
-		RoomID int    `json:"room_id"`
-		Status string `json:"status"` // tersedia, dipesan, atau dalam perawatan
-	}
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
 
 	var req UpdateStatusRequest
-	err := json.NewDecoder(r.Body).Decode(&req)
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	// Route REST membawa ID di path, route lama di body
+	if _, err := pathInt(r, "id", &req.RoomID); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	propertyID, err := getPropertyIDForRoom(ctx, db, req.RoomID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			apierror.Write(w, r, apierror.NotFound("Room not found"))
+			return
+		}
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching room: %w", err)))
+		return
+	}
+
+	// Staff hanya boleh mengubah status kamar pada properti yang ditugaskan kepadanya
+	allowed, err := canManageProperty(ctx, db, r, propertyID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error checking property access: %w", err)))
+		return
+	}
+	if !allowed {
+		apierror.Write(w, r, apierror.Forbidden("Forbidden: You are not assigned to this property"))
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	// Status lama dikunci dan dibaca untuk snapshot audit
+	var oldStatus string
+	err = tx.QueryRowContext(ctx, `SELECT status FROM rooms WHERE room_id = ? FOR UPDATE`, req.RoomID).Scan(&oldStatus)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching room status: %w", err)))
 		return
 	}
 
 	query := `UPDATE rooms SET status = ? WHERE room_id = ?`
-	_, err = db.Exec(query, req.Status, req.RoomID)
+	_, err = tx.ExecContext(ctx, query, req.Status, req.RoomID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error updating room status: %w", err)))
+		return
+	}
+
+	err = recordAudit(ctx, tx, r, auditEvent{
+		Action:     auditRoomStatusUpdate,
+		EntityType: entityRoom,
+		EntityID:   req.RoomID,
+		Before:     map[string]string{"status": oldStatus},
+		After:      map[string]string{"status": req.Status},
+	})
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error updating room status: %v", err), http.StatusInternalServerError)
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
 		return
 	}
 
@@ -292,147 +686,312 @@ func UpdateRoomStatus(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(Response{Message: "Room status updated successfully"})
 }
 
-// SearchRooms menangani pencarian kamar
+// SearchRooms mencari kamar dengan filter nama properti, lokasi, tipe,
+// rentang harga, jarak dari suatu titik dan area peta. Hasil dibagi per
+// halaman memakai cursor; sort: price (default), name, distance (default jika
+// near diisi), awalan "-" untuk urutan menurun.
 func SearchRooms(db *sql.DB, w http.ResponseWriter, r *http.Request) {
-    // Struktur untuk menerima kriteria pencarian
-    type SearchCriteria struct {
-        PropertyName string  `json:"property_name"`
-        RoomType     string  `json:"room_type"`
-        MinPrice     float64 `json:"min_price"`
-        MaxPrice     float64 `json:"max_price"`
-    }
+    ctx, cancel := requestContext(r, queryTimeout)
+    defer cancel()
 
     var criteria SearchCriteria
-    err := json.NewDecoder(r.Body).Decode(&criteria)
+    err := decodeJSON(w, r, &criteria)
     if err != nil {
-        http.Error(w, "Invalid request body", http.StatusBadRequest)
+        apierror.Write(w, r, err)
         return
     }
 
-    // Validasi harga (min_price tidak boleh lebih besar dari max_price)
-    if criteria.MinPrice > criteria.MaxPrice {
-        http.Error(w, "min_price cannot be greater than max_price", http.StatusBadRequest)
+    // Validasi kriteria (termasuk min_price tidak boleh lebih besar dari max_price)
+    if err := criteria.Validate(); err != nil {
+        apierror.Write(w, r, err)
         return
     }
-
-    // Gunakan wildcard jika property_name atau room_type kosong
-    propertyName := "%"
-    if criteria.PropertyName != "" {
-        propertyName = "%" + criteria.PropertyName + "%"
-    }
-
-    roomType := "%"
-    if criteria.RoomType != "" {
-        roomType = "%" + criteria.RoomType + "%"
+    list, err := criteria.searchSpec().parse(criteria.ListOptions)
+    if err != nil {
+        apierror.Write(w, r, err)
+        return
     }
 
     // Log nilai kriteria pencarian untuk debugging
-    fmt.Printf("Searching with property_name: %s, room_type: %s, min_price: %.2f, max_price: %.2f\n",
-        criteria.PropertyName, criteria.RoomType, criteria.MinPrice, criteria.MaxPrice)
+    logging.FromContext(r.Context()).Debug("searching rooms", "criteria", logging.Redact(criteria))
 
-    // Query SQL untuk pencarian kamar
-    query := `
-        SELECT r.room_id, r.room_name, r.room_type, r.price_per_night, r.status, p.name AS property_name
+    conditions, args := criteria.conditions()
+    var total int
+    err = db.QueryRowContext(ctx, `
+        SELECT COUNT(*)
         FROM rooms r
         JOIN properties p ON r.property_id = p.property_id
-        WHERE p.name LIKE ? AND r.room_type LIKE ? AND r.price_per_night BETWEEN ? AND ?
-    `
+        `+sqlConditions(conditions), args...).Scan(&total)
+    if err != nil {
+        apierror.Write(w, r, apierror.Internal(fmt.Errorf("error counting rooms: %w", err)))
+        return
+    }
+
+    if after, afterArgs := list.where(); after != "" {
+        conditions = append(conditions, after)
+        args = append(args, afterArgs...)
+    }
+    orderBy, limitArgs := list.orderBy()
+    results, err := queryRoomSearch(ctx, db, criteria.distanceColumn(), sqlConditions(conditions)+" "+orderBy, append(args, limitArgs...))
+    if err != nil {
+        apierror.Write(w, r, apierror.Internal(err))
+        return
+    }
+
+    // Baris ekstra dari LIMIT hanya penanda adanya halaman berikutnya
+    fetched := len(results)
+    if fetched > list.limit {
+        results = results[:list.limit]
+    }
+    page := list.pageInfo(total, fetched, func() (interface{}, int64) {
+        last := results[len(results)-1]
+        switch list.field() {
+        case "name":
+            return last.RoomName, int64(last.ID)
+        case "distance":
+            return *last.DistanceKM, int64(last.ID)
+        case "rating":
+            if last.Rating == nil {
+                return 0.0, int64(last.ID)
+            }
+            return last.Rating.Average, int64(last.ID)
+        }
+        return last.PricePerNight, int64(last.ID)
+    })
 
-    // Log query dan parameter untuk debugging
-    fmt.Printf("Query: %s\nParams: propertyName=%s, roomType=%s, minPrice=%.2f, maxPrice=%.2f\n",
-        query, propertyName, roomType, criteria.MinPrice, criteria.MaxPrice)
+    // Respons sukses dengan hasil pencarian
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(RoomSearchResponse{Rooms: results, PageInfo: page})
+}
+
+// SearchRoomsLegacy melayani route lama /search_rooms yang mengembalikan
+// seluruh hasil sebagai array tanpa paging. Filter dan sort sama dengan
+// SearchRooms, kecuali room_type yang tetap dicocokkan sebagai substring
+// seperti sebelumnya; limit dan cursor diabaikan.
+func SearchRoomsLegacy(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+    ctx, cancel := requestContext(r, queryTimeout)
+    defer cancel()
 
-    // Eksekusi query dengan parameter pencarian
-    rows, err := db.Query(query, propertyName, roomType, criteria.MinPrice, criteria.MaxPrice)
+    var criteria SearchCriteria
+    err := decodeJSON(w, r, &criteria)
     if err != nil {
-        http.Error(w, fmt.Sprintf("Error Searching Rooms: %v", err), http.StatusInternalServerError)
+        apierror.Write(w, r, err)
+        return
+    }
+    // room_type di route lama bukan enum, jadi dikeluarkan dari validasi dan
+    // filter exact, lalu dicocokkan dengan LIKE seperti perilaku lama
+    roomType := criteria.RoomType
+    criteria.RoomType = ""
+    if err := criteria.Validate(); err != nil {
+        apierror.Write(w, r, err)
+        return
+    }
+    list, err := criteria.searchSpec().parse(ListOptions{Sort: criteria.Sort})
+    if err != nil {
+        apierror.Write(w, r, err)
+        return
+    }
+
+    conditions, args := criteria.conditions()
+    if roomType != "" {
+        conditions = append(conditions, `r.room_type LIKE ? ESCAPE '\\'`)
+        args = append(args, containsPattern(roomType))
+    }
+    results, err := queryRoomSearch(ctx, db, criteria.distanceColumn(), sqlConditions(conditions)+" "+list.order(), args)
+    if err != nil {
+        apierror.Write(w, r, apierror.Internal(err))
         return
     }
-    defer rows.Close()
 
-    // Struktur untuk hasil pencarian kamar
-    type RoomSearchResult struct {
-        ID            int     `json:"id"`
-        RoomName      string  `json:"room_name"`
-        RoomType      string  `json:"room_type"`
-        PricePerNight float64 `json:"price_per_night"`
-        Status        string  `json:"status"`
-        PropertyName  string  `json:"property_name"`
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(results)
+}
+
+// conditions mengubah kriteria pencarian menjadi kondisi WHERE
+func (c SearchCriteria) conditions() ([]string, []interface{}) {
+    conditions := []string{"r.price_per_night >= ?"}
+    args := []interface{}{c.MinPrice}
+    if c.MaxPrice > 0 {
+        conditions = append(conditions, "r.price_per_night <= ?")
+        args = append(args, c.MaxPrice)
+    }
+    if c.PropertyName != "" {
+        conditions = append(conditions, "p.name LIKE ?")
+        args = append(args, "%"+c.PropertyName+"%")
+    }
+    if c.Location != "" {
+        conditions = append(conditions, "p.address LIKE ?")
+        args = append(args, "%"+c.Location+"%")
+    }
+    if c.RoomType != "" {
+        conditions = append(conditions, "r.room_type = ?")
+        args = append(args, c.RoomType)
+    }
+    if c.Adults > 0 || c.Children > 0 {
+        conditions = append(conditions, "r.max_adults >= ?", "r.max_adults + r.max_children >= ?")
+        args = append(args, c.Adults, c.Adults+c.Children)
     }
+    if c.MinRating > 0 {
+        conditions = append(conditions, "p.rating_average >= ?")
+        args = append(args, c.MinRating)
+    }
+    amenityConditions, amenityArgs := amenityConditions(c.Amenities)
+    conditions = append(conditions, amenityConditions...)
+    args = append(args, amenityArgs...)
+    geoConditions, geoArgs := c.geoConditions()
+    return append(conditions, geoConditions...), append(args, geoArgs...)
+}
 
-    var results []RoomSearchResult
+// queryRoomSearch menjalankan query pencarian kamar. distance adalah ekspresi
+// kolom distance_km, clauses berisi WHERE, ORDER BY dan LIMIT yang sudah
+// disusun pemanggil.
+func queryRoomSearch(ctx context.Context, db *sql.DB, distance, clauses string, args []interface{}) ([]RoomSearchResult, error) {
+    query := `
+        SELECT r.room_id, r.room_name, r.room_type, r.price_per_night, r.status, p.property_id, p.name AS property_name,
+               p.latitude, p.longitude, ` + distance + `, r.max_adults, r.max_children, r.size_sqm,
+               COALESCE(
+                   (SELECT thumbnail_key FROM photos WHERE room_id = r.room_id AND is_cover LIMIT 1),
+                   (SELECT thumbnail_key FROM photos WHERE property_id = p.property_id AND room_id IS NULL AND is_cover LIMIT 1)
+               ) AS cover_thumbnail,
+               p.rating_count, p.rating_average, p.rating_cleanliness, p.rating_service, p.rating_location
+        FROM rooms r
+        JOIN properties p ON r.property_id = p.property_id
+        ` + clauses
+    rows, err := db.QueryContext(ctx, query, args...)
+    if err != nil {
+        return nil, fmt.Errorf("error searching rooms: %w", err)
+    }
+    defer rows.Close()
 
-    // Memindai hasil query dan mengisi hasil pencarian
+    // Selalu berupa array JSON, kosong jika tidak ada kamar yang cocok
+    results := []RoomSearchResult{}
+    var propertyIDs []int
     for rows.Next() {
         var result RoomSearchResult
-        err := rows.Scan(&result.ID, &result.RoomName, &result.RoomType, &result.PricePerNight, &result.Status, &result.PropertyName)
+        var propertyID int
+        var latitude, longitude, distanceKM, size sql.NullFloat64
+        var cover sql.NullString
+        var ratingCount int
+        var rating, cleanliness, service, location sql.NullFloat64
+        err := rows.Scan(&result.ID, &result.RoomName, &result.RoomType, &result.PricePerNight, &result.Status, &propertyID, &result.PropertyName,
+            &latitude, &longitude, &distanceKM, &result.MaxAdults, &result.MaxChildren, &size, &cover,
+            &ratingCount, &rating, &cleanliness, &service, &location)
         if err != nil {
-            http.Error(w, "Error reading search results", http.StatusInternalServerError)
-            return
+            return nil, fmt.Errorf("error reading search results: %w", err)
+        }
+        result.Latitude = nullFloatPtr(latitude)
+        result.Longitude = nullFloatPtr(longitude)
+        result.DistanceKM = nullFloatPtr(distanceKM)
+        result.SizeSqm = nullFloatPtr(size)
+        if cover.Valid {
+            url := mediaURL(cover.String)
+            result.CoverThumbnailURL = &url
         }
+        result.Rating = propertyRating(ratingCount, rating, cleanliness, service, location)
         results = append(results, result)
+        propertyIDs = append(propertyIDs, propertyID)
     }
-
-    // Jika tidak ada hasil ditemukan
-    if len(results) == 0 {
-        w.Header().Set("Content-Type", "application/json")
-        json.NewEncoder(w).Encode(map[string]string{"message": "No rooms found"})
-        return
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("error reading search results: %w", err)
     }
 
-    // Respons sukses dengan hasil pencarian
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(results)
+    // Tempat tidur dan fasilitas dibaca sekaligus untuk semua kamar di halaman ini
+    rooms := map[int]*RoomFeatures{}
+    for i := range results {
+        rooms[results[i].ID] = &results[i].RoomFeatures
+    }
+    if err := loadRoomDetails(ctx, db, rooms); err != nil {
+        return nil, err
+    }
+    propertyAmenities, err := loadPropertyAmenities(ctx, db, propertyIDs)
+    if err != nil {
+        return nil, err
+    }
+    for i := range results {
+        results[i].PropertyAmenities = propertyAmenities[propertyIDs[i]]
+        if results[i].PropertyAmenities == nil {
+            results[i].PropertyAmenities = []string{}
+        }
+    }
+    return results, nil
 }
 
 func BookRoom(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+    ctx, cancel := requestContext(r, txTimeout)
+    defer cancel()
+
     var req BookingRequest
-    err := json.NewDecoder(r.Body).Decode(&req)
+    err := decodeJSON(w, r, &req)
     if err != nil {
-        http.Error(w, "Invalid request body", http.StatusBadRequest)
+        bookingFailed("invalid_body")
+        apierror.Write(w, r, err)
         return
     }
 
-    // Validasi input
-    log.Println("req", req)
-    if req.CustomerID <= 0 || len(req.BookingDetails) == 0 || req.CheckInDate == "" || req.CheckOutDate == "" || req.PaymentDetails.TotalAmount <= 0 {
-        http.Error(w, fmt.Sprintf("Invalid Input Data: %v", err), http.StatusBadRequest)
+    // Validasi input, payment_details diredaksi dari log
+    logger := logging.FromContext(r.Context())
+    logger.Debug("booking request received", "request", logging.Redact(req))
+    if err := req.Validate(time.Now()); err != nil {
+        bookingFailed("validation")
+        apierror.Write(w, r, err)
         return
     }
 
-    // Parse tanggal check-in dan check-out
-    checkIn, err := time.Parse("2006-01-02", req.CheckInDate)
-    checkOut, err := time.Parse("2006-01-02", req.CheckOutDate)
-    if err != nil || checkOut.Before(checkIn) {
-        http.Error(w, "Invalid dates", http.StatusBadRequest)
-        return
-    }
+    // Tanggal sudah divalidasi sehingga parse tidak mungkin gagal
+    checkIn, _ := time.Parse(validate.DateLayout, req.CheckInDate)
+    checkOut, _ := time.Parse(validate.DateLayout, req.CheckOutDate)
 
     // Hitung durasi menginap
     duration := int(checkOut.Sub(checkIn).Hours() / 24)
 
-    // Mulai transaksi
-    tx, err := db.Begin()
+    // Mulai transaksi. Jika klien memutus koneksi atau txTimeout habis,
+    // database/sql otomatis me-rollback transaksi ini.
+    tx, err := db.BeginTx(ctx, nil)
     if err != nil {
-        http.Error(w, "Error starting transaction", http.StatusInternalServerError)
+        bookingFailed(failureReason(err))
+        apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
         return
     }
     defer tx.Rollback()
 
+    // Booking selalu atas nama pengguna yang sedang login
+    customerID, err := getUserIDByEmail(ctx, tx, middleware.UserEmail(r))
+    if err != nil {
+        bookingFailed(failureReason(err))
+        apierror.Write(w, r, apierror.Internal(fmt.Errorf("error fetching customer: %w", err)))
+        return
+    }
+
     var totalPrice float64
     var bookingIDs []int  // Pastikan ini slice []int
 
     // Proses setiap kamar yang dipesan
-    for _, detail := range req.BookingDetails {
-        log.Printf("Checking room_id: %d", detail.RoomID)
+    for i, detail := range req.BookingDetails {
+        logger.Debug("checking room", "room_id", detail.RoomID)
 
-        // Ambil harga kamar berdasarkan tipe
+        // Ambil harga dan kapasitas kamar. Baris kamar dikunci agar booking
+        // bersamaan untuk kamar yang sama diproses bergiliran.
         var pricePerNight float64
-        query := `SELECT price_per_night FROM rooms WHERE room_id = ?`
-        err := db.QueryRow(query, detail.RoomID).Scan(&pricePerNight)
+        var maxAdults, maxChildren int
+        query := `SELECT price_per_night, max_adults, max_children FROM rooms WHERE room_id = ? FOR UPDATE`
+        err := tx.QueryRowContext(ctx, query, detail.RoomID).Scan(&pricePerNight, &maxAdults, &maxChildren)
         if err != nil {
-            log.Printf("Error: Room ID '%d' not found. %v", detail.RoomID, err)
-            http.Error(w, fmt.Sprintf("Error: Room ID '%d' not found. %v", detail.RoomID, err), http.StatusNotFound)
+            logger.Warn("room not found for booking", "room_id", detail.RoomID, "error", err)
+            bookingFailed("room_not_found")
+            apierror.Write(w, r, apierror.NotFound(fmt.Sprintf("Room ID %d not found", detail.RoomID)))
+            return
+        }
+
+        // Tamu dibagi ke seluruh kamar pada detail ini; anak boleh menempati slot dewasa
+        if err := checkCapacity(i, detail, maxAdults, maxChildren); err != nil {
+            bookingFailed("capacity")
+            apierror.Write(w, r, err)
+            return
+        }
+
+        if err := checkRoomAvailable(ctx, tx, detail.RoomID, req.CheckInDate, req.CheckOutDate); err != nil {
+            bookingFailed(failureReason(err))
+            apierror.Write(w, r, err)
             return
         }
 
@@ -443,33 +1002,57 @@ func BookRoom(db *sql.DB, w http.ResponseWriter, r *http.Request) {
         // Simpan pemesanan untuk setiap tipe kamar
         query = `INSERT INTO bookings (user_id, room_id, check_in_date, check_out_date, total_price)
                  VALUES (?, ?, ?, ?, ?)`
-        result, err := tx.Exec(query, req.CustomerID, detail.RoomID, req.CheckInDate, req.CheckOutDate, totalRoomPrice)
+        result, err := tx.ExecContext(ctx, query, customerID, detail.RoomID, req.CheckInDate, req.CheckOutDate, totalRoomPrice)
         if err != nil {
-            http.Error(w, fmt.Sprintf("Error booking room: %v", err), http.StatusInternalServerError)
+            bookingFailed(failureReason(err))
+            apierror.Write(w, r, apierror.Internal(fmt.Errorf("error booking room: %w", err)))
             return
         }
 
         // Dapatkan ID pemesanan
         bookingID, err := result.LastInsertId()
         if err != nil {
-            http.Error(w, "Error retrieving booking ID", http.StatusInternalServerError)
+            bookingFailed(failureReason(err))
+            apierror.Write(w, r, apierror.Internal(fmt.Errorf("error retrieving booking ID: %w", err)))
             return
         }
         bookingIDs = append(bookingIDs, int(bookingID))  // Menambahkan booking ID ke slice
+
+        err = recordAudit(ctx, tx, r, auditEvent{
+            Action:     auditBookingCreate,
+            EntityType: entityBooking,
+            EntityID:   bookingID,
+            After: map[string]interface{}{
+                "customer_id":    customerID,
+                "room_id":        detail.RoomID,
+                "quantity":       detail.Quantity,
+                "check_in_date":  req.CheckInDate,
+                "check_out_date": req.CheckOutDate,
+                "total_price":    totalRoomPrice,
+                "payment_method": req.PaymentDetails.PaymentMethod,
+            },
+        })
+        if err != nil {
+            bookingFailed(failureReason(err))
+            apierror.Write(w, r, apierror.Internal(err))
+            return
+        }
     }
 
     // Simulasi pembayaran (misalnya menggunakan metode pembayaran tertentu)
     paymentMethod := req.PaymentDetails.PaymentMethod
     paymentStatus := "completed" // Status pembayaran sementara
-    paymentDate := time.Now().Format("2006-01-02 15:04:05")
+    paymentDate := time.Now() // dikonversi driver ke zona waktu koneksi (DB_TIMEZONE)
 
     // Simpan data pembayaran ke tabel payments
     for _, bookingID := range bookingIDs {
         query := `INSERT INTO payments (booking_id, payment_method, payment_status, payment_date, amount)
                   VALUES (?, ?, ?, ?, ?)`
-        _, err := tx.Exec(query, bookingID, paymentMethod, paymentStatus, paymentDate, totalPrice)
+        _, err := tx.ExecContext(ctx, query, bookingID, paymentMethod, paymentStatus, paymentDate, totalPrice)
         if err != nil {
-            http.Error(w, fmt.Sprintf("Error processing payment: %v", err), http.StatusInternalServerError)
+            bookingFailed("payment_failed")
+            paymentsTotal.WithLabelValues(paymentMethod, "failed").Inc()
+            apierror.Write(w, r, apierror.Internal(fmt.Errorf("error processing payment: %w", err)))
             return
         }
     }
@@ -477,10 +1060,15 @@ func BookRoom(db *sql.DB, w http.ResponseWriter, r *http.Request) {
     // Commit transaksi
     err = tx.Commit()
     if err != nil {
-        http.Error(w, "Error completing transaction", http.StatusInternalServerError)
+        bookingFailed(failureReason(err))
+        apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
         return
     }
 
+    // Metrik hanya dicatat setelah commit agar tidak menghitung booking yang di-rollback
+    bookingsTotal.WithLabelValues("created", "").Add(float64(len(bookingIDs)))
+    paymentsTotal.WithLabelValues(paymentMethod, paymentStatus).Add(float64(len(bookingIDs)))
+
     // Respons sukses
     w.Header().Set("Content-Type", "application/json")
     w.WriteHeader(http.StatusCreated)
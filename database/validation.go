@@ -0,0 +1,402 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"booking_system_app/apierror"
+	"booking_system_app/middleware"
+	"booking_system_app/validate"
+)
+
+// maxBodyBytes membatasi ukuran body JSON yang diterima handler
+const maxBodyBytes = 1 << 20
+
+// maxStayNights adalah lama menginap maksimum dalam satu pemesanan
+const maxStayNights = 30
+
+// Nilai enum mengikuti definisi kolom di booking_system.sql
+var (
+	roomTypes      = []string{"single", "double", "suite", "family"}
+	roomStatuses   = []string{"available", "booked", "maintenance"}
+	paymentMethods = []string{"credit_card", "debit_card", "paypal", "cash"}
+)
+
+// FieldEnums mengembalikan nilai yang diterima field enum, dengan key
+// "NamaStruct.field_json". Dipakai untuk dokumen OpenAPI agar enum di
+// spesifikasi selalu sama dengan aturan validasi.
+func FieldEnums() map[string][]string {
+	return map[string][]string{
+		"Room.room_type":                roomTypes,
+		"Room.status":                   roomStatuses,
+		"UpdateStatusRequest.status":    roomStatuses,
+		"SearchCriteria.room_type":      roomTypes,
+		"RoomBed.type":                  bedTypes,
+		"PaymentDetails.payment_method": paymentMethods,
+		"Booking.status":                {BookingPending, BookingConfirmed, BookingCancelled, BookingCompleted},
+		"Review.status":                 {ReviewPublished, ReviewHidden},
+		"ModerateReviewRequest.status":  {ReviewPublished, ReviewHidden},
+		"RegisterRequest.role":          {RoleCustomer, RoleStaff, RoleAdmin},
+		"ChangeRoleRequest.role":        {RoleCustomer, RoleStaff, RoleAdmin},
+	}
+}
+
+// decodeJSON membaca body request ke v dengan batas ukuran maxBodyBytes.
+// Error yang dikembalikan sudah berupa *apierror.Error.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return apierror.BodyTooLarge()
+		}
+		return apierror.InvalidBody()
+	}
+	return nil
+}
+
+// Validate memeriksa data registrasi publik
+func (req RegisterRequest) Validate() error {
+	var v validate.Validator
+	req.validateAccount(&v)
+	return v.Err()
+}
+
+// validateForAdmin memeriksa data akun yang dibuat admin, termasuk role
+func (req RegisterRequest) validateForAdmin() error {
+	var v validate.Validator
+	req.validateAccount(&v)
+	v.OneOf("role", req.Role, RoleCustomer, RoleStaff, RoleAdmin)
+	return v.Err()
+}
+
+func (req RegisterRequest) validateAccount(v *validate.Validator) {
+	if v.Required("name", req.Name) {
+		v.MaxLength("name", req.Name, 100)
+	}
+	v.Email("email", req.Email)
+	v.Password("password", req.Password)
+	v.Phone("phone_number", req.PhoneNumber)
+}
+
+// Validate memeriksa data properti baru
+func (p Property) Validate() error {
+	var v validate.Validator
+	if v.Required("name", p.Name) {
+		v.MaxLength("name", p.Name, 100)
+	}
+	v.Required("address", p.Address)
+	v.Phone("contact_number", p.ContactNumber)
+	validateCoordinates(&v, "", p.Latitude, p.Longitude)
+	validateAmenityCodes(&v, p.Amenities)
+	return v.Err()
+}
+
+// Validate memeriksa request perubahan koordinat properti
+func (req PropertyLocation) Validate() error {
+	var v validate.Validator
+	validateCoordinates(&v, "", req.Latitude, req.Longitude)
+	return v.Err()
+}
+
+// validateCoordinates memeriksa pasangan latitude/longitude yang harus diisi
+// bersama-sama. prefix ditambahkan di depan nama field, misalnya "near.".
+func validateCoordinates(v *validate.Validator, prefix string, latitude, longitude *float64) {
+	if (latitude == nil) != (longitude == nil) {
+		v.AddError(prefix+"latitude", "latitude and longitude must be set together")
+		return
+	}
+	if latitude == nil {
+		return
+	}
+	v.Check(*latitude >= -90 && *latitude <= 90, prefix+"latitude", "must be between -90 and 90")
+	v.Check(*longitude >= -180 && *longitude <= 180, prefix+"longitude", "must be between -180 and 180")
+}
+
+// Validate memeriksa data kamar baru
+func (room Room) Validate() error {
+	var v validate.Validator
+	v.PositiveInt("property_id", room.PropertyID)
+	if v.Required("room_name", room.RoomName) {
+		v.MaxLength("room_name", room.RoomName, 50)
+	}
+	v.OneOf("room_type", room.RoomType, roomTypes...)
+	v.Positive("price_per_night", room.PricePerNight)
+	v.OneOf("status", room.Status, roomStatuses...)
+	validateRoomFeatures(&v, room.features())
+	return v.Err()
+}
+
+// maxRoomGuests membatasi max_adults dan max_children agar muat di kolom tinyint
+const maxRoomGuests = 20
+
+// Validate memeriksa kapasitas dan fasilitas kamar
+func (f RoomFeatures) Validate() error {
+	var v validate.Validator
+	validateRoomFeatures(&v, f)
+	return v.Err()
+}
+
+func validateRoomFeatures(v *validate.Validator, f RoomFeatures) {
+	v.Check(f.MaxAdults >= 1 && f.MaxAdults <= maxRoomGuests, "max_adults", fmt.Sprintf("must be between 1 and %d", maxRoomGuests))
+	v.Check(f.MaxChildren >= 0 && f.MaxChildren <= maxRoomGuests, "max_children", fmt.Sprintf("must be between 0 and %d", maxRoomGuests))
+	if f.SizeSqm != nil {
+		v.Positive("size_sqm", *f.SizeSqm)
+	}
+	seen := map[string]bool{}
+	for i, bed := range f.Beds {
+		field := fmt.Sprintf("beds[%d]", i)
+		v.OneOf(field+".type", bed.Type, bedTypes...)
+		v.Check(!seen[bed.Type], field+".type", "is listed more than once")
+		v.PositiveInt(field+".count", bed.Count)
+		seen[bed.Type] = true
+	}
+	validateAmenityCodes(v, f.Amenities)
+}
+
+// Validate memeriksa request penggantian fasilitas properti
+func (req PropertyAmenitiesRequest) Validate() error {
+	var v validate.Validator
+	validateAmenityCodes(&v, req.Amenities)
+	return v.Err()
+}
+
+// validateAmenityCodes memeriksa bentuk daftar kode; keberadaan kode di
+// katalog dicek saat disimpan (amenityIDs)
+func validateAmenityCodes(v *validate.Validator, codes []string) {
+	seen := map[string]bool{}
+	for i, code := range codes {
+		field := fmt.Sprintf("amenities[%d]", i)
+		v.Required(field, code)
+		v.Check(!seen[code], field, "is listed more than once")
+		seen[code] = true
+	}
+}
+
+// Validate memeriksa request perubahan status kamar
+func (req UpdateStatusRequest) Validate() error {
+	var v validate.Validator
+	v.PositiveInt("room_id", req.RoomID)
+	v.OneOf("status", req.Status, roomStatuses...)
+	return v.Err()
+}
+
+// Validate memeriksa kriteria pencarian kamar
+func (c SearchCriteria) Validate() error {
+	var v validate.Validator
+	v.NonNegative("min_price", c.MinPrice)
+	v.NonNegative("max_price", c.MaxPrice)
+	v.Check(c.MaxPrice == 0 || c.MinPrice <= c.MaxPrice, "min_price", "cannot be greater than max_price")
+	if c.RoomType != "" {
+		v.OneOf("room_type", c.RoomType, roomTypes...)
+	}
+	if c.Near != nil {
+		validateCoordinates(&v, "near.", &c.Near.Latitude, &c.Near.Longitude)
+	}
+	v.Check(c.Adults >= 0, "adults", "must not be negative")
+	v.Check(c.Children >= 0, "children", "must not be negative")
+	v.Check(c.MinRating >= 0 && c.MinRating <= 5, "min_rating", "must be between 0 and 5")
+	validateAmenityCodes(&v, c.Amenities)
+	v.NonNegative("radius_km", c.RadiusKM)
+	v.Check(c.RadiusKM == 0 || c.Near != nil, "radius_km", "requires near")
+	if b := c.Bounds; b != nil {
+		v.Check(b.North >= -90 && b.North <= 90, "bounds.north", "must be between -90 and 90")
+		v.Check(b.South >= -90 && b.South <= 90, "bounds.south", "must be between -90 and 90")
+		v.Check(b.East >= -180 && b.East <= 180, "bounds.east", "must be between -180 and 180")
+		v.Check(b.West >= -180 && b.West <= 180, "bounds.west", "must be between -180 and 180")
+		v.Check(b.South <= b.North, "bounds.south", "cannot be greater than bounds.north")
+	}
+	return v.Err()
+}
+
+// Validate memeriksa request pemesanan. now dipakai sebagai acuan tanggal
+// hari ini sehingga check-in di masa lalu ditolak.
+func (req BookingRequest) Validate(now time.Time) error {
+	var v validate.Validator
+	checkIn, okIn := v.Date("check_in_date", req.CheckInDate)
+	checkOut, okOut := v.Date("check_out_date", req.CheckOutDate)
+	if okIn {
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		v.Check(!checkIn.Before(today), "check_in_date", "must not be in the past")
+	}
+	if okIn && okOut {
+		nights := int(checkOut.Sub(checkIn).Hours() / 24)
+		if nights < 1 {
+			v.AddError("check_out_date", "must be after check_in_date")
+		} else if nights > maxStayNights {
+			v.AddError("check_out_date", fmt.Sprintf("stay must be at most %d nights", maxStayNights))
+		}
+	}
+
+	if len(req.BookingDetails) == 0 {
+		v.AddError("booking_details", "must contain at least one room")
+	}
+	for i, detail := range req.BookingDetails {
+		v.PositiveInt(fmt.Sprintf("booking_details[%d].room_id", i), detail.RoomID)
+		v.PositiveInt(fmt.Sprintf("booking_details[%d].quantity", i), detail.Quantity)
+		v.Check(detail.Adults >= 0, fmt.Sprintf("booking_details[%d].adults", i), "must not be negative")
+		v.Check(detail.Children >= 0, fmt.Sprintf("booking_details[%d].children", i), "must not be negative")
+		v.Check(detail.Children == 0 || detail.Adults > 0, fmt.Sprintf("booking_details[%d].adults", i), "at least one adult is required when booking for children")
+	}
+
+	v.OneOf("payment_details.payment_method", req.PaymentDetails.PaymentMethod, paymentMethods...)
+	v.Positive("payment_details.total_amount", req.PaymentDetails.TotalAmount)
+	return v.Err()
+}
+
+// Validate memeriksa request perubahan password
+func (req ChangePasswordRequest) Validate() error {
+	var v validate.Validator
+	v.Required("current_password", req.CurrentPassword)
+	v.Password("new_password", req.NewPassword)
+	return v.Err()
+}
+
+// Validate memeriksa request perubahan email
+func (req ChangeEmailRequest) Validate() error {
+	var v validate.Validator
+	v.Email("new_email", req.NewEmail)
+	v.Required("current_password", req.CurrentPassword)
+	return v.Err()
+}
+
+// Validate memeriksa request perubahan profil, hanya field yang dikirim yang diperiksa
+func (req UpdateProfileRequest) Validate() error {
+	var v validate.Validator
+	if req.Name != nil && v.Required("name", *req.Name) {
+		v.MaxLength("name", *req.Name, 100)
+	}
+	if req.PhoneNumber != nil {
+		v.Phone("phone_number", *req.PhoneNumber)
+	}
+	return v.Err()
+}
+
+// Validate memeriksa request reset password
+func (req ResetPasswordRequest) Validate() error {
+	var v validate.Validator
+	v.Required("token", req.Token)
+	v.Password("new_password", req.NewPassword)
+	return v.Err()
+}
+
+// pathInt mengisi dst dari parameter path numerik, misalnya {id} pada
+// /properties/{id}/rooms. Nilai bool false berarti route tidak punya parameter
+// tersebut (route lama) sehingga dst tetap memakai nilai dari body atau query.
+func pathInt(r *http.Request, name string, dst *int) (bool, error) {
+	raw := r.PathValue(name)
+	if raw == "" {
+		return false, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value <= 0 {
+		return false, apierror.BadRequest("Invalid path parameter " + name)
+	}
+	*dst = value
+	return true, nil
+}
+
+// Validate memeriksa request perubahan urutan atau foto sampul
+func (req UpdatePhotoRequest) Validate() error {
+	var v validate.Validator
+	if req.Position == nil && req.Cover == nil {
+		v.AddError("position", "position or cover is required")
+	}
+	if req.Position != nil {
+		v.PositiveInt("position", *req.Position)
+	}
+	return v.Err()
+}
+
+// Validate memeriksa request ulasan: setiap rating 1-5 dan panjang komentar
+func (req ReviewRequest) Validate() error {
+	var v validate.Validator
+	v.Check(req.Cleanliness >= 1 && req.Cleanliness <= 5, "cleanliness", "must be between 1 and 5")
+	v.Check(req.Service >= 1 && req.Service <= 5, "service", "must be between 1 and 5")
+	v.Check(req.Location >= 1 && req.Location <= 5, "location", "must be between 1 and 5")
+	v.MaxLength("comment", req.Comment, maxReviewText)
+	return v.Err()
+}
+
+// Validate memeriksa request balasan ulasan dari pengelola properti
+func (req ReviewReplyRequest) Validate() error {
+	var v validate.Validator
+	if v.Required("reply", req.Reply) {
+		v.MaxLength("reply", req.Reply, maxReviewText)
+	}
+	return v.Err()
+}
+
+// Validate memeriksa request moderasi ulasan
+func (req ModerateReviewRequest) Validate() error {
+	var v validate.Validator
+	v.OneOf("status", req.Status, ReviewPublished, ReviewHidden)
+	v.MaxLength("reason", req.Reason, 255)
+	return v.Err()
+}
+
+// Validate memeriksa request perubahan role pengguna
+func (req ChangeRoleRequest) Validate() error {
+	var v validate.Validator
+	v.PositiveInt("user_id", req.UserID)
+	v.OneOf("role", req.Role, RoleCustomer, RoleStaff, RoleAdmin)
+	return v.Err()
+}
+
+// Validate memeriksa request menonaktifkan/mengaktifkan akun
+func (req SetUserStatusRequest) Validate() error {
+	var v validate.Validator
+	v.PositiveInt("user_id", req.UserID)
+	return v.Err()
+}
+
+// Validate memeriksa request penugasan staff ke properti
+func (req StaffAssignmentRequest) Validate() error {
+	var v validate.Validator
+	v.PositiveInt("user_id", req.UserID)
+	v.PositiveInt("property_id", req.PropertyID)
+	return v.Err()
+}
+
+// Validate memeriksa request penerbitan API key. now dipakai sebagai acuan
+// sehingga expires_at di masa lalu ditolak.
+func (req CreateAPIKeyRequest) Validate(now time.Time) error {
+	var v validate.Validator
+	v.PositiveInt("user_id", req.UserID)
+	if v.Required("name", req.Name) {
+		v.MaxLength("name", req.Name, 100)
+	}
+
+	if len(req.Scopes) == 0 {
+		v.AddError("scopes", "is required")
+	}
+	seen := map[string]bool{}
+	for i, scope := range req.Scopes {
+		field := fmt.Sprintf("scopes[%d]", i)
+		v.OneOf(field, scope, middleware.Permissions()...)
+		v.Check(!seen[scope], field, "is listed more than once")
+		seen[scope] = true
+	}
+
+	if req.ExpiresAt != "" {
+		t, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			v.AddError("expires_at", "must be an RFC 3339 timestamp")
+		} else {
+			v.Check(t.After(now), "expires_at", "must be in the future")
+		}
+	}
+	return v.Err()
+}
+
+// Validate memeriksa request pencabutan API key pada route lama (ID di body)
+func (req RevokeAPIKeyRequest) Validate() error {
+	var v validate.Validator
+	v.PositiveInt("api_key_id", req.APIKeyID)
+	return v.Err()
+}
@@ -0,0 +1,244 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"booking_system_app/apierror"
+	"booking_system_app/logging"
+	"booking_system_app/notify"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Struct untuk request yang hanya berisi email (kirim ulang verifikasi, lupa password)
+type EmailRequest struct {
+	Email string `json:"email"`
+}
+
+// Struct untuk request reset password
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// sendVerificationEmail membuat token verifikasi dan mengirimkannya ke email pengguna
+func sendVerificationEmail(ctx context.Context, db *sql.DB, userID int, email string) error {
+	token, err := issueToken(ctx, db, userID, tokenPurposeEmailVerification, "")
+	if err != nil {
+		return err
+	}
+
+	return mailer.Send(ctx, notify.Message{
+		To:      email,
+		Subject: "Verify your email address",
+		Body:    "Use the following token to verify your email address:\n\n" + token,
+	})
+}
+
+// VerifyEmail menandai email pengguna sudah terverifikasi menggunakan token verifikasi
+func VerifyEmail(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var req ConfirmTokenRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	userID, _, err := consumeToken(ctx, tx, req.Token, tokenPurposeEmailVerification)
+	if err != nil {
+		if err == errInvalidToken {
+			apierror.Write(w, r, apierror.BadRequest("Invalid or expired token"))
+			return
+		}
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE user_id = ?`, userID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error verifying email: %w", err)))
+		return
+	}
+
+	err = recordAuditForUser(ctx, tx, r, userID, auditEvent{
+		Action:     auditEmailVerify,
+		EntityType: entityUser,
+		EntityID:   userID,
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Message: "Email verified successfully"})
+}
+
+// ResendVerification mengirim ulang email verifikasi. Respons selalu sama
+// agar endpoint ini tidak bisa dipakai untuk menebak email yang terdaftar.
+func ResendVerification(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var req EmailRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	var userID int
+	query := `SELECT user_id FROM users WHERE email = ? AND email_verified_at IS NULL AND deleted_at IS NULL`
+	err = db.QueryRowContext(ctx, query, req.Email).Scan(&userID)
+	if err == nil {
+		err = sendVerificationEmail(ctx, db, userID, req.Email)
+	}
+	if err != nil && err != sql.ErrNoRows {
+		logging.FromContext(r.Context()).Error("error resending verification email", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(Response{Message: "If the account exists and is unverified, a verification email has been sent"})
+}
+
+// ForgotPassword mengirim token reset password ke email pengguna.
+// Seperti ResendVerification, respons tidak membedakan email terdaftar atau tidak.
+func ForgotPassword(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var req EmailRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	var userID int
+	err = db.QueryRowContext(ctx, `SELECT user_id FROM users WHERE email = ? AND deleted_at IS NULL`, req.Email).Scan(&userID)
+	if err == nil {
+		var token string
+		token, err = issueToken(ctx, db, userID, tokenPurposePasswordReset, "")
+		if err == nil {
+			err = mailer.Send(r.Context(), notify.Message{
+				To:      req.Email,
+				Subject: "Reset your password",
+				Body:    "Use the following token to reset your password:\n\n" + token + "\n\nThe token expires in one hour. If you did not request a reset, ignore this email.",
+			})
+		}
+	}
+	if err != nil && err != sql.ErrNoRows {
+		logging.FromContext(r.Context()).Error("error sending password reset email", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(Response{Message: "If the account exists, a password reset email has been sent"})
+}
+
+// ResetPassword mengganti password menggunakan token reset password
+func ResetPassword(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, queryTimeout)
+	defer cancel()
+
+	var req ResetPasswordRequest
+	err := decodeJSON(w, r, &req)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error hashing password: %w", err)))
+		return
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error starting transaction: %w", err)))
+		return
+	}
+	defer tx.Rollback()
+
+	userID, _, err := consumeToken(ctx, tx, req.Token, tokenPurposePasswordReset)
+	if err != nil {
+		if err == errInvalidToken {
+			apierror.Write(w, r, apierror.BadRequest("Invalid or expired token"))
+			return
+		}
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	// Token reset yang diterima lewat email juga membuktikan kepemilikan email
+	// Sesi yang mungkin dipegang penyerang ikut dicabut
+	query := `
+		UPDATE users SET password_hash = ?, email_verified_at = COALESCE(email_verified_at, NOW()), sessions_valid_after = NOW()
+		WHERE user_id = ?
+	`
+	_, err = tx.ExecContext(ctx, query, hash, userID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error resetting password: %w", err)))
+		return
+	}
+
+	// Reset password membuka kunci login untuk email akun ini
+	query = `DELETE FROM login_throttles WHERE throttle_key = (SELECT CONCAT('email:', LOWER(email)) FROM users WHERE user_id = ?)`
+	_, err = tx.ExecContext(ctx, query, userID)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error unlocking account: %w", err)))
+		return
+	}
+
+	// Token reset lain yang masih aktif tidak boleh dipakai lagi
+	_, err = tx.ExecContext(ctx, `UPDATE user_tokens SET used_at = NOW() WHERE user_id = ? AND purpose = ? AND used_at IS NULL`, userID, tokenPurposePasswordReset)
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error invalidating reset tokens: %w", err)))
+		return
+	}
+
+	err = recordAuditForUser(ctx, tx, r, userID, auditEvent{
+		Action:     auditPasswordReset,
+		EntityType: entityUser,
+		EntityID:   userID,
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(err))
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		apierror.Write(w, r, apierror.Internal(fmt.Errorf("error completing transaction: %w", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Response{Message: "Password reset successfully"})
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/XSAM/otelsql"
+	"github.com/go-sql-driver/mysql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// defaultDSN dipakai jika DB_DSN tidak diisi
+const defaultDSN = "root:@tcp(127.0.0.1:3306)/booking_system"
+
+// dbConfig berisi pengaturan koneksi dan connection pool database
+type dbConfig struct {
+	DSN             string
+	Location        *time.Location
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	ConnectTimeout  time.Duration
+}
+
+// loadDBConfig membaca pengaturan database dari environment variable
+func loadDBConfig() (dbConfig, error) {
+	cfg := dbConfig{
+		DSN:             os.Getenv("DB_DSN"),
+		MaxOpenConns:    envInt("DB_MAX_OPEN_CONNS", 25),
+		MaxIdleConns:    envInt("DB_MAX_IDLE_CONNS", 10),
+		ConnMaxLifetime: envDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+		ConnMaxIdleTime: envDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+		ConnectTimeout:  envDuration("DB_CONNECT_TIMEOUT", 30*time.Second),
+	}
+	if cfg.DSN == "" {
+		cfg.DSN = defaultDSN
+	}
+
+	tz := os.Getenv("DB_TIMEZONE")
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid DB_TIMEZONE %q: %w", tz, err)
+	}
+	cfg.Location = loc
+	return cfg, nil
+}
+
+// buildDSN melengkapi DSN dengan parseTime=true dan zona waktu eksplisit.
+// Zona waktu sesi MySQL disamakan dengan loc agar NOW() dan nilai yang
+// di-parse driver berada di zona yang sama. Offset dihitung saat startup,
+// jadi zona dengan daylight saving sebaiknya diganti UTC.
+func buildDSN(dsn string, loc *time.Location) (string, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", fmt.Errorf("invalid DB_DSN: %w", err)
+	}
+
+	cfg.ParseTime = true
+	cfg.Loc = loc
+	if cfg.Params == nil {
+		cfg.Params = map[string]string{}
+	}
+	cfg.Params["time_zone"] = "'" + time.Now().In(loc).Format("-07:00") + "'"
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	return cfg.FormatDSN(), nil
+}
+
+// openDatabase membuka connection pool lalu memastikan database bisa dihubungi.
+// Ping diulang dengan backoff eksponensial sampai cfg.ConnectTimeout habis
+// sehingga DSN yang salah langsung terlihat saat startup, bukan pada request pertama.
+func openDatabase(ctx context.Context, logger *slog.Logger, cfg dbConfig) (*sql.DB, error) {
+	dsn, err := buildDSN(cfg.DSN, cfg.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	// otelsql membuat span untuk setiap statement SQL (tanpa nilai argumen)
+	db, err := otelsql.Open("mysql", dsn,
+		otelsql.WithAttributes(attribute.String("db.system", "mysql")),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{OmitConnResetSession: true, OmitRows: true}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
+	defer cancel()
+
+	backoff := 500 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		err = db.PingContext(ctx)
+		if err == nil {
+			logger.Info("database connected",
+				"attempt", attempt,
+				"max_open_conns", cfg.MaxOpenConns,
+				"max_idle_conns", cfg.MaxIdleConns,
+				"timezone", cfg.Location.String(),
+			)
+			return db, nil
+		}
+
+		logger.Warn("database not reachable, retrying", "attempt", attempt, "retry_in", backoff.String(), "error", err)
+		select {
+		case <-ctx.Done():
+			db.Close()
+			return nil, fmt.Errorf("database not reachable after %d attempts: %w", attempt, err)
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > 5*time.Second {
+			backoff = 5 * time.Second
+		}
+	}
+}
+
+// envInt membaca bilangan bulat positif dari environment variable,
+// atau mengembalikan fallback jika kosong atau tidak valid
+func envInt(name string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// registerDBMetrics mengekspos sql.DBStats sebagai metrik Prometheus
+func registerDBMetrics(db *sql.DB) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "db_max_open_connections",
+		Help: "Maximum number of open connections to the database.",
+	}, func() float64 { return float64(db.Stats().MaxOpenConnections) })
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "db_open_connections",
+		Help: "Number of established connections, both in use and idle.",
+	}, func() float64 { return float64(db.Stats().OpenConnections) })
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "db_in_use_connections",
+		Help: "Number of connections currently in use.",
+	}, func() float64 { return float64(db.Stats().InUse) })
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "db_idle_connections",
+		Help: "Number of idle connections.",
+	}, func() float64 { return float64(db.Stats().Idle) })
+	promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "db_wait_count_total",
+		Help: "Total number of connections waited for.",
+	}, func() float64 { return float64(db.Stats().WaitCount) })
+	promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "db_wait_duration_seconds_total",
+		Help: "Total time blocked waiting for a new connection.",
+	}, func() float64 { return db.Stats().WaitDuration.Seconds() })
+	promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "db_max_idle_closed_total",
+		Help: "Total connections closed due to SetMaxIdleConns.",
+	}, func() float64 { return float64(db.Stats().MaxIdleClosed) })
+	promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "db_max_idle_time_closed_total",
+		Help: "Total connections closed due to SetConnMaxIdleTime.",
+	}, func() float64 { return float64(db.Stats().MaxIdleTimeClosed) })
+	promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "db_max_lifetime_closed_total",
+		Help: "Total connections closed due to SetConnMaxLifetime.",
+	}, func() float64 { return float64(db.Stats().MaxLifetimeClosed) })
+}
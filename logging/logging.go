@@ -0,0 +1,112 @@
+// Package logging menyediakan logger log/slog terstruktur untuk aplikasi,
+// termasuk penyimpanan logger per request di context dan redaksi field sensitif.
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+type contextKey struct{}
+
+// redactedValue menggantikan nilai field sensitif di log
+const redactedValue = "[REDACTED]"
+
+// sensitiveKeys berisi nama field (huruf kecil) yang nilainya tidak boleh masuk log
+var sensitiveKeys = map[string]bool{
+	"password":         true,
+	"current_password": true,
+	"new_password":     true,
+	"password_hash":    true,
+	"token":            true,
+	"authorization":    true,
+	"x-api-key":        true,
+	"api_key":          true,
+	"key":              true,
+	"secret":           true,
+	"totp_secret":      true,
+	"code":             true,
+	"recovery_codes":   true,
+	"payment_details":  true,
+	"card_number":      true,
+	"cvv":              true,
+}
+
+// New membuat logger JSON dengan level minimum level. Atribut dengan nama
+// sensitif otomatis diredaksi.
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if isSensitive(a.Key) {
+				return slog.String(a.Key, redactedValue)
+			}
+			return a
+		},
+	}))
+}
+
+// ParseLevel mengubah nama level (debug, info, warn, error) menjadi slog.Level, default info
+func ParseLevel(name string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// WithContext menyimpan logger ke context
+func WithContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext mengambil logger dari context, atau slog.Default() jika belum ada
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// Redact mengubah v menjadi bentuk JSON generik dengan semua field sensitif
+// diredaksi, sehingga struct request bisa dicatat dengan aman.
+func Redact(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return redactedValue
+	}
+
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return redactedValue
+	}
+	return redactValue(generic)
+}
+
+func redactValue(v any) any {
+	switch value := v.(type) {
+	case map[string]any:
+		for k, inner := range value {
+			if isSensitive(k) {
+				value[k] = redactedValue
+			} else {
+				value[k] = redactValue(inner)
+			}
+		}
+		return value
+	case []any:
+		for i, inner := range value {
+			value[i] = redactValue(inner)
+		}
+		return value
+	default:
+		return v
+	}
+}
+
+func isSensitive(key string) bool {
+	return sensitiveKeys[strings.ToLower(key)]
+}
@@ -1,87 +1,201 @@
 package main
 
 import (
-	"database/sql"
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+	"booking_system_app/blobstore"
 	"booking_system_app/database"   // Pastikan path ini sesuai dengan struktur project Anda
+	"booking_system_app/logging"
 	"booking_system_app/middleware" // Import middleware
+	"booking_system_app/notify"
+	"booking_system_app/tracing"
 	_ "github.com/go-sql-driver/mysql" // Driver MySQL
 )
 
 func main() {
-	// Konfigurasi DSN (Data Source Name) untuk MySQL
-	dsn := "root:@tcp(127.0.0.1:3306)/booking_system" // Ganti dengan credential database Anda
-	// Koneksi ke database
-	db, err := sql.Open("mysql", dsn)
+	// "openapi" mencetak atau memeriksa dokumen OpenAPI tanpa menjalankan server
+	if len(os.Args) > 1 && os.Args[1] == "openapi" {
+		os.Exit(runOpenAPICommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
+	// Logger terstruktur (JSON) dipakai oleh semua handler lewat context request
+	logger := logging.New(os.Stdout, logging.ParseLevel(os.Getenv("LOG_LEVEL")))
+	slog.SetDefault(logger)
+
+	// Tracing OpenTelemetry, exporter dipilih lewat OTEL_TRACES_EXPORTER (lihat package tracing)
+	shutdownTracing, err := tracing.Setup(context.Background())
 	if err != nil {
-		log.Fatal("Error connecting to the database: ", err)
+		logger.Error("error configuring tracing", "error", err)
+		os.Exit(1)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("error flushing traces", "error", err)
+		}
+	}()
+
+	// Koneksi ke database, DSN dan ukuran pool diatur lewat environment variable (lihat db.go)
+	dbCfg, err := loadDBConfig()
+	if err != nil {
+		logger.Error("invalid database configuration", "error", err)
+		os.Exit(1)
+	}
+	db, err := openDatabase(context.Background(), logger, dbCfg)
+	if err != nil {
+		logger.Error("error connecting to the database", "error", err)
+		os.Exit(1)
 	}
 	defer db.Close()
 
-	// Menyiapkan route untuk Register dan Login
-	http.HandleFunc("/register", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost {
-			database.RegisterUser(db, w, r)
-		} else {
-			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
-		}
-	})
+	// Konfigurasi pengiriman email: SMTP jika SMTP_HOST diisi, selain itu disimpan ke file
+	if err := configureMailer(); err != nil {
+		logger.Error("error configuring mailer", "error", err)
+		os.Exit(1)
+	}
 
-	http.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost {
-			database.LoginUser(db, w, r)
-		} else {
-			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
-		}
-	})
-
-	// Menambahkan route untuk properti dengan middleware untuk proteksi role
-	http.HandleFunc("/add_property", middleware.AuthMiddleware([]string{"staff", "admin"}, db, func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost {
-			database.AddProperty(db, w, r)  // Fungsi untuk menambahkan properti ke database
-		} else {
-			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
-		}
-	}))
+	// Kebijakan autentikasi. Wajib 2FA untuk staff dan admin nonaktif secara default:
+	// aktifkan setelah mereka diberi tahu, karena akun yang belum mendaftar TOTP
+	// langsung diminta enrollment pada login berikutnya
+	database.SetRequireEmailVerification(os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true")
+	database.SetRequire2FAForElevatedRoles(os.Getenv("REQUIRE_2FA_ELEVATED_ROLES") == "true")
+	// Umur maksimum sesi sejak login (misalnya "168h"), setelah itu token tidak bisa di-refresh
+	database.SetMaxSessionAge(envDuration("SESSION_MAX_AGE", 7*24*time.Hour))
 
-	http.HandleFunc("/add_room", middleware.AuthMiddleware([]string{"staff", "admin"}, db, func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost {
-			database.AddRoom(db, w, r)
-		} else {
-			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
-		}
-	}))
+	// Penyimpanan foto properti dan kamar di MEDIA_DIR
+	if err := configureBlobStore(); err != nil {
+		logger.Error("error configuring media storage", "error", err)
+		os.Exit(1)
+	}
+
+	// Batas waktu query per request (misalnya "5s"), booking memakai DB_TX_TIMEOUT
+	queryTimeout := envDuration("DB_QUERY_TIMEOUT", 5*time.Second)
+	database.SetQueryTimeouts(queryTimeout, envDuration("DB_TX_TIMEOUT", 15*time.Second))
+	middleware.SetQueryTimeout(queryTimeout)
+
+	// Statistik connection pool ikut diekspos di /metrics
+	registerDBMetrics(db)
+
+	// Tabel routing ada di routes.go
+	mux := newRouter(db)
+
+	// Semua request mendapat request ID, dicatat di access log dan dihitung di /metrics
+	handler := middleware.Tracing(middleware.RequestID(logger, middleware.AccessLog(middleware.Metrics(middleware.TraceRoute(mux)))))
+
+	// Server HTTP dengan timeout agar koneksi lambat tidak menahan resource
+	server := &http.Server{
+		Addr:              ":8080",
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelError),
+	}
 
-	http.HandleFunc("/update_room_status", middleware.AuthMiddleware([]string{"staff", "admin"}, db, func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPut {
-			database.UpdateRoomStatus(db, w, r)
-		} else {
-			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+	// SIGTERM/SIGINT memulai graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("server running", "addr", server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("error starting server", "error", err)
+			os.Exit(1)
 		}
-	}))
-
-	// Menambahkan route untuk pencarian kamar dengan middleware untuk proteksi role
-	http.HandleFunc("/search_rooms", middleware.AuthMiddleware([]string{"customer"}, db, func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost {
-			database.SearchRooms(db, w, r)
-		} else {
-			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	case <-ctx.Done():
+	}
+	stop()
+
+	// /readyz langsung gagal agar load balancer berhenti mengirim trafik baru,
+	// lalu request yang sedang berjalan (termasuk transaksi booking) ditunggu selesai
+	logger.Info("shutting down server")
+	database.SetDraining(true)
+
+	shutdownTimeout := envDuration("SHUTDOWN_TIMEOUT", 30*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err = server.Shutdown(shutdownCtx)
+	if err != nil {
+		logger.Error("error shutting down server", "error", err, "timeout", shutdownTimeout.String())
+		return
+	}
+	logger.Info("server stopped")
+}
+
+// envDuration membaca durasi (misalnya "30s") dari environment variable,
+// atau mengembalikan fallback jika kosong atau tidak valid
+func envDuration(name string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// configureBlobStore menyiapkan direktori file media dan batas ukuran unggahan foto
+func configureBlobStore() error {
+	dir := os.Getenv("MEDIA_DIR")
+	if dir == "" {
+		dir = "media"
+	}
+	store, err := blobstore.NewLocalStore(dir)
+	if err != nil {
+		return err
+	}
+	database.SetBlobStore(store)
+
+	if value := os.Getenv("PHOTO_MAX_BYTES"); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid PHOTO_MAX_BYTES %q", value)
 		}
-	}))
+		database.SetMaxPhotoBytes(n)
+	}
+	return nil
+}
 
-	http.HandleFunc("/booking", middleware.AuthMiddleware([]string{"customer"}, db, func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost {
-			database.BookRoom(db, w, r)
-		} else {
-			http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+// configureMailer memilih implementasi notify.Mailer berdasarkan environment variable
+func configureMailer() error {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@booking-system.local"
+	}
+
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+		if err != nil {
+			port = 587
 		}
-	}))
-	
-	// Menjalankan server HTTP di port 8080
-	log.Println("Server running on port 8080...")
-	err = http.ListenAndServe(":8080", nil)
+		database.SetMailer(notify.NewSMTPMailer(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from))
+		return nil
+	}
+
+	dir := os.Getenv("MAIL_OUTBOX_DIR")
+	if dir == "" {
+		dir = "mail_outbox"
+	}
+	fileMailer, err := notify.NewFileMailer(dir, from)
 	if err != nil {
-		log.Fatal("Error starting server: ", err)
+		return err
 	}
-}
\ No newline at end of file
+	database.SetMailer(fileMailer)
+	return nil
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+    "context"
+    "crypto/sha256"
+    "database/sql"
+    "encoding/hex"
+    "fmt"
+    "net/http"
+    "strings"
+
+    "booking_system_app/apierror"
+    "booking_system_app/logging"
+)
+
+// APIKeyPrefix adalah awalan semua API key yang diterbitkan sistem
+const APIKeyPrefix = "bk_"
+
+// apiKeyFromRequest mengambil API key dari header "Authorization: ApiKey <key>" atau "X-API-Key"
+func apiKeyFromRequest(r *http.Request) string {
+    if key := r.Header.Get("X-API-Key"); key != "" {
+        return key
+    }
+
+    parts := strings.Split(r.Header.Get("Authorization"), " ")
+    if len(parts) == 2 && parts[0] == "ApiKey" {
+        return parts[1]
+    }
+    return ""
+}
+
+// HashAPIKey menghitung hash SHA-256 yang disimpan di tabel api_keys
+func HashAPIKey(key string) string {
+    sum := sha256.Sum256([]byte(key))
+    return hex.EncodeToString(sum[:])
+}
+
+// authenticateAPIKey mencari API key yang masih aktif dan mengembalikan principal
+// dengan role milik pengguna pemilik key
+func authenticateAPIKey(ctx context.Context, db *sql.DB, r *http.Request, key string) (*Principal, *apierror.Error) {
+    if !strings.HasPrefix(key, APIKeyPrefix) {
+        return nil, apierror.Unauthorized("Unauthorized: Invalid API key")
+    }
+
+    var apiKeyID int
+    var scopes, email, role string
+    var disabled bool
+    query := `
+        SELECT k.api_key_id, k.scopes, u.email, u.role, u.disabled
+        FROM api_keys k
+        JOIN users u ON k.user_id = u.user_id
+        WHERE k.key_hash = ? AND k.revoked_at IS NULL AND (k.expires_at IS NULL OR k.expires_at > NOW())
+          AND u.deleted_at IS NULL
+    `
+    err := db.QueryRowContext(ctx, query, HashAPIKey(key)).Scan(&apiKeyID, &scopes, &email, &role, &disabled)
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return nil, apierror.Unauthorized("Unauthorized: Invalid or expired API key")
+        }
+        return nil, apierror.Internal(fmt.Errorf("error fetching API key from database: %w", err))
+    }
+    if disabled {
+        return nil, apierror.Forbidden("Forbidden: Account disabled")
+    }
+
+    // Kegagalan mencatat last_used_at tidak boleh menggagalkan request
+    _, err = db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = NOW() WHERE api_key_id = ?`, apiKeyID)
+    if err != nil {
+        logging.FromContext(r.Context()).Error("error updating API key last_used_at", "api_key_id", apiKeyID, "error", err)
+    }
+
+    return &Principal{Email: email, Role: role, APIKeyID: apiKeyID, Scopes: strings.Split(scopes, ",")}, nil
+}
@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+const (
+	apiKeyQuery     = `SELECT k.api_key_id, k.scopes, u.email, u.role, u.disabled\s+FROM api_keys k`
+	apiKeyLastUsed  = `UPDATE api_keys SET last_used_at = NOW\(\) WHERE api_key_id = \?`
+	testAPIKey      = APIKeyPrefix + "0123456789abcdef"
+	testAPIKeyEmail = "partner@example.com"
+)
+
+func expectAPIKey(mock sqlmock.Sqlmock, scopes, role string, disabled bool) {
+	mock.ExpectQuery(apiKeyQuery).
+		WithArgs(HashAPIKey(testAPIKey)).
+		WillReturnRows(sqlmock.NewRows([]string{"api_key_id", "scopes", "email", "role", "disabled"}).
+			AddRow(9, scopes, testAPIKeyEmail, role, disabled))
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	headers := map[string]func(*http.Request){
+		"X-API-Key header":     func(r *http.Request) { r.Header.Set("X-API-Key", testAPIKey) },
+		"Authorization ApiKey": func(r *http.Request) { r.Header.Set("Authorization", "ApiKey "+testAPIKey) },
+	}
+
+	for name, setHeader := range headers {
+		t.Run(name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			expectAPIKey(mock, PermRoomWrite+","+PermBookingCheckin, "staff", false)
+			mock.ExpectExec(apiKeyLastUsed).WithArgs(9).WillReturnResult(sqlmock.NewResult(0, 1))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			setHeader(req)
+			rec, principal := serve(db, PermRoomWrite, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			if principal == nil || principal.APIKeyID != 9 || principal.Email != testAPIKeyEmail || principal.Role != "staff" {
+				t.Fatalf("principal = %+v", principal)
+			}
+			if len(principal.Scopes) != 2 {
+				t.Errorf("Scopes = %v, want 2 entries", principal.Scopes)
+			}
+		})
+	}
+}
+
+func TestAPIKeyScopeLimitsRole(t *testing.T) {
+	// Role staff memiliki property:write, tetapi key ini hanya diberi room:write
+	db, mock := newMockDB(t)
+	expectAPIKey(mock, PermRoomWrite, "staff", false)
+	mock.ExpectExec(apiKeyLastUsed).WithArgs(9).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-API-Key", testAPIKey)
+	rec, principal := serve(db, PermPropertyWrite, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if principal != nil {
+		t.Error("handler called for out-of-scope key")
+	}
+}
+
+func TestAPIKeyRejected(t *testing.T) {
+	t.Run("missing prefix", func(t *testing.T) {
+		// Key tanpa awalan ditolak tanpa query
+		db, _ := newMockDB(t)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-API-Key", "0123456789abcdef")
+		rec, _ := serve(db, PermRoomWrite, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("unknown, revoked or expired", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		mock.ExpectQuery(apiKeyQuery).
+			WithArgs(HashAPIKey(testAPIKey)).
+			WillReturnRows(sqlmock.NewRows([]string{"api_key_id", "scopes", "email", "role", "disabled"}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-API-Key", testAPIKey)
+		rec, _ := serve(db, PermRoomWrite, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("owner disabled", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectAPIKey(mock, PermRoomWrite, "staff", true)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-API-Key", testAPIKey)
+		rec, _ := serve(db, PermRoomWrite, req)
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+	})
+}
+
+func TestAPIKeyLastUsedFailureIgnored(t *testing.T) {
+	db, mock := newMockDB(t)
+	expectAPIKey(mock, PermRoomWrite, "staff", false)
+	mock.ExpectExec(apiKeyLastUsed).WithArgs(9).WillReturnError(errors.New("connection reset"))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-API-Key", testAPIKey)
+	rec, principal := serve(db, PermRoomWrite, req)
+	if rec.Code != http.StatusOK || principal == nil {
+		t.Fatalf("status = %d, principal = %+v; want the request to succeed", rec.Code, principal)
+	}
+}
+
+func TestAPIKeyTakesPrecedenceOverBearer(t *testing.T) {
+	// Jika X-API-Key ada, JWT di Authorization tidak diperiksa
+	db, mock := newMockDB(t)
+	expectAPIKey(mock, PermRoomWrite, "staff", false)
+	mock.ExpectExec(apiKeyLastUsed).WithArgs(9).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-API-Key", testAPIKey)
+	req.Header.Set("Authorization", "Bearer not-a-jwt")
+	rec, principal := serve(db, PermRoomWrite, req)
+	if rec.Code != http.StatusOK || principal == nil || principal.APIKeyID != 9 {
+		t.Fatalf("status = %d, principal = %+v", rec.Code, principal)
+	}
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+    "net/http"
+
+    "booking_system_app/logging"
+)
+
+// Deprecated menandai route lama yang masih dilayani demi klien yang sudah ada.
+// Respons diberi header Deprecation dan Link ke route pengganti, dan setiap
+// pemakaiannya dicatat agar klien yang belum migrasi bisa dilacak.
+func Deprecated(successor string, next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Deprecation", "true")
+        w.Header().Set("Link", "<"+successor+">; rel=\"successor-version\"")
+        logging.FromContext(r.Context()).Warn("deprecated route used",
+            "method", r.Method,
+            "path", r.URL.Path,
+            "successor", successor,
+        )
+        next.ServeHTTP(w, r)
+    })
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "log/slog"
+    "net/http"
+    "time"
+
+    "booking_system_app/logging"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader adalah header yang membawa ID korelasi request
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// RequestIDFromRequest mengembalikan ID korelasi request yang dipasang oleh RequestID
+func RequestIDFromRequest(r *http.Request) string {
+    id, _ := r.Context().Value(requestIDKey{}).(string)
+    return id
+}
+
+// RequestID memakai X-Request-ID dari klien (jika valid) atau membuat yang baru,
+// mengembalikannya di header respons, dan memasang logger dengan request_id ke context
+func RequestID(logger *slog.Logger, next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        id := r.Header.Get(RequestIDHeader)
+        if !validRequestID(id) {
+            id = newRequestID()
+        }
+        w.Header().Set(RequestIDHeader, id)
+
+        ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+        requestLogger := logger.With("request_id", id)
+
+        // Log dan trace bisa dikorelasikan lewat trace_id jika tracing aktif
+        span := trace.SpanFromContext(ctx)
+        if span.SpanContext().IsValid() {
+            span.SetAttributes(attribute.String("request.id", id))
+            requestLogger = requestLogger.With("trace_id", span.SpanContext().TraceID().String())
+        }
+        ctx = logging.WithContext(ctx, requestLogger)
+        next.ServeHTTP(w, r.WithContext(ctx))
+    })
+}
+
+// AccessLog mencatat setiap request beserta status, ukuran respons dan latensinya
+func AccessLog(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        start := time.Now()
+        recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+        next.ServeHTTP(recorder, r)
+
+        logging.FromContext(r.Context()).Info("http request",
+            "method", r.Method,
+            "path", r.URL.Path,
+            "status", recorder.status,
+            "bytes", recorder.bytes,
+            "latency_ms", float64(time.Since(start).Microseconds())/1000,
+            "remote_addr", r.RemoteAddr,
+            "user_agent", r.UserAgent(),
+        )
+    })
+}
+
+// statusRecorder menyimpan status dan jumlah byte yang ditulis handler
+type statusRecorder struct {
+    http.ResponseWriter
+    status      int
+    bytes       int
+    wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+    if !s.wroteHeader {
+        s.status = status
+        s.wroteHeader = true
+    }
+    s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+    s.wroteHeader = true
+    n, err := s.ResponseWriter.Write(b)
+    s.bytes += n
+    return n, err
+}
+
+// Unwrap memungkinkan http.ResponseController mengakses ResponseWriter asli
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+    return s.ResponseWriter
+}
+
+// validRequestID menerima ID dari klien hanya jika pendek dan berisi karakter aman
+func validRequestID(id string) bool {
+    if id == "" || len(id) > 128 {
+        return false
+    }
+    for _, c := range id {
+        if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+            return false
+        }
+    }
+    return true
+}
+
+func newRequestID() string {
+    buf := make([]byte, 16)
+    rand.Read(buf)
+    return hex.EncodeToString(buf)
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+    "net/http"
+    "strconv"
+    "time"
+
+    "booking_system_app/apierror"
+    "github.com/prometheus/client_golang/prometheus"
+    "github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+    httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+        Name: "http_requests_total",
+        Help: "HTTP requests by method, route pattern and status code.",
+    }, []string{"method", "route", "status"})
+    httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+        Name:    "http_request_duration_seconds",
+        Help:    "HTTP request latency by method and route pattern.",
+        Buckets: prometheus.DefBuckets,
+    }, []string{"method", "route"})
+    authRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+        Name: "auth_requests_total",
+        Help: "Authentication checks on protected routes by credential type and result.",
+    }, []string{"credential", "result"})
+)
+
+// Metrics mencatat jumlah request dan latensi per route. Label route memakai
+// pola ServeMux (misalnya "POST /properties/{id}/rooms") yang diisi mux setelah
+// routing, bukan path mentah, agar jumlah seri tetap terbatas.
+func Metrics(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        start := time.Now()
+        recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+        next.ServeHTTP(recorder, r)
+
+        route := r.Pattern
+        if route == "" {
+            route = "unmatched"
+        }
+        httpRequestsTotal.WithLabelValues(r.Method, route, strconv.Itoa(recorder.status)).Inc()
+        httpRequestDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+    })
+}
+
+// recordAuth mencatat hasil autentikasi/otorisasi untuk metrik
+func recordAuth(r *http.Request, result string) {
+    credential := "jwt"
+    if apiKeyFromRequest(r) != "" {
+        credential = "api_key"
+    }
+    authRequestsTotal.WithLabelValues(credential, result).Inc()
+}
+
+// authFailureResult mengubah error autentikasi menjadi label metrik
+func authFailureResult(err *apierror.Error) string {
+    switch {
+    case err.Status >= http.StatusInternalServerError:
+        return "error"
+    case err.Status == http.StatusForbidden:
+        return "disabled"
+    default:
+        return "unauthorized"
+    }
+}
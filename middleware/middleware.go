@@ -1,16 +1,68 @@
 package middleware
 
 import (
+    "context"
     "database/sql"
     "net/http"
     "strings"
     "fmt"
+    "time"
+    "booking_system_app/apierror"
+    "booking_system_app/tracing"
     "github.com/golang-jwt/jwt/v4"
     _ "github.com/go-sql-driver/mysql" // Pastikan driver MySQL sudah terpasang
 )
 
 var secretKey = []byte("your_secret_key")
 
+// queryTimeout membatasi lama query autentikasi per request
+var queryTimeout = 5 * time.Second
+
+// SetQueryTimeout mengatur batas waktu query autentikasi, nilai <= 0 diabaikan
+func SetQueryTimeout(timeout time.Duration) {
+    if timeout > 0 {
+        queryTimeout = timeout
+    }
+}
+
+type contextKey string
+
+const principalKey contextKey = "principal"
+
+// Principal adalah identitas yang sudah diautentikasi, baik lewat JWT maupun API key.
+// Scopes hanya diisi untuk API key dan membatasi permission role pemiliknya.
+type Principal struct {
+    Email    string
+    Role     string
+    APIKeyID int
+    Scopes   []string
+    // SessionStart adalah waktu login awal (klaim orig_iat) untuk token sesi,
+    // nol untuk API key dan token lama
+    SessionStart time.Time
+}
+
+// CurrentPrincipal mengembalikan principal yang disimpan middleware, atau nil jika tidak ada
+func CurrentPrincipal(r *http.Request) *Principal {
+    principal, _ := r.Context().Value(principalKey).(*Principal)
+    return principal
+}
+
+// UserEmail mengembalikan email pengguna yang sudah diverifikasi oleh RequirePermission
+func UserEmail(r *http.Request) string {
+    if principal := CurrentPrincipal(r); principal != nil {
+        return principal.Email
+    }
+    return ""
+}
+
+// UserRole mengembalikan role pengguna yang sudah diverifikasi oleh middleware
+func UserRole(r *http.Request) string {
+    if principal := CurrentPrincipal(r); principal != nil {
+        return principal.Role
+    }
+    return ""
+}
+
 // Fungsi untuk mendapatkan email dari klaim token JWT
 func getEmailFromClaims(claims jwt.MapClaims) (string, error) {
     email, ok := claims["email"].(string)
@@ -20,84 +72,128 @@ func getEmailFromClaims(claims jwt.MapClaims) (string, error) {
     return email, nil
 }
 
-// Fungsi untuk mendapatkan role pengguna berdasarkan email dari database
-func getRoleFromEmail(db *sql.DB, email string) (string, error) {
+var (
+    errAccountDisabled = fmt.Errorf("account disabled")
+    errUserNotFound    = fmt.Errorf("user not found")
+    errSessionRevoked  = fmt.Errorf("session revoked")
+)
+
+// Fungsi untuk mendapatkan role pengguna berdasarkan email dari database.
+// Akun yang dinonaktifkan admin ditolak di sini sehingga token lamanya langsung tidak berlaku.
+// Token yang diterbitkan (issuedAt) sebelum sessions_valid_after juga ditolak.
+func getRoleFromEmail(ctx context.Context, db *sql.DB, email string, issuedAt time.Time) (string, error) {
     var role string
-    query := `SELECT role FROM users WHERE email = ?`
-    err := db.QueryRow(query, email).Scan(&role)
+    var disabled bool
+    var validAfter sql.NullTime
+    query := `SELECT role, disabled, sessions_valid_after FROM users WHERE email = ?`
+    err := db.QueryRowContext(ctx, query, email).Scan(&role, &disabled, &validAfter)
     if err != nil {
         if err == sql.ErrNoRows {
-            return "", fmt.Errorf("user not found")
+            return "", errUserNotFound
         }
-        return "", fmt.Errorf("error fetching user role from database: %v", err)
+        return "", fmt.Errorf("error fetching user role from database: %w", err)
+    }
+    if disabled {
+        return "", errAccountDisabled
+    }
+    if validAfter.Valid && issuedAt.Before(validAfter.Time) {
+        return "", errSessionRevoked
     }
     return role, nil
 }
 
-// AuthMiddleware untuk melindungi route berdasarkan role pengguna
-func AuthMiddleware(allowedRoles []string, db *sql.DB, next http.HandlerFunc) http.HandlerFunc {
-    return func(w http.ResponseWriter, r *http.Request) {
-        authHeader := r.Header.Get("Authorization")
-        if authHeader == "" {
-            http.Error(w, "Unauthorized: Missing token", http.StatusUnauthorized)
-            return
-        }
+// claimTime membaca klaim waktu (detik Unix), nol jika klaim tidak ada
+func claimTime(claims jwt.MapClaims, name string) time.Time {
+    seconds, ok := claims[name].(float64)
+    if !ok {
+        return time.Time{}
+    }
+    return time.Unix(int64(seconds), 0)
+}
 
-        parts := strings.Split(authHeader, " ")
-        if len(parts) != 2 || parts[0] != "Bearer" {
-            http.Error(w, "Unauthorized: Invalid token format", http.StatusUnauthorized)
-            return
-        }
+// authenticate memvalidasi kredensial pada request (Bearer JWT atau API key)
+// lalu mengembalikan principal pemiliknya
+func authenticate(db *sql.DB, r *http.Request) (*Principal, *apierror.Error) {
+    ctx, cancel := context.WithTimeout(r.Context(), queryTimeout)
+    defer cancel()
 
-        tokenString := parts[1]
+    if apiKey := apiKeyFromRequest(r); apiKey != "" {
+        keyCtx, keySpan := tracing.Start(ctx, "auth.api_key")
+        defer keySpan.End()
+        return authenticateAPIKey(keyCtx, db, r, apiKey)
+    }
 
-        token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-            if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-                return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-            }
-            return secretKey, nil
-        })
+    authHeader := r.Header.Get("Authorization")
+    if authHeader == "" {
+        return nil, apierror.Unauthorized("Unauthorized: Missing token")
+    }
 
-        if err != nil || !token.Valid {
-            http.Error(w, fmt.Sprintf("Invalid Token: %v", err), http.StatusUnauthorized)
-            return
-        }
+    parts := strings.Split(authHeader, " ")
+    if len(parts) != 2 || parts[0] != "Bearer" {
+        return nil, apierror.Unauthorized("Unauthorized: Invalid token format")
+    }
 
-        claims, ok := token.Claims.(jwt.MapClaims)
-        if !ok {
-            http.Error(w, "Invalid claims", http.StatusUnauthorized)
-            return
-        }
+    tokenString := parts[1]
 
-        // Menggunakan fungsi untuk mengambil email dari klaim
-        email, err := getEmailFromClaims(claims)
-        if err != nil {
-            http.Error(w, err.Error(), http.StatusUnauthorized)
-            return
+    _, parseSpan := tracing.Start(ctx, "auth.parse_token")
+    token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+        if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+            return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
         }
+        return secretKey, nil
+    })
 
-        // Menggunakan fungsi untuk mendapatkan role berdasarkan email
-        role, err := getRoleFromEmail(db, email)
-        if err != nil {
-            http.Error(w, err.Error(), http.StatusUnauthorized)
-            return
-        }
+    parseSpan.End()
+    if err != nil || !token.Valid {
+        return nil, apierror.Unauthorized("Unauthorized: Invalid or expired token")
+    }
 
-        // Verifikasi apakah role sesuai dengan role yang diizinkan
-        if !contains(allowedRoles, role) {
-            http.Error(w, "Forbidden: Insufficient role", http.StatusForbidden)
-            return
-        }
+    claims, ok := token.Claims.(jwt.MapClaims)
+    if !ok {
+        return nil, apierror.Unauthorized("Invalid claims")
+    }
 
-        // Menambahkan email ke dalam header respons jika diperlukan
-        w.Header().Set("X-User-Email", email)
+    // Token parsial dari langkah password belum boleh dipakai sebelum 2FA selesai
+    if pending, _ := claims["mfa_pending"].(bool); pending {
+        return nil, apierror.Unauthorized("Unauthorized: Two-factor authentication not completed")
+    }
 
-        // Melanjutkan eksekusi request
-        next.ServeHTTP(w, r)
+    // Menggunakan fungsi untuk mengambil email dari klaim
+    email, err := getEmailFromClaims(claims)
+    if err != nil {
+        return nil, apierror.Unauthorized(err.Error())
+    }
+
+    // Menggunakan fungsi untuk mendapatkan role berdasarkan email
+    roleCtx, roleSpan := tracing.Start(ctx, "auth.get_role_from_email")
+    role, err := getRoleFromEmail(roleCtx, db, email, claimTime(claims, "iat"))
+    roleSpan.End()
+    if err == errAccountDisabled {
+        return nil, apierror.Forbidden("Forbidden: Account disabled")
     }
+    if err == errSessionRevoked {
+        return nil, apierror.Unauthorized("Unauthorized: Session revoked, please login again")
+    }
+    if err == errUserNotFound {
+        return nil, apierror.Unauthorized("Unauthorized: User not found")
+    }
+    if err != nil {
+        return nil, apierror.Internal(err)
+    }
+
+    return &Principal{Email: email, Role: role, SessionStart: claimTime(claims, "orig_iat")}, nil
+}
+
+// withUser menyimpan principal ke context agar handler tahu siapa aktornya
+func withUser(w http.ResponseWriter, r *http.Request, principal *Principal) *http.Request {
+    // Menambahkan email ke dalam header respons jika diperlukan
+    w.Header().Set("X-User-Email", principal.Email)
+
+    ctx := context.WithValue(r.Context(), principalKey, principal)
+    return r.WithContext(ctx)
 }
 
-// Helper function untuk memeriksa apakah role ada dalam allowedRoles
+// Helper function untuk memeriksa apakah str ada dalam slice
 func contains(slice []string, str string) bool {
     for _, v := range slice {
         if v == str {
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+    "database/sql"
+    "net/http"
+    "sort"
+
+    "booking_system_app/apierror"
+)
+
+// Permission yang bisa diberikan ke role
+const (
+    PermProfileManage  = "profile:manage"
+    PermPropertyWrite  = "property:write"
+    PermRoomWrite      = "room:write"
+    PermRoomSearch     = "room:search"
+    PermBookingCreate  = "booking:create"
+    PermBookingCheckin = "booking:checkin"
+    PermReviewReply    = "review:reply"
+    PermReviewModerate = "review:moderate"
+    PermUserManage     = "user:manage"
+    PermSystemMonitor  = "system:monitor"
+)
+
+// rolePermissions memetakan setiap role ke permission yang dimilikinya.
+// Permission staff untuk properti dan kamar masih dibatasi lagi oleh
+// penugasan properti (staff_property_assignments) di handler.
+var rolePermissions = map[string][]string{
+    "customer": {
+        PermProfileManage,
+        PermRoomSearch,
+        PermBookingCreate,
+    },
+    "staff": {
+        PermProfileManage,
+        PermPropertyWrite,
+        PermRoomWrite,
+        PermBookingCheckin,
+        PermReviewReply,
+    },
+    "admin": {
+        PermProfileManage,
+        PermPropertyWrite,
+        PermRoomWrite,
+        PermBookingCheckin,
+        PermReviewReply,
+        PermReviewModerate,
+        PermUserManage,
+        PermSystemMonitor,
+    },
+}
+
+// HasPermission memeriksa apakah role memiliki permission tertentu
+func HasPermission(role, permission string) bool {
+    return contains(rolePermissions[role], permission)
+}
+
+// Can memeriksa permission principal. Untuk API key, permission harus dimiliki
+// role pemilik key dan juga tercantum di scope key tersebut.
+func (p *Principal) Can(permission string) bool {
+    if !HasPermission(p.Role, permission) {
+        return false
+    }
+    if p.APIKeyID != 0 {
+        return contains(p.Scopes, permission)
+    }
+    return true
+}
+
+// IsPermission memeriksa apakah nama permission dikenal sistem
+func IsPermission(permission string) bool {
+    for _, permissions := range rolePermissions {
+        if contains(permissions, permission) {
+            return true
+        }
+    }
+    return false
+}
+
+// Permissions mengembalikan semua permission yang dikenal sistem, terurut
+func Permissions() []string {
+    var all []string
+    for _, permissions := range rolePermissions {
+        for _, permission := range permissions {
+            if !contains(all, permission) {
+                all = append(all, permission)
+            }
+        }
+    }
+    sort.Strings(all)
+    return all
+}
+
+// RequirePermission melindungi route berdasarkan permission, bukan daftar nama role
+func RequirePermission(permission string, db *sql.DB, next http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        principal, authErr := authenticate(db, r)
+        if authErr != nil {
+            recordAuth(r, authFailureResult(authErr))
+            apierror.Write(w, r, authErr)
+            return
+        }
+
+        if !principal.Can(permission) {
+            recordAuth(r, "denied")
+            apierror.Write(w, r, apierror.Forbidden("Forbidden: Missing permission "+permission))
+            return
+        }
+
+        recordAuth(r, "success")
+        next.ServeHTTP(w, withUser(w, r, principal))
+    }
+}
@@ -0,0 +1,209 @@
+package middleware
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const roleQuery = `SELECT role, disabled, sessions_valid_after FROM users WHERE email = \?`
+
+// newMockDB membuat *sql.DB tiruan yang mencocokkan query dengan regexp.
+// Semua ekspektasi harus terpenuhi saat test selesai.
+func newMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	return db, mock
+}
+
+// signTestJWT menandatangani klaim dengan secretKey milik middleware
+func signTestJWT(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func sessionClaims(email string, issuedAt time.Time) jwt.MapClaims {
+	return jwt.MapClaims{
+		"email":    email,
+		"iat":      issuedAt.Unix(),
+		"orig_iat": issuedAt.Unix(),
+		"exp":      issuedAt.Add(time.Hour).Unix(),
+	}
+}
+
+// serve menjalankan RequirePermission dan mengembalikan principal yang diterima handler
+func serve(db *sql.DB, permission string, req *http.Request) (*httptest.ResponseRecorder, *Principal) {
+	var got *Principal
+	handler := RequirePermission(permission, db, func(w http.ResponseWriter, r *http.Request) {
+		got = CurrentPrincipal(r)
+	})
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec, got
+}
+
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding error body: %v", err)
+	}
+	return body.Error.Code
+}
+
+func TestRequirePermissionSession(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name       string
+		permission string
+		role       string
+		disabled   bool
+		validAfter interface{}
+		wantStatus int
+	}{
+		{"admin allowed", PermUserManage, "admin", false, nil, http.StatusOK},
+		{"customer lacks permission", PermUserManage, "customer", false, nil, http.StatusForbidden},
+		{"staff allowed", PermRoomWrite, "staff", false, nil, http.StatusOK},
+		{"staff cannot moderate", PermReviewModerate, "staff", false, nil, http.StatusForbidden},
+		{"disabled account", PermProfileManage, "customer", true, nil, http.StatusForbidden},
+		{"revoked session", PermProfileManage, "customer", false, now.Add(time.Minute), http.StatusUnauthorized},
+		{"session after revocation", PermProfileManage, "customer", false, now.Add(-time.Minute), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.ExpectQuery(roleQuery).
+				WithArgs("user@example.com").
+				WillReturnRows(sqlmock.NewRows([]string{"role", "disabled", "sessions_valid_after"}).
+					AddRow(tt.role, tt.disabled, tt.validAfter))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+signTestJWT(t, sessionClaims("user@example.com", now)))
+			rec, principal := serve(db, tt.permission, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if principal != nil {
+					t.Error("handler called for rejected request")
+				}
+				return
+			}
+			if principal == nil || principal.Email != "user@example.com" || principal.Role != tt.role {
+				t.Fatalf("principal = %+v", principal)
+			}
+			if principal.SessionStart.Unix() != now.Unix() {
+				t.Errorf("SessionStart = %v, want %v", principal.SessionStart, now)
+			}
+			if rec.Header().Get("X-User-Email") != "user@example.com" {
+				t.Errorf("X-User-Email = %q", rec.Header().Get("X-User-Email"))
+			}
+		})
+	}
+}
+
+func TestRequirePermissionRejectsBadTokens(t *testing.T) {
+	now := time.Now()
+	pending := sessionClaims("user@example.com", now)
+	pending["mfa_pending"] = true
+	expired := sessionClaims("user@example.com", now.Add(-2*time.Hour))
+	otherKey, err := jwt.NewWithClaims(jwt.SigningMethodHS256, sessionClaims("user@example.com", now)).SignedString([]byte("other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{"missing", ""},
+		{"not bearer", "Token abc"},
+		{"malformed", "Bearer not-a-jwt"},
+		{"wrong key", "Bearer " + otherKey},
+		{"expired", "Bearer " + signTestJWT(t, expired)},
+		{"mfa pending", "Bearer " + signTestJWT(t, pending)},
+		{"no email", "Bearer " + signTestJWT(t, jwt.MapClaims{"exp": now.Add(time.Hour).Unix()})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Tidak ada query yang diharapkan: token ditolak sebelum menyentuh database
+			db, _ := newMockDB(t)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec, principal := serve(db, PermProfileManage, req)
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+			if principal != nil {
+				t.Error("handler called for rejected request")
+			}
+			if code := errorCode(t, rec); code == "" {
+				t.Error("error envelope has no code")
+			}
+		})
+	}
+}
+
+func TestRequirePermissionUnknownUser(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectQuery(roleQuery).WithArgs("gone@example.com").WillReturnError(sql.ErrNoRows)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+signTestJWT(t, sessionClaims("gone@example.com", time.Now())))
+	rec, _ := serve(db, PermProfileManage, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestPrincipalCan(t *testing.T) {
+	tests := []struct {
+		name       string
+		principal  Principal
+		permission string
+		want       bool
+	}{
+		{"session uses role", Principal{Role: "staff"}, PermRoomWrite, true},
+		{"role without permission", Principal{Role: "customer"}, PermRoomWrite, false},
+		{"key within scope", Principal{Role: "staff", APIKeyID: 1, Scopes: []string{PermRoomWrite}}, PermRoomWrite, true},
+		{"key outside scope", Principal{Role: "staff", APIKeyID: 1, Scopes: []string{PermRoomWrite}}, PermPropertyWrite, false},
+		{"scope beyond role", Principal{Role: "customer", APIKeyID: 1, Scopes: []string{PermUserManage}}, PermUserManage, false},
+		{"unknown role", Principal{Role: "guest"}, PermRoomSearch, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.principal.Can(tt.permission); got != tt.want {
+				t.Errorf("Can(%q) = %v, want %v", tt.permission, got, tt.want)
+			}
+		})
+	}
+}
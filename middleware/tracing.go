@@ -0,0 +1,31 @@
+package middleware
+
+import (
+    "net/http"
+
+    "go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/trace"
+)
+
+// Tracing membuka span server untuk setiap request dan meneruskan konteks trace
+// dari header traceparent. Harus dipasang paling luar agar semua middleware
+// (request ID, access log, autentikasi) termasuk di dalam span.
+func Tracing(next http.Handler) http.Handler {
+    return otelhttp.NewHandler(next, "http.request")
+}
+
+// TraceRoute mengganti nama span request dengan pola route ServeMux
+// (misalnya "POST /properties/{id}/rooms"). Dipasang tepat di sekitar mux
+// karena pola route baru diketahui setelah routing.
+func TraceRoute(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        next.ServeHTTP(w, r)
+
+        if r.Pattern != "" {
+            span := trace.SpanFromContext(r.Context())
+            span.SetName(r.Pattern)
+            span.SetAttributes(attribute.String("http.route", r.Pattern))
+        }
+    })
+}
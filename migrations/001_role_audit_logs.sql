@@ -0,0 +1,20 @@
+--
+-- Struktur dari tabel `role_audit_logs`
+--
+-- Mencatat setiap role yang diberikan atau diubah pada akun pengguna.
+-- `old_role` bernilai NULL ketika akun baru dibuat.
+--
+
+CREATE TABLE `role_audit_logs` (
+  `role_audit_log_id` int(11) NOT NULL AUTO_INCREMENT,
+  `user_id` int(11) NOT NULL,
+  `old_role` enum('customer','staff','admin') DEFAULT NULL,
+  `new_role` enum('customer','staff','admin') NOT NULL,
+  `changed_by` int(11) DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`role_audit_log_id`),
+  KEY `user_id` (`user_id`),
+  KEY `changed_by` (`changed_by`),
+  CONSTRAINT `role_audit_logs_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `users` (`user_id`),
+  CONSTRAINT `role_audit_logs_ibfk_2` FOREIGN KEY (`changed_by`) REFERENCES `users` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,28 @@
+--
+-- Penanda akun yang sudah dihapus (data pribadi dianonimkan)
+--
+
+ALTER TABLE `users`
+  ADD COLUMN `deleted_at` timestamp NULL DEFAULT NULL;
+
+--
+-- Struktur dari tabel `user_tokens`
+--
+-- Token sekali pakai milik pengguna, misalnya untuk konfirmasi perubahan email.
+-- Hanya hash SHA-256 dari token yang disimpan.
+--
+
+CREATE TABLE `user_tokens` (
+  `user_token_id` int(11) NOT NULL AUTO_INCREMENT,
+  `user_id` int(11) NOT NULL,
+  `purpose` varchar(50) NOT NULL,
+  `token_hash` char(64) NOT NULL,
+  `payload` varchar(255) DEFAULT NULL,
+  `expires_at` datetime NOT NULL,
+  `used_at` datetime DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`user_token_id`),
+  UNIQUE KEY `token_hash` (`token_hash`),
+  KEY `user_id` (`user_id`),
+  CONSTRAINT `user_tokens_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `users` (`user_id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,9 @@
+--
+-- Penanda email yang sudah diverifikasi pemiliknya
+--
+
+ALTER TABLE `users`
+  ADD COLUMN `email_verified_at` timestamp NULL DEFAULT NULL;
+
+-- Akun yang sudah ada sebelum verifikasi email diperkenalkan dianggap terverifikasi
+UPDATE `users` SET `email_verified_at` = `created_at` WHERE `email_verified_at` IS NULL;
@@ -0,0 +1,6 @@
+--
+-- Flag untuk menonaktifkan akun pengguna oleh admin
+--
+
+ALTER TABLE `users`
+  ADD COLUMN `disabled` tinyint(1) NOT NULL DEFAULT 0;
@@ -0,0 +1,17 @@
+--
+-- Struktur dari tabel `staff_property_assignments`
+--
+-- Staff hanya boleh mengelola kamar pada properti yang ditugaskan kepadanya.
+--
+
+CREATE TABLE `staff_property_assignments` (
+  `user_id` int(11) NOT NULL,
+  `property_id` int(11) NOT NULL,
+  `assigned_by` int(11) DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`user_id`, `property_id`),
+  KEY `property_id` (`property_id`),
+  CONSTRAINT `staff_property_assignments_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `users` (`user_id`) ON DELETE CASCADE,
+  CONSTRAINT `staff_property_assignments_ibfk_2` FOREIGN KEY (`property_id`) REFERENCES `properties` (`property_id`) ON DELETE CASCADE,
+  CONSTRAINT `staff_property_assignments_ibfk_3` FOREIGN KEY (`assigned_by`) REFERENCES `users` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
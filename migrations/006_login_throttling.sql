@@ -0,0 +1,34 @@
+--
+-- Struktur dari tabel `login_throttles`
+--
+-- Hitungan kegagalan login dan kunci sementara per email (`email:<alamat>`)
+-- dan per IP (`ip:<alamat>`). Disimpan di database agar tetap berlaku setelah restart.
+--
+
+CREATE TABLE `login_throttles` (
+  `throttle_key` varchar(191) NOT NULL,
+  `failed_count` int(11) NOT NULL DEFAULT 0,
+  `last_failed_at` datetime DEFAULT NULL,
+  `locked_until` datetime DEFAULT NULL,
+  PRIMARY KEY (`throttle_key`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Struktur dari tabel `login_attempts`
+--
+-- Audit setiap percobaan login, berhasil maupun gagal.
+--
+
+CREATE TABLE `login_attempts` (
+  `login_attempt_id` int(11) NOT NULL AUTO_INCREMENT,
+  `email` varchar(100) DEFAULT NULL,
+  `ip_address` varchar(45) DEFAULT NULL,
+  `user_id` int(11) DEFAULT NULL,
+  `success` tinyint(1) NOT NULL DEFAULT 0,
+  `result` varchar(50) NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`login_attempt_id`),
+  KEY `email` (`email`),
+  KEY `ip_address` (`ip_address`),
+  KEY `user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,28 @@
+--
+-- Kolom TOTP (RFC 6238) untuk autentikasi dua faktor
+--
+-- `totp_secret` berisi secret base32; sebelum `totp_enabled` = 1 secret ini
+-- masih menunggu konfirmasi kode pertama. `totp_last_step` mencegah kode dipakai ulang.
+--
+
+ALTER TABLE `users`
+  ADD COLUMN `totp_secret` varchar(64) DEFAULT NULL,
+  ADD COLUMN `totp_enabled` tinyint(1) NOT NULL DEFAULT 0,
+  ADD COLUMN `totp_last_step` bigint(20) DEFAULT NULL;
+
+--
+-- Struktur dari tabel `totp_recovery_codes`
+--
+-- Recovery code sekali pakai, disimpan sebagai hash SHA-256.
+--
+
+CREATE TABLE `totp_recovery_codes` (
+  `totp_recovery_code_id` int(11) NOT NULL AUTO_INCREMENT,
+  `user_id` int(11) NOT NULL,
+  `code_hash` char(64) NOT NULL,
+  `used_at` datetime DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`totp_recovery_code_id`),
+  KEY `user_id` (`user_id`),
+  CONSTRAINT `totp_recovery_codes_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `users` (`user_id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,26 @@
+--
+-- Struktur dari tabel `api_keys`
+--
+-- API key untuk partner OTA dan skrip internal. Key bertindak sebagai
+-- pengguna `user_id` dan dibatasi oleh `scopes` (daftar permission dipisah koma).
+-- Hanya hash SHA-256 dari key yang disimpan; `key_prefix` dipakai untuk identifikasi.
+--
+
+CREATE TABLE `api_keys` (
+  `api_key_id` int(11) NOT NULL AUTO_INCREMENT,
+  `user_id` int(11) NOT NULL,
+  `name` varchar(100) NOT NULL,
+  `key_prefix` varchar(16) NOT NULL,
+  `key_hash` char(64) NOT NULL,
+  `scopes` varchar(255) NOT NULL,
+  `expires_at` datetime DEFAULT NULL,
+  `last_used_at` datetime DEFAULT NULL,
+  `revoked_at` datetime DEFAULT NULL,
+  `created_by` int(11) DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`api_key_id`),
+  UNIQUE KEY `key_hash` (`key_hash`),
+  KEY `user_id` (`user_id`),
+  CONSTRAINT `api_keys_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `users` (`user_id`) ON DELETE CASCADE,
+  CONSTRAINT `api_keys_ibfk_2` FOREIGN KEY (`created_by`) REFERENCES `users` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
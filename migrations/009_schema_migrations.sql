@@ -0,0 +1,17 @@
+--
+-- Struktur dari tabel `schema_migrations`
+--
+-- Mencatat versi migrasi yang sudah dijalankan. Endpoint /readyz menolak
+-- melayani trafik selama versi tertinggi di sini lebih kecil dari versi yang
+-- diharapkan aplikasi (database.SchemaVersion). Setiap migrasi baru harus
+-- menambahkan barisnya sendiri di akhir file.
+--
+
+CREATE TABLE `schema_migrations` (
+  `version` int(11) NOT NULL,
+  `applied_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Migrasi 001-008 dijalankan sebelum tabel ini ada
+INSERT INTO `schema_migrations` (`version`) VALUES (1), (2), (3), (4), (5), (6), (7), (8), (9);
@@ -0,0 +1,41 @@
+--
+-- Struktur dari tabel `audit_logs`
+--
+-- Jejak audit append-only untuk setiap operasi yang mengubah data. Baris ditulis
+-- dalam transaksi yang sama dengan perubahannya. `actor_email` disimpan apa
+-- adanya (tanpa foreign key) agar catatan tetap utuh walaupun akun dihapus.
+-- `before_data`/`after_data` berisi snapshot JSON dengan field sensitif diredaksi.
+--
+
+CREATE TABLE `audit_logs` (
+  `audit_id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `actor_user_id` int(11) DEFAULT NULL,
+  `actor_email` varchar(100) DEFAULT NULL,
+  `actor_api_key_id` int(11) DEFAULT NULL,
+  `action` varchar(50) NOT NULL,
+  `entity_type` varchar(50) NOT NULL,
+  `entity_id` varchar(64) DEFAULT NULL,
+  `before_data` longtext DEFAULT NULL,
+  `after_data` longtext DEFAULT NULL,
+  `ip_address` varchar(45) DEFAULT NULL,
+  `request_id` varchar(64) DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`audit_id`),
+  KEY `entity` (`entity_type`, `entity_id`),
+  KEY `actor_user_id` (`actor_user_id`),
+  KEY `action` (`action`),
+  KEY `created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Trigger yang menjaga tabel tetap append-only
+--
+
+DELIMITER $$
+CREATE TRIGGER `audit_logs_no_update` BEFORE UPDATE ON `audit_logs`
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_logs is append-only'$$
+CREATE TRIGGER `audit_logs_no_delete` BEFORE DELETE ON `audit_logs`
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_logs is append-only'$$
+DELIMITER ;
+
+INSERT INTO `schema_migrations` (`version`) VALUES (10);
@@ -0,0 +1,15 @@
+--
+-- Koordinat properti untuk pencarian berdasarkan jarak dan area peta
+--
+-- Diisi staff lewat POST /properties atau PUT /properties/{id}/location.
+-- Properti tanpa koordinat tidak muncul di pencarian yang memakai `near`
+-- atau `bounds`. Index dipakai untuk menyaring kotak lintang/bujur sebelum
+-- jarak sebenarnya dihitung.
+--
+
+ALTER TABLE `properties`
+  ADD COLUMN `latitude` decimal(9,6) DEFAULT NULL,
+  ADD COLUMN `longitude` decimal(9,6) DEFAULT NULL,
+  ADD KEY `location` (`latitude`, `longitude`);
+
+INSERT INTO `schema_migrations` (`version`) VALUES (11);
@@ -0,0 +1,70 @@
+--
+-- Kapasitas, ukuran dan konfigurasi tempat tidur kamar
+--
+-- Anak boleh menempati slot dewasa, sehingga kapasitas total kamar adalah
+-- `max_adults` + `max_children`. Kamar lama mendapat default 2 dewasa.
+--
+
+ALTER TABLE `rooms`
+  ADD COLUMN `max_adults` tinyint(3) UNSIGNED NOT NULL DEFAULT 2,
+  ADD COLUMN `max_children` tinyint(3) UNSIGNED NOT NULL DEFAULT 0,
+  ADD COLUMN `size_sqm` decimal(6,1) DEFAULT NULL,
+  ADD KEY `capacity` (`max_adults`, `max_children`);
+
+CREATE TABLE `room_beds` (
+  `room_id` int(11) NOT NULL,
+  `bed_type` enum('single','double','queen','king','sofa_bed','bunk') NOT NULL,
+  `quantity` tinyint(3) UNSIGNED NOT NULL DEFAULT 1,
+  PRIMARY KEY (`room_id`, `bed_type`),
+  CONSTRAINT `room_beds_ibfk_1` FOREIGN KEY (`room_id`) REFERENCES `rooms` (`room_id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+--
+-- Struktur dari tabel `amenities`
+--
+-- Katalog fasilitas yang bisa ditautkan ke kamar maupun properti. Klien
+-- memakai `code`; fasilitas baru ditambahkan lewat migrasi.
+--
+
+CREATE TABLE `amenities` (
+  `amenity_id` int(11) NOT NULL AUTO_INCREMENT,
+  `code` varchar(50) NOT NULL,
+  `name` varchar(100) NOT NULL,
+  PRIMARY KEY (`amenity_id`),
+  UNIQUE KEY `code` (`code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+INSERT INTO `amenities` (`code`, `name`) VALUES
+  ('wifi', 'Wi-Fi'),
+  ('air_conditioning', 'Air conditioning'),
+  ('balcony', 'Balcony'),
+  ('sea_view', 'Sea view'),
+  ('tv', 'TV'),
+  ('minibar', 'Minibar'),
+  ('bathtub', 'Bathtub'),
+  ('kitchenette', 'Kitchenette'),
+  ('parking', 'Parking'),
+  ('swimming_pool', 'Swimming pool'),
+  ('breakfast', 'Breakfast included'),
+  ('gym', 'Gym'),
+  ('airport_shuttle', 'Airport shuttle');
+
+CREATE TABLE `room_amenities` (
+  `room_id` int(11) NOT NULL,
+  `amenity_id` int(11) NOT NULL,
+  PRIMARY KEY (`room_id`, `amenity_id`),
+  KEY `amenity_id` (`amenity_id`),
+  CONSTRAINT `room_amenities_ibfk_1` FOREIGN KEY (`room_id`) REFERENCES `rooms` (`room_id`) ON DELETE CASCADE,
+  CONSTRAINT `room_amenities_ibfk_2` FOREIGN KEY (`amenity_id`) REFERENCES `amenities` (`amenity_id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `property_amenities` (
+  `property_id` int(11) NOT NULL,
+  `amenity_id` int(11) NOT NULL,
+  PRIMARY KEY (`property_id`, `amenity_id`),
+  KEY `amenity_id` (`amenity_id`),
+  CONSTRAINT `property_amenities_ibfk_1` FOREIGN KEY (`property_id`) REFERENCES `properties` (`property_id`) ON DELETE CASCADE,
+  CONSTRAINT `property_amenities_ibfk_2` FOREIGN KEY (`amenity_id`) REFERENCES `amenities` (`amenity_id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+INSERT INTO `schema_migrations` (`version`) VALUES (12);
@@ -0,0 +1,34 @@
+--
+-- Struktur dari tabel `photos`
+--
+-- Foto properti (`room_id` NULL) dan foto kamar. File asli dan thumbnail-nya
+-- disimpan di BlobStore dengan key `storage_key`/`thumbnail_key`; baris yang
+-- terhapus lewat cascade tidak menghapus file-nya. `position` dimulai dari 1
+-- per properti atau per kamar, dan paling banyak satu foto per kelompok itu
+-- yang `is_cover`.
+--
+
+CREATE TABLE `photos` (
+  `photo_id` int(11) NOT NULL AUTO_INCREMENT,
+  `property_id` int(11) NOT NULL,
+  `room_id` int(11) DEFAULT NULL,
+  `storage_key` varchar(255) NOT NULL,
+  `thumbnail_key` varchar(255) NOT NULL,
+  `content_type` varchar(50) NOT NULL,
+  `size_bytes` int(11) NOT NULL,
+  `width` int(11) NOT NULL,
+  `height` int(11) NOT NULL,
+  `position` int(11) NOT NULL,
+  `is_cover` tinyint(1) NOT NULL DEFAULT 0,
+  `uploaded_by` int(11) DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`photo_id`),
+  KEY `property_position` (`property_id`, `room_id`, `position`),
+  KEY `room_position` (`room_id`, `position`),
+  KEY `uploaded_by` (`uploaded_by`),
+  CONSTRAINT `photos_ibfk_1` FOREIGN KEY (`property_id`) REFERENCES `properties` (`property_id`) ON DELETE CASCADE,
+  CONSTRAINT `photos_ibfk_2` FOREIGN KEY (`room_id`) REFERENCES `rooms` (`room_id`) ON DELETE CASCADE,
+  CONSTRAINT `photos_ibfk_3` FOREIGN KEY (`uploaded_by`) REFERENCES `users` (`user_id`) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+INSERT INTO `schema_migrations` (`version`) VALUES (13);
@@ -0,0 +1,54 @@
+--
+-- Ulasan tamu dan rating agregat properti
+--
+-- Satu ulasan per booking, hanya untuk booking berstatus `completed` milik
+-- penulisnya. Rating per kategori bernilai 1-5; `rating` adalah rata-rata
+-- ketiganya. Ulasan `hidden` (hasil moderasi) tidak tampil publik dan tidak
+-- dihitung di agregat.
+--
+-- Kolom `rating_*` di `properties` adalah agregat ulasan `published` yang
+-- dihitung ulang setiap kali ulasan properti itu dibuat atau dimoderasi,
+-- sehingga pencarian bisa memfilter dan mengurutkan berdasarkan rating
+-- tanpa join ke `reviews`.
+--
+
+CREATE TABLE `reviews` (
+  `review_id` int(11) NOT NULL AUTO_INCREMENT,
+  `booking_id` int(11) NOT NULL,
+  `user_id` int(11) DEFAULT NULL,
+  `property_id` int(11) NOT NULL,
+  `cleanliness` tinyint(1) NOT NULL,
+  `service` tinyint(1) NOT NULL,
+  `location` tinyint(1) NOT NULL,
+  `rating` decimal(3,2) NOT NULL,
+  `comment` text DEFAULT NULL,
+  `status` enum('published','hidden') NOT NULL DEFAULT 'published',
+  `moderation_reason` varchar(255) DEFAULT NULL,
+  `moderated_by` int(11) DEFAULT NULL,
+  `moderated_at` timestamp NULL DEFAULT NULL,
+  `reply` text DEFAULT NULL,
+  `replied_by` int(11) DEFAULT NULL,
+  `replied_at` timestamp NULL DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`review_id`),
+  UNIQUE KEY `booking_id` (`booking_id`),
+  KEY `property_status` (`property_id`, `status`, `created_at`),
+  KEY `user_id` (`user_id`),
+  KEY `moderated_by` (`moderated_by`),
+  KEY `replied_by` (`replied_by`),
+  CONSTRAINT `reviews_ibfk_1` FOREIGN KEY (`booking_id`) REFERENCES `bookings` (`booking_id`) ON DELETE CASCADE,
+  CONSTRAINT `reviews_ibfk_2` FOREIGN KEY (`user_id`) REFERENCES `users` (`user_id`) ON DELETE SET NULL,
+  CONSTRAINT `reviews_ibfk_3` FOREIGN KEY (`property_id`) REFERENCES `properties` (`property_id`) ON DELETE CASCADE,
+  CONSTRAINT `reviews_ibfk_4` FOREIGN KEY (`moderated_by`) REFERENCES `users` (`user_id`) ON DELETE SET NULL,
+  CONSTRAINT `reviews_ibfk_5` FOREIGN KEY (`replied_by`) REFERENCES `users` (`user_id`) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+ALTER TABLE `properties`
+  ADD COLUMN `rating_count` int(11) NOT NULL DEFAULT 0,
+  ADD COLUMN `rating_average` decimal(3,2) DEFAULT NULL,
+  ADD COLUMN `rating_cleanliness` decimal(3,2) DEFAULT NULL,
+  ADD COLUMN `rating_service` decimal(3,2) DEFAULT NULL,
+  ADD COLUMN `rating_location` decimal(3,2) DEFAULT NULL,
+  ADD KEY `rating` (`rating_average`);
+
+INSERT INTO `schema_migrations` (`version`) VALUES (14);
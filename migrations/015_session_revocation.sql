@@ -0,0 +1,12 @@
+--
+-- Pencabutan sesi JWT
+--
+-- JWT yang diterbitkan (klaim `iat`) sebelum `sessions_valid_after` ditolak
+-- middleware. Kolom diisi saat password diganti atau direset dan saat akun
+-- dinonaktifkan, sehingga token lama tidak bisa dipakai maupun di-refresh.
+--
+
+ALTER TABLE `users`
+  ADD COLUMN `sessions_valid_after` timestamp NULL DEFAULT NULL;
+
+INSERT INTO `schema_migrations` (`version`) VALUES (15);
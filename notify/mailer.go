@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message adalah email yang akan dikirim ke satu penerima
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer mengirim email. Implementasi yang tersedia: SMTPMailer untuk
+// produksi, serta FileMailer dan MemoryMailer untuk pengembangan dan pengujian.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPMailer mengirim email melalui server SMTP
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// NewSMTPMailer membuat SMTPMailer baru
+func NewSMTPMailer(host string, port int, username, password, from string) *SMTPMailer {
+	return &SMTPMailer{Host: host, Port: port, Username: username, Password: password, From: from}
+}
+
+// Send mengirim email lewat SMTP. Autentikasi PLAIN hanya dipakai jika username diisi.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	addr := net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+	err := smtp.SendMail(addr, auth, m.From, []string{msg.To}, formatMessage(m.From, msg))
+	if err != nil {
+		return fmt.Errorf("error sending email to %s: %v", msg.To, err)
+	}
+	return nil
+}
+
+// FileMailer menyimpan setiap email sebagai file .eml di Dir
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+// NewFileMailer membuat FileMailer baru dan memastikan direktori tujuan ada
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating mail directory: %v", err)
+	}
+	return &FileMailer{Dir: dir, From: from}, nil
+}
+
+// Send menulis email ke file baru di Dir
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitizeFileName(msg.To))
+	err := os.WriteFile(filepath.Join(m.Dir, name), formatMessage(m.From, msg), 0o600)
+	if err != nil {
+		return fmt.Errorf("error writing email to file: %v", err)
+	}
+	return nil
+}
+
+// MemoryMailer menyimpan email di memori, berguna untuk pengujian
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemoryMailer membuat MemoryMailer kosong
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+// Send menyimpan email ke dalam daftar pesan
+func (m *MemoryMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages mengembalikan salinan semua email yang sudah dikirim
+func (m *MemoryMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
+
+// formatMessage menyusun email dalam format RFC 5322 sederhana
+func formatMessage(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// sanitizeFileName mengganti karakter yang tidak aman untuk nama file
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_' || r == '@' {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMemoryMailer(t *testing.T) {
+	m := NewMemoryMailer()
+	messages := []Message{
+		{To: "a@example.com", Subject: "Verify your email address", Body: "token-a"},
+		{To: "b@example.com", Subject: "Reset your password", Body: "token-b"},
+	}
+	for _, msg := range messages {
+		if err := m.Send(context.Background(), msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := m.Messages()
+	if !reflect.DeepEqual(got, messages) {
+		t.Errorf("Messages() = %v, want %v", got, messages)
+	}
+	// Messages mengembalikan salinan
+	got[0].To = "changed@example.com"
+	if m.Messages()[0].To != "a@example.com" {
+		t.Error("Messages() exposes internal slice")
+	}
+}
+
+func TestSendCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	fileMailer, err := NewFileMailer(t.TempDir(), "noreply@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	memory := NewMemoryMailer()
+	for _, mailer := range []Mailer{memory, fileMailer, NewSMTPMailer("localhost", 25, "", "", "noreply@example.com")} {
+		if err := mailer.Send(ctx, Message{To: "a@example.com"}); !errors.Is(err, context.Canceled) {
+			t.Errorf("%T.Send() = %v, want context.Canceled", mailer, err)
+		}
+	}
+	if len(memory.Messages()) != 0 {
+		t.Error("canceled message was stored")
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m, err := NewFileMailer(dir, "noreply@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Send(context.Background(), Message{To: "user+tag@example.com", Subject: "Hello", Body: "line 1\nline 2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*-user_tag@example.com.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("files = %v, err = %v", files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []string{
+		"From: noreply@example.com\r\n",
+		"To: user+tag@example.com\r\n",
+		"Subject: Hello\r\n",
+		"\r\n\r\nline 1\r\nline 2",
+	} {
+		if !strings.Contains(string(data), part) {
+			t.Errorf("email does not contain %q:\n%s", part, data)
+		}
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"user@example.com", "user@example.com"},
+		{"../etc/passwd", ".._etc_passwd"},
+		{"a b+c", "a_b_c"},
+	}
+	for _, tt := range tests {
+		if got := sanitizeFileName(tt.in); got != tt.want {
+			t.Errorf("sanitizeFileName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"unicode"
+
+	"booking_system_app/apierror"
+	"booking_system_app/database"
+	"booking_system_app/middleware"
+	"booking_system_app/openapi"
+)
+
+// apiVersion dicantumkan di info.version dokumen OpenAPI
+const apiVersion = "1.0.0"
+
+// specFile adalah salinan dokumen OpenAPI yang di-commit untuk tim frontend/partner.
+// CI menjalankan "go run . openapi -check" agar file ini selalu sama dengan kode.
+const specFile = "openapi.json"
+
+// Parameter query yang dipakai beberapa route daftar
+var (
+	listParams = []openapi.Parameter{
+		queryParam("limit", "integer", "Items per page (default 20, max 100)"),
+		queryParam("cursor", "string", "next_cursor from the previous page"),
+		queryParam("sort", "string", "Sort field, prefix with - for descending order"),
+	}
+	userListParams = append([]openapi.Parameter{
+		queryParam("q", "string", "Search by email or name"),
+	}, listParams...)
+	legacyUserBookingsParams = append([]openapi.Parameter{
+		queryParam("user_id", "integer", "User whose bookings are listed"),
+	}, listParams...)
+	staffAssignmentParams = append([]openapi.Parameter{
+		queryParam("user_id", "integer", "Only assignments of this staff user"),
+		queryParam("property_id", "integer", "Only assignments for this property"),
+	}, listParams...)
+	apiKeyListParams = append([]openapi.Parameter{
+		queryParam("user_id", "integer", "Only keys owned by this user"),
+	}, listParams...)
+	reviewListParams = append([]openapi.Parameter{
+		queryParam("status", "string", "Only reviews with this status (published or hidden)"),
+		queryParam("property_id", "integer", "Only reviews of this property"),
+	}, listParams...)
+	auditLogParams = append([]openapi.Parameter{
+		queryParam("actor", "string", "Actor email"),
+		queryParam("actor_user_id", "integer", "Actor user ID"),
+		queryParam("action", "string", "Action, for example booking.create"),
+		queryParam("entity_type", "string", "Entity type, for example room"),
+		queryParam("entity_id", "string", "Entity ID"),
+		queryParam("request_id", "string", "Request ID (X-Request-ID)"),
+		queryParam("from", "string", "Start time, RFC 3339 or YYYY-MM-DD"),
+		queryParam("to", "string", "End time, RFC 3339 or YYYY-MM-DD (a date is inclusive)"),
+	}, listParams...)
+)
+
+func queryParam(name, typ, description string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: &openapi.Schema{Type: typ}}
+}
+
+// extraRoutes adalah route di luar tabel routes yang tetap didokumentasikan
+var extraRoutes = []openapi.Route{
+	{Pattern: "GET /metrics", OperationID: "Metrics", Summary: "Prometheus metrics", Tag: "system", ContentType: "text/plain"},
+	{Pattern: "GET /openapi.json", OperationID: "OpenAPI", Summary: "This OpenAPI document", Tag: "system", Response: map[string]interface{}{}},
+	{Pattern: "GET /docs", OperationID: "Docs", Summary: "Swagger UI", Tag: "system", ContentType: "text/html"},
+}
+
+// buildOpenAPI membangun dokumen OpenAPI dari tabel routes dan tipe Go di package database
+func buildOpenAPI() (*openapi.Document, error) {
+	b := openapi.NewBuilder(openapi.Info{
+		Title:   "Booking System API",
+		Version: apiVersion,
+		Description: "Errors use the envelope {\"error\": {\"code\", \"message\", \"details\", \"request_id\"}}. " +
+			"Protected routes accept a JWT from /login (Authorization: Bearer) or an API key (X-API-Key); " +
+			"x-permission names the RBAC permission the route requires.",
+	})
+	b.AddSecurityScheme("bearerAuth", openapi.SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"})
+	b.AddSecurityScheme("apiKeyAuth", openapi.SecurityScheme{Type: "apiKey", In: "header", Name: "X-API-Key"})
+	for _, tag := range []struct{ name, description string }{
+		{"auth", "Registration, login and account recovery"},
+		{"account", "The logged-in user's own account"},
+		{"properties", "Properties and their rooms"},
+		{"rooms", "Room status and search"},
+		{"bookings", "Bookings"},
+		{"admin", "Admin console"},
+		{"system", "Health, monitoring and documentation"},
+		{"legacy", "Deprecated action-style routes, kept for existing clients"},
+	} {
+		b.AddTag(tag.name, tag.description)
+	}
+
+	enums := database.FieldEnums()
+	enums["CreateAPIKeyRequest.scopes"] = middleware.Permissions()
+	b.SetEnums(enums)
+	if err := b.SetErrorResponse(apierror.Envelope{}); err != nil {
+		return nil, err
+	}
+
+	// Dokumentasi route utama per handler, diwarisi route alias deprecated
+	primary := map[uintptr]route{}
+	for _, rt := range routes {
+		key := reflect.ValueOf(rt.handler).Pointer()
+		if _, ok := primary[key]; !ok && rt.successor == "" {
+			primary[key] = rt
+		}
+	}
+
+	for _, rt := range routes {
+		name := handlerName(rt.handler)
+		op := openapi.Route{
+			Pattern:     rt.pattern,
+			OperationID: name,
+			Summary:     summarize(name),
+			Tag:         routeTag(rt),
+			Request:     rt.request,
+			Response:    rt.response,
+			ContentType: rt.contentType,
+			Status:      rt.status,
+			Query:       rt.query,
+			Permission:  rt.permission,
+
+			RequestContentType: rt.requestContentType,
+		}
+		if rt.permission != "" {
+			op.Security = []string{"bearerAuth", "apiKeyAuth"}
+		}
+		if rt.successor != "" {
+			// Handler khusus route lama sudah bernama "...Legacy"
+			if !strings.HasSuffix(op.OperationID, "Legacy") {
+				op.OperationID += "Legacy"
+			}
+			op.Deprecated = true
+			op.Description = "Deprecated, use " + rt.successor + " instead."
+			base := primary[reflect.ValueOf(rt.handler).Pointer()]
+			if op.Request == nil {
+				op.Request = base.request
+			}
+			if op.Response == nil {
+				op.Response = base.response
+			}
+			if op.Status == 0 {
+				op.Status = base.status
+			}
+			if op.Query == nil {
+				op.Query = base.query
+			}
+		}
+		if err := b.Add(op); err != nil {
+			return nil, err
+		}
+	}
+	for _, op := range extraRoutes {
+		if err := b.Add(op); err != nil {
+			return nil, err
+		}
+	}
+	return b.Document(), nil
+}
+
+// handlerName mengambil nama fungsi handler, misalnya "BookRoom"
+func handlerName(h handlerFunc) string {
+	name := runtime.FuncForPC(reflect.ValueOf(h).Pointer()).Name()
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// summarize mengubah nama handler menjadi kalimat, "BookRoom" menjadi "Book room"
+func summarize(name string) string {
+	runes := []rune(name)
+	var words []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		// Kata baru dimulai di huruf besar setelah huruf kecil ("BookRoom")
+		// atau di huruf besar terakhir sebuah singkatan ("APIKey")
+		if unicode.IsUpper(runes[i]) && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	words = append(words, string(runes[start:]))
+
+	// Singkatan seperti "API" tetap huruf besar
+	for i := 1; i < len(words); i++ {
+		if strings.ToUpper(words[i]) != words[i] {
+			words[i] = strings.ToLower(words[i])
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// routeTag mengelompokkan route berdasarkan segmen path pertama
+func routeTag(rt route) string {
+	if rt.successor != "" {
+		return "legacy"
+	}
+	_, path, _ := strings.Cut(rt.pattern, " ")
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	switch segment {
+	case "admin", "properties", "rooms", "bookings":
+		return segment
+	case "amenities":
+		return "rooms"
+	case "reviews":
+		return "properties"
+	case "photos", "media":
+		return "properties"
+	case "me":
+		return "account"
+	case "healthz", "readyz":
+		return "system"
+	}
+	return "auth"
+}
+
+// registerOpenAPI melayani dokumen di /openapi.json dan Swagger UI di /docs.
+// Dokumen dibangun saat startup; route yang tidak terdokumentasi membuat server gagal start.
+func registerOpenAPI(mux *http.ServeMux) {
+	doc, err := buildOpenAPI()
+	if err != nil {
+		panic(err)
+	}
+	spec, err := openapi.Marshal(doc)
+	if err != nil {
+		panic(err)
+	}
+	mux.Handle("GET /openapi.json", openapi.Handler(spec))
+	mux.Handle("GET /docs", openapi.UIHandler())
+}
+
+// runOpenAPICommand menjalankan subcommand "openapi":
+//
+//	go run . openapi              tulis dokumen ke stdout
+//	go run . openapi -o FILE      tulis dokumen ke FILE
+//	go run . openapi -check       pastikan openapi.json sama dengan kode (untuk CI)
+func runOpenAPICommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("openapi", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	output := flags.String("o", "", "write the document to this file instead of stdout")
+	check := flags.Bool("check", false, "fail if "+specFile+" is out of date")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	doc, err := buildOpenAPI()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	spec, err := openapi.Marshal(doc)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	switch {
+	case *check:
+		committed, err := os.ReadFile(specFile)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		if !bytes.Equal(committed, spec) {
+			fmt.Fprintf(stderr, "%s is out of date, run: go run . openapi -o %s\n", specFile, specFile)
+			return 1
+		}
+		fmt.Fprintf(stdout, "%s is up to date (%d paths)\n", specFile, len(doc.Paths))
+	case *output != "":
+		if err := os.WriteFile(*output, spec, 0o644); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	default:
+		stdout.Write(spec)
+	}
+	return 0
+}
@@ -0,0 +1,38 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+)
+
+// uiPage adalah halaman Swagger UI yang memuat /openapi.json
+//
+//go:embed ui.html
+var uiPage []byte
+
+// Marshal meng-encode dokumen sebagai JSON terindentasi dengan newline di akhir,
+// format yang sama dengan file openapi.json di repository
+func Marshal(doc *Document) ([]byte, error) {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Handler melayani dokumen yang sudah di-encode di GET /openapi.json
+func Handler(spec []byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(spec)
+	})
+}
+
+// UIHandler melayani Swagger UI di GET /docs
+func UIHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(uiPage)
+	})
+}
@@ -0,0 +1,285 @@
+// Package openapi membangun dokumen OpenAPI 3.1 langsung dari tipe Go yang
+// dipakai handler, sehingga spesifikasi tidak bisa berbeda dari kode.
+// Dokumen dilayani di /openapi.json dan ditampilkan lewat Swagger UI di /docs.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version adalah versi OpenAPI yang dihasilkan
+const Version = "3.1.0"
+
+// Document adalah dokumen OpenAPI lengkap
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+	Tags       []Tag               `json:"tags,omitempty"`
+}
+
+// Info berisi judul dan versi API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Tag mengelompokkan operasi di Swagger UI
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem memetakan method (huruf kecil) ke operasi
+type PathItem map[string]*Operation
+
+// Components berisi schema dan skema keamanan yang dirujuk operasi
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme menjelaskan cara mengirim kredensial
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// Operation adalah satu endpoint pada dokumen
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	// Permission adalah permission RBAC yang dibutuhkan (ekstensi x-permission)
+	Permission string `json:"x-permission,omitempty"`
+}
+
+// Parameter adalah parameter path atau query
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody adalah body JSON yang diterima operasi
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response adalah satu kemungkinan respons operasi
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType merujuk schema untuk satu content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Route adalah deskripsi endpoint yang akan dimasukkan ke dokumen
+type Route struct {
+	// Pattern memakai sintaks ServeMux: "METHOD /path/{param}"
+	Pattern     string
+	OperationID string
+	Summary     string
+	Description string
+	Tag         string
+	// Request dan Response berisi nilai contoh (biasanya zero value) dari tipe
+	// body; nil berarti operasi tidak punya body request atau respons JSON
+	Request  interface{}
+	Response interface{}
+	// RequestContentType body request selain JSON, misalnya multipart/form-data
+	// untuk unggahan file
+	RequestContentType string
+	// ContentType respons selain JSON, misalnya text/plain untuk /metrics
+	ContentType string
+	// Status sukses, default 200
+	Status     int
+	Query      []Parameter
+	Permission string
+	// Security adalah nama security scheme yang diterima, kosong untuk route publik
+	Security   []string
+	Deprecated bool
+}
+
+// Builder mengumpulkan route lalu menghasilkan Document
+type Builder struct {
+	doc   *Document
+	types map[string]reflect.Type
+	enums map[string][]string
+	// errorSchema dipakai untuk respons default (error) setiap operasi
+	errorSchema *Schema
+}
+
+// NewBuilder membuat Builder untuk API dengan judul dan versi tertentu
+func NewBuilder(info Info) *Builder {
+	return &Builder{
+		doc: &Document{
+			OpenAPI:    Version,
+			Info:       info,
+			Paths:      map[string]PathItem{},
+			Components: Components{Schemas: map[string]*Schema{}, SecuritySchemes: map[string]SecurityScheme{}},
+		},
+		types: map[string]reflect.Type{},
+		enums: map[string][]string{},
+	}
+}
+
+// SetEnums mendaftarkan nilai enum per field dengan key "NamaStruct.field_json".
+// Harus dipanggil sebelum Add agar ikut masuk ke schema.
+func (b *Builder) SetEnums(enums map[string][]string) {
+	for key, values := range enums {
+		b.enums[key] = values
+	}
+}
+
+// SetErrorResponse menetapkan tipe body error yang dipakai sebagai respons default
+func (b *Builder) SetErrorResponse(sample interface{}) error {
+	schema, err := b.schemaFor(reflect.TypeOf(sample))
+	if err != nil {
+		return err
+	}
+	b.errorSchema = schema
+	return nil
+}
+
+// AddSecurityScheme mendaftarkan skema keamanan dengan nama tertentu
+func (b *Builder) AddSecurityScheme(name string, scheme SecurityScheme) {
+	b.doc.Components.SecuritySchemes[name] = scheme
+}
+
+// AddTag menambahkan deskripsi grup operasi, urutannya dipakai Swagger UI
+func (b *Builder) AddTag(name, description string) {
+	b.doc.Tags = append(b.doc.Tags, Tag{Name: name, Description: description})
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}.]+)(\.\.\.)?\}`)
+
+// Add memasukkan satu route ke dokumen
+func (b *Builder) Add(rt Route) error {
+	method, path, ok := strings.Cut(rt.Pattern, " ")
+	if !ok || method == "" || !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("openapi: route %q must have the form \"METHOD /path\"", rt.Pattern)
+	}
+	method = strings.ToLower(method)
+
+	if rt.OperationID == "" {
+		return fmt.Errorf("openapi: route %q has no operation id", rt.Pattern)
+	}
+	for _, item := range b.doc.Paths {
+		for _, existing := range item {
+			if existing.OperationID == rt.OperationID {
+				return fmt.Errorf("openapi: duplicate operation id %q", rt.OperationID)
+			}
+		}
+	}
+
+	op := &Operation{
+		OperationID: rt.OperationID,
+		Summary:     rt.Summary,
+		Description: rt.Description,
+		Deprecated:  rt.Deprecated,
+		Permission:  rt.Permission,
+		Responses:   map[string]*Response{},
+	}
+	if rt.Tag != "" {
+		op.Tags = []string{rt.Tag}
+	}
+	for _, name := range rt.Security {
+		if _, ok := b.doc.Components.SecuritySchemes[name]; !ok {
+			return fmt.Errorf("openapi: route %q uses unknown security scheme %q", rt.Pattern, name)
+		}
+		op.Security = append(op.Security, map[string][]string{name: {}})
+	}
+
+	// Parameter path diambil dari pattern; parameter biasa berupa ID numerik,
+	// wildcard "{name...}" berupa sisa path
+	for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		schema := &Schema{Type: "integer"}
+		if match[2] != "" {
+			schema = &Schema{Type: "string"}
+		}
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   schema,
+		})
+	}
+	path = pathParamPattern.ReplaceAllString(path, "{$1}")
+	op.Parameters = append(op.Parameters, rt.Query...)
+
+	if rt.Request != nil {
+		schema, err := b.schemaFor(reflect.TypeOf(rt.Request))
+		if err != nil {
+			return fmt.Errorf("openapi: request body of %q: %w", rt.Pattern, err)
+		}
+		contentType := rt.RequestContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{contentType: {Schema: schema}}}
+	}
+
+	status := rt.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status)}
+	switch {
+	case rt.Response != nil:
+		schema, err := b.schemaFor(reflect.TypeOf(rt.Response))
+		if err != nil {
+			return fmt.Errorf("openapi: response body of %q: %w", rt.Pattern, err)
+		}
+		success.Content = map[string]MediaType{"application/json": {Schema: schema}}
+	case rt.ContentType != "":
+		success.Content = map[string]MediaType{rt.ContentType: {Schema: &Schema{Type: "string"}}}
+	default:
+		return fmt.Errorf("openapi: route %q has no documented response", rt.Pattern)
+	}
+	op.Responses[strconv.Itoa(status)] = success
+	if b.errorSchema != nil {
+		op.Responses["default"] = &Response{
+			Description: "Error",
+			Content:     map[string]MediaType{"application/json": {Schema: b.errorSchema}},
+		}
+	}
+
+	item, ok := b.doc.Paths[path]
+	if !ok {
+		item = PathItem{}
+		b.doc.Paths[path] = item
+	}
+	if _, exists := item[method]; exists {
+		return fmt.Errorf("openapi: duplicate route %q", rt.Pattern)
+	}
+	item[method] = op
+	return nil
+}
+
+// Document mengembalikan dokumen yang sudah dibangun
+func (b *Builder) Document() *Document {
+	return b.doc
+}
@@ -0,0 +1,175 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema adalah subset JSON Schema (dialek OpenAPI 3.1) yang dipakai API ini
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 interface{}        `json:"type,omitempty"` // string, atau []string untuk tipe nullable
+	Format               string             `json:"format,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaFor membuat schema dari tipe Go mengikuti aturan encoding/json.
+// Struct bernama didaftarkan di components dan dirujuk lewat $ref.
+//
+// Aturan yang dipakai:
+//   - field tanpa omitempty dan bukan pointer dianggap required
+//   - field pointer bersifat nullable dan opsional
+//   - tag `format:"..."` mengisi format, misalnya format:"date" untuk YYYY-MM-DD
+func (b *Builder) schemaFor(t reflect.Type) (*Schema, error) {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}, nil
+	case rawMessageType:
+		return &Schema{}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}, nil
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}, nil
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Interface:
+		return &Schema{}, nil
+	case reflect.Pointer:
+		inner, err := b.schemaFor(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		if typ, ok := inner.Type.(string); ok {
+			inner.Type = []string{typ, "null"}
+		}
+		return inner, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}, nil
+		}
+		items, err := b.schemaFor(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("openapi: unsupported map key type %s", t.Key())
+		}
+		values, err := b.schemaFor(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Struct:
+		return b.structRef(t)
+	}
+	return nil, fmt.Errorf("openapi: unsupported type %s", t)
+}
+
+// structRef mendaftarkan struct bernama di components lalu mengembalikan $ref ke sana
+func (b *Builder) structRef(t reflect.Type) (*Schema, error) {
+	if t.Name() == "" {
+		return b.structSchema(t)
+	}
+
+	name := t.Name()
+	if existing, ok := b.types[name]; ok {
+		if existing != t {
+			return nil, fmt.Errorf("openapi: schema name %s used by both %s and %s", name, existing, t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}, nil
+	}
+
+	// Didaftarkan dulu agar tipe rekursif tidak berulang tanpa akhir
+	b.types[name] = t
+	schema, err := b.structSchema(t)
+	if err != nil {
+		return nil, err
+	}
+	b.doc.Components.Schemas[name] = schema
+	return &Schema{Ref: "#/components/schemas/" + name}, nil
+}
+
+// structSchema membuat schema object dari field struct yang diekspor
+func (b *Builder) structSchema(t reflect.Type) (*Schema, error) {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	err := b.addFields(schema, t)
+	if err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func (b *Builder) addFields(schema *Schema, t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		// Struct embedded tanpa nama JSON digabung ke struct induk, seperti encoding/json
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if err := b.addFields(schema, embedded); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property, err := b.schemaFor(field.Type)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", t.Name(), field.Name, err)
+		}
+		if format := field.Tag.Get("format"); format != "" {
+			property.Format = format
+		}
+		if values, ok := b.enums[t.Name()+"."+name]; ok {
+			// Untuk array, enum berlaku pada setiap elemen
+			if property.Items != nil {
+				property.Items.Enum = values
+			} else {
+				property.Enum = values
+			}
+		}
+		schema.Properties[name] = property
+
+		omitEmpty := strings.Contains(","+opts+",", ",omitempty,")
+		if !omitEmpty && field.Type.Kind() != reflect.Pointer {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestOpenAPIUpToDate menjalankan pemeriksaan yang sama dengan "go run . openapi -check"
+func TestOpenAPIUpToDate(t *testing.T) {
+	var stdout, stderr strings.Builder
+	if code := runOpenAPICommand([]string{"-check"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"BookRoom", "Book room"},
+		{"CreateAPIKey", "Create API key"},
+		{"ListAPIKeys", "List API keys"},
+		{"GetBooking", "Get booking"},
+		{"Login", "Login"},
+	}
+	for _, tt := range tests {
+		if got := summarize(tt.name); got != tt.want {
+			t.Errorf("summarize(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRouteTag(t *testing.T) {
+	tests := []struct {
+		rt   route
+		want string
+	}{
+		{route{pattern: "GET /properties/{id}/rooms"}, "properties"},
+		{route{pattern: "GET /amenities"}, "rooms"},
+		{route{pattern: "PUT /reviews/{id}/reply"}, "properties"},
+		{route{pattern: "GET /media/{key...}"}, "properties"},
+		{route{pattern: "GET /me"}, "account"},
+		{route{pattern: "GET /healthz"}, "system"},
+		{route{pattern: "POST /login"}, "auth"},
+		{route{pattern: "POST /booking", successor: "/bookings"}, "legacy"},
+	}
+	for _, tt := range tests {
+		if got := routeTag(tt.rt); got != tt.want {
+			t.Errorf("routeTag(%s) = %s, want %s", tt.rt.pattern, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"booking_system_app/apierror"
+	"booking_system_app/database"
+	"booking_system_app/middleware"
+	"booking_system_app/openapi"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// handlerFunc adalah bentuk handler di package database
+type handlerFunc func(db *sql.DB, w http.ResponseWriter, r *http.Request)
+
+// route adalah satu baris tabel routing
+type route struct {
+	// pattern memakai sintaks ServeMux Go 1.22: "METHOD /path/{param}"
+	pattern string
+	// permission yang dibutuhkan, kosong untuk route publik
+	permission string
+	handler    handlerFunc
+	// successor diisi untuk route lama yang dipertahankan sebagai alias deprecated
+	successor string
+
+	// Dokumentasi OpenAPI (lihat openapi.go): request dan response berisi zero
+	// value tipe body, status adalah kode sukses jika bukan 200. Route alias
+	// mewarisi dokumentasi route utama dengan handler yang sama.
+	request  interface{}
+	response interface{}
+	status   int
+	query    []openapi.Parameter
+	// requestContentType dan contentType diisi untuk body selain JSON
+	requestContentType string
+	contentType        string
+}
+
+// routes adalah tabel routing seluruh API
+var routes = []route{
+	// Probe untuk load balancer (publik)
+	{pattern: "GET /healthz", handler: database.Healthz, response: database.HealthResponse{}},
+	{pattern: "GET /readyz", handler: database.Readyz, response: database.HealthResponse{}},
+
+	// Registrasi, login dan pemulihan akun (publik)
+	{pattern: "POST /register", handler: database.RegisterUser, request: database.RegisterRequest{}, response: database.Response{}, status: http.StatusCreated},
+	{pattern: "POST /login", handler: database.LoginUser, request: database.LoginRequest{}, response: database.Response{}},
+	{pattern: "POST /login/2fa", handler: database.VerifyTwoFactorLogin, request: database.TwoFactorLoginRequest{}, response: database.Response{}},
+	{pattern: "POST /login/2fa/enroll", handler: database.EnrollTwoFactorLogin, request: database.TwoFactorLoginRequest{}, response: database.TOTPSetupResponse{}},
+	{pattern: "POST /verify_email", handler: database.VerifyEmail, request: database.ConfirmTokenRequest{}, response: database.Response{}},
+	{pattern: "POST /resend_verification", handler: database.ResendVerification, request: database.EmailRequest{}, response: database.Response{}, status: http.StatusAccepted},
+	{pattern: "POST /forgot_password", handler: database.ForgotPassword, request: database.EmailRequest{}, response: database.Response{}, status: http.StatusAccepted},
+	{pattern: "POST /reset_password", handler: database.ResetPassword, request: database.ResetPasswordRequest{}, response: database.Response{}},
+
+	// Katalog fasilitas untuk filter pencarian dan form kamar/properti (publik)
+	{pattern: "GET /amenities", handler: database.ListAmenities, response: []database.Amenity{}},
+
+	// Galeri foto dan file media (publik)
+	{pattern: "GET /properties/{id}/photos", handler: database.ListPropertyPhotos, response: []database.Photo{}},
+	{pattern: "GET /rooms/{id}/photos", handler: database.ListRoomPhotos, response: []database.Photo{}},
+	{pattern: "GET /media/{key...}", handler: database.ServeMedia, contentType: "image/*"},
+
+	// Ulasan properti dan agregat ratingnya (publik)
+	{pattern: "GET /properties/{id}/reviews", handler: database.ListPropertyReviews, response: database.ReviewListResponse{}, query: listParams},
+
+	// Akun milik pengguna yang sedang login
+	{pattern: "GET /me", permission: middleware.PermProfileManage, handler: database.GetProfile, response: database.Profile{}},
+	{pattern: "PATCH /me", permission: middleware.PermProfileManage, handler: database.UpdateProfile, request: database.UpdateProfileRequest{}, response: database.Response{}},
+	{pattern: "DELETE /me", permission: middleware.PermProfileManage, handler: database.DeleteAccount, request: database.DeleteAccountRequest{}, response: database.Response{}},
+	{pattern: "POST /me/password", permission: middleware.PermProfileManage, handler: database.ChangePassword, request: database.ChangePasswordRequest{}, response: database.Response{}},
+	{pattern: "POST /me/email", permission: middleware.PermProfileManage, handler: database.RequestEmailChange, request: database.ChangeEmailRequest{}, response: database.Response{}, status: http.StatusAccepted},
+	{pattern: "POST /me/email/confirm", permission: middleware.PermProfileManage, handler: database.ConfirmEmailChange, request: database.ConfirmTokenRequest{}, response: database.Response{}},
+	{pattern: "POST /me/token", permission: middleware.PermProfileManage, handler: database.RefreshToken, response: database.Response{}},
+	{pattern: "POST /me/2fa/setup", permission: middleware.PermProfileManage, handler: database.SetupTwoFactor, response: database.TOTPSetupResponse{}},
+	{pattern: "POST /me/2fa/enable", permission: middleware.PermProfileManage, handler: database.EnableTwoFactor, request: database.TwoFactorCodeRequest{}, response: database.RecoveryCodesResponse{}},
+	{pattern: "POST /me/2fa/disable", permission: middleware.PermProfileManage, handler: database.DisableTwoFactor, request: database.DisableTwoFactorRequest{}, response: database.Response{}},
+	{pattern: "POST /me/2fa/recovery_codes", permission: middleware.PermProfileManage, handler: database.RegenerateRecoveryCodes, request: database.TwoFactorCodeRequest{}, response: database.RecoveryCodesResponse{}},
+
+	// Properti, kamar dan pemesanan
+	{pattern: "POST /properties", permission: middleware.PermPropertyWrite, handler: database.AddProperty, request: database.Property{}, response: database.Response{}, status: http.StatusCreated},
+	{pattern: "PUT /properties/{id}/location", permission: middleware.PermPropertyWrite, handler: database.UpdatePropertyLocation, request: database.PropertyLocation{}, response: database.Response{}},
+	{pattern: "PUT /properties/{id}/amenities", permission: middleware.PermPropertyWrite, handler: database.SetPropertyAmenities, request: database.PropertyAmenitiesRequest{}, response: database.Response{}},
+	{pattern: "POST /properties/{id}/photos", permission: middleware.PermPropertyWrite, handler: database.UploadPropertyPhoto, request: database.PhotoUpload{}, requestContentType: "multipart/form-data", response: database.Photo{}, status: http.StatusCreated},
+	{pattern: "PATCH /photos/{id}", permission: middleware.PermPropertyWrite, handler: database.UpdatePhoto, request: database.UpdatePhotoRequest{}, response: database.Photo{}},
+	{pattern: "DELETE /photos/{id}", permission: middleware.PermPropertyWrite, handler: database.DeletePhoto, response: database.Response{}},
+	{pattern: "POST /properties/{id}/rooms", permission: middleware.PermRoomWrite, handler: database.AddRoom, request: database.Room{}, response: database.Response{}, status: http.StatusCreated},
+	{pattern: "PUT /rooms/{id}/status", permission: middleware.PermRoomWrite, handler: database.UpdateRoomStatus, request: database.UpdateStatusRequest{}, response: database.Response{}},
+	{pattern: "POST /rooms/{id}/photos", permission: middleware.PermRoomWrite, handler: database.UploadRoomPhoto, request: database.PhotoUpload{}, requestContentType: "multipart/form-data", response: database.Photo{}, status: http.StatusCreated},
+	{pattern: "PUT /rooms/{id}/features", permission: middleware.PermRoomWrite, handler: database.UpdateRoomFeatures, request: database.RoomFeatures{}, response: database.Response{}},
+	{pattern: "POST /rooms/search", permission: middleware.PermRoomSearch, handler: database.SearchRooms, request: database.SearchCriteria{}, response: database.RoomSearchResponse{}},
+	{pattern: "POST /bookings", permission: middleware.PermBookingCreate, handler: database.BookRoom, request: database.BookingRequest{}, response: database.BookingResponse{}, status: http.StatusCreated},
+	{pattern: "GET /bookings/{id}", permission: middleware.PermProfileManage, handler: database.GetBooking, response: database.Booking{}},
+	{pattern: "POST /bookings/{id}/cancel", permission: middleware.PermProfileManage, handler: database.CancelBooking, response: database.Booking{}},
+	{pattern: "POST /bookings/{id}/complete", permission: middleware.PermBookingCheckin, handler: database.CompleteBooking, response: database.Booking{}},
+	{pattern: "POST /bookings/{id}/review", permission: middleware.PermProfileManage, handler: database.CreateReview, request: database.ReviewRequest{}, response: database.Review{}, status: http.StatusCreated},
+	{pattern: "PUT /reviews/{id}/reply", permission: middleware.PermReviewReply, handler: database.ReplyToReview, request: database.ReviewReplyRequest{}, response: database.Review{}},
+
+	// Konsol admin
+	{pattern: "GET /admin/users", permission: middleware.PermUserManage, handler: database.ListUsers, response: database.UserListResponse{}, query: userListParams},
+	{pattern: "POST /admin/users", permission: middleware.PermUserManage, handler: database.CreateUser, request: database.RegisterRequest{}, response: database.Response{}, status: http.StatusCreated},
+	{pattern: "PUT /admin/users/{id}/role", permission: middleware.PermUserManage, handler: database.ChangeUserRole, request: database.ChangeRoleRequest{}, response: database.Response{}},
+	{pattern: "PUT /admin/users/{id}/status", permission: middleware.PermUserManage, handler: database.SetUserStatus, request: database.SetUserStatusRequest{}, response: database.Response{}},
+	{pattern: "GET /admin/users/{id}/bookings", permission: middleware.PermUserManage, handler: database.ListUserBookings, response: database.UserBookingListResponse{}, query: listParams},
+	{pattern: "GET /admin/staff_assignments", permission: middleware.PermUserManage, handler: database.ListStaffAssignments, response: database.StaffAssignmentListResponse{}, query: staffAssignmentParams},
+	{pattern: "POST /admin/staff_assignments", permission: middleware.PermUserManage, handler: database.AssignStaff, request: database.StaffAssignmentRequest{}, response: database.Response{}, status: http.StatusCreated},
+	{pattern: "DELETE /admin/staff_assignments", permission: middleware.PermUserManage, handler: database.UnassignStaff, request: database.StaffAssignmentRequest{}, response: database.Response{}},
+	{pattern: "GET /admin/api_keys", permission: middleware.PermUserManage, handler: database.ListAPIKeys, response: database.APIKeyListResponse{}, query: apiKeyListParams},
+	{pattern: "POST /admin/api_keys", permission: middleware.PermUserManage, handler: database.CreateAPIKey, request: database.CreateAPIKeyRequest{}, response: database.CreateAPIKeyResponse{}, status: http.StatusCreated},
+	{pattern: "DELETE /admin/api_keys/{id}", permission: middleware.PermUserManage, handler: database.RevokeAPIKey, response: database.Response{}},
+	{pattern: "GET /admin/audit_logs", permission: middleware.PermUserManage, handler: database.ListAuditLogs, response: database.AuditLogListResponse{}, query: auditLogParams},
+	{pattern: "GET /admin/reviews", permission: middleware.PermReviewModerate, handler: database.ListReviews, response: database.ReviewListResponse{}, query: reviewListParams},
+	{pattern: "PUT /admin/reviews/{id}/moderation", permission: middleware.PermReviewModerate, handler: database.ModerateReview, request: database.ModerateReviewRequest{}, response: database.Review{}},
+
+	// Monitoring, bisa diakses admin atau API key dengan scope system:monitor
+	{pattern: "GET /admin/db_stats", permission: middleware.PermSystemMonitor, handler: database.PoolStats, response: database.PoolStatsResponse{}},
+
+	// Route lama berbasis aksi, dipertahankan untuk klien yang sudah ada
+	{pattern: "POST /add_property", permission: middleware.PermPropertyWrite, handler: database.AddProperty, successor: "/properties"},
+	{pattern: "POST /add_room", permission: middleware.PermRoomWrite, handler: database.AddRoom, successor: "/properties/{id}/rooms"},
+	{pattern: "PUT /update_room_status", permission: middleware.PermRoomWrite, handler: database.UpdateRoomStatus, successor: "/rooms/{id}/status"},
+	{pattern: "POST /search_rooms", permission: middleware.PermRoomSearch, handler: database.SearchRoomsLegacy, successor: "/rooms/search", request: database.SearchCriteria{}, response: []database.RoomSearchResult{}},
+	{pattern: "POST /booking", permission: middleware.PermBookingCreate, handler: database.BookRoom, successor: "/bookings"},
+	{pattern: "POST /admin/create_user", permission: middleware.PermUserManage, handler: database.CreateUser, successor: "/admin/users"},
+	{pattern: "PUT /admin/users/role", permission: middleware.PermUserManage, handler: database.ChangeUserRole, successor: "/admin/users/{id}/role"},
+	{pattern: "PUT /admin/users/status", permission: middleware.PermUserManage, handler: database.SetUserStatus, successor: "/admin/users/{id}/status"},
+	{pattern: "GET /admin/users/bookings", permission: middleware.PermUserManage, handler: database.ListUserBookings, successor: "/admin/users/{id}/bookings", query: legacyUserBookingsParams},
+	{pattern: "DELETE /admin/api_keys", permission: middleware.PermUserManage, handler: database.RevokeAPIKey, successor: "/admin/api_keys/{id}", request: database.RevokeAPIKeyRequest{}},
+}
+
+// newRouter mendaftarkan tabel routes ke ServeMux baru
+func newRouter(db *sql.DB) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	for _, rt := range routes {
+		handle := rt.handler
+		var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handle(db, w, r)
+		})
+		if rt.permission != "" {
+			h = middleware.RequirePermission(rt.permission, db, h.ServeHTTP)
+		}
+		if rt.successor != "" {
+			h = middleware.Deprecated(rt.successor, h)
+		}
+		mux.Handle(rt.pattern, h)
+	}
+
+	// Endpoint scrape Prometheus, publik seperti /healthz dan sebaiknya
+	// hanya dibuka di jaringan internal
+	mux.Handle("GET /metrics", promhttp.Handler())
+
+	// Dokumen OpenAPI dan Swagger UI (lihat openapi.go)
+	registerOpenAPI(mux)
+
+	// Route yang tidak dikenal tetap dijawab dengan format error JSON
+	mux.Handle("/", fallback(mux))
+	return mux
+}
+
+// probeMethods adalah method yang diperiksa untuk membangun header Allow
+var probeMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// fallback menjawab 405 jika path dikenal dengan method lain, selain itu 404.
+// Ini menggantikan respons teks bawaan ServeMux agar tetap memakai format error JSON.
+func fallback(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var allowed []string
+		for _, method := range probeMethods {
+			probe := r.Clone(r.Context())
+			probe.Method = method
+			if _, pattern := mux.Handler(probe); pattern != "" && pattern != "/" {
+				allowed = append(allowed, method)
+			}
+		}
+
+		if len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			apierror.Write(w, r, apierror.MethodNotAllowed())
+			return
+		}
+		apierror.Write(w, r, apierror.NotFound("Route not found"))
+	})
+}
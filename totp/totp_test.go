@@ -0,0 +1,113 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret adalah secret ASCII "12345678901234567890" dari RFC 6238 Appendix B
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeAtRFC6238(t *testing.T) {
+	// Vektor SHA1 dari RFC 6238, dipotong ke 6 digit terakhir
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := CodeAt(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("CodeAt(%d): %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("CodeAt(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeAtNormalizesSecret(t *testing.T) {
+	got, err := CodeAt(" "+strings.ToLower(rfcSecret)+" ", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "287082" {
+		t.Errorf("got %s, want 287082", got)
+	}
+	if _, err := CodeAt("not base32!", 1); err == nil {
+		t.Error("expected error for invalid secret")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+	codeAt := func(step int64) string {
+		code, err := CodeAt(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", rfcSecret, codeAt(current), current, true},
+		{"previous step within skew", rfcSecret, codeAt(current - 1), current - 1, true},
+		{"next step within skew", rfcSecret, codeAt(current + 1), current + 1, true},
+		{"outside skew", rfcSecret, codeAt(current - 2), 0, false},
+		{"surrounding spaces", rfcSecret, " " + codeAt(current) + " ", current, true},
+		{"too short", rfcSecret, "12345", 0, false},
+		{"too long", rfcSecret, "1234567", 0, false},
+		{"invalid secret", "not base32!", "123456", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(tt.secret, tt.code, now)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("Validate = (%d, %v), want (%d, %v)", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 160 bit dalam base32 tanpa padding
+	if len(secret) != 32 {
+		t.Errorf("len(secret) = %d, want 32", len(secret))
+	}
+	if _, err := CodeAt(secret, 1); err != nil {
+		t.Errorf("generated secret is not usable: %v", err)
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	got := ProvisioningURI("Booking System", "user@example.com", rfcSecret)
+	for _, part := range []string{
+		"otpauth://totp/Booking%20System:user@example.com?",
+		"secret=" + rfcSecret,
+		"issuer=Booking+System",
+		"digits=6",
+		"period=30",
+	} {
+		if !strings.Contains(got, part) {
+			t.Errorf("%s does not contain %s", got, part)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Package tracing mengonfigurasi OpenTelemetry tracing untuk seluruh aplikasi.
+// Exporter dipilih lewat OTEL_TRACES_EXPORTER:
+//
+//	otlp   - kirim ke collector lewat OTLP/HTTP (OTEL_EXPORTER_OTLP_ENDPOINT dan variabel OTEL_* standar lainnya)
+//	stdout - cetak span ke stdout, untuk development
+//	file   - tulis span sebagai JSON ke TRACES_FILE (default traces.json)
+//	none   - tracing nonaktif (default)
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName adalah nama tracer untuk span yang dibuat manual oleh aplikasi
+const instrumentationName = "booking_system_app"
+
+// defaultServiceName dipakai jika OTEL_SERVICE_NAME tidak diisi
+const defaultServiceName = "booking-system"
+
+// Tracer mengembalikan tracer aplikasi. Sebelum Setup dipanggil (atau jika
+// tracing nonaktif) tracer ini tidak merekam apa pun.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start membuka span anak dari span di ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// Setup memasang TracerProvider dan propagator global sesuai environment.
+// Fungsi shutdown yang dikembalikan harus dipanggil saat server berhenti agar
+// span yang masih di-buffer ikut terkirim.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	// Header traceparent dari klien/proxy diteruskan agar trace tersambung
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	exporter, closer, err := newExporter(ctx, os.Getenv("OTEL_TRACES_EXPORTER"))
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(attribute.String("service.name", serviceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			closer.Close()
+		}
+		return err
+	}, nil
+}
+
+// newExporter membuat exporter sesuai nama. Exporter nil berarti tracing nonaktif.
+func newExporter(ctx context.Context, name string) (sdktrace.SpanExporter, io.Closer, error) {
+	switch name {
+	case "", "none":
+		return nil, nil, nil
+	case "otlp":
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error creating OTLP exporter: %w", err)
+		}
+		return exporter, nil, nil
+	case "stdout":
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, nil, fmt.Errorf("error creating stdout exporter: %w", err)
+		}
+		return exporter, nil, nil
+	case "file":
+		path := os.Getenv("TRACES_FILE")
+		if path == "" {
+			path = "traces.json"
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error opening traces file: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("error creating file exporter: %w", err)
+		}
+		return exporter, file, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown OTEL_TRACES_EXPORTER %q (use otlp, stdout, file or none)", name)
+	}
+}
@@ -0,0 +1,162 @@
+// Package validate menyediakan validator eksplisit yang mengumpulkan semua
+// kesalahan field sekaligus, lalu mengubahnya menjadi apierror validasi.
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"booking_system_app/apierror"
+)
+
+// DateLayout adalah format tanggal yang diterima API
+const DateLayout = "2006-01-02"
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{8,15}$`)
+
+// Validator mengumpulkan kesalahan validasi per field
+type Validator struct {
+	errors []apierror.FieldError
+}
+
+// AddError mencatat kesalahan pada field
+func (v *Validator) AddError(field, message string) {
+	v.errors = append(v.errors, apierror.FieldError{Field: field, Message: message})
+}
+
+// Check mencatat message untuk field jika ok bernilai false
+func (v *Validator) Check(ok bool, field, message string) {
+	if !ok {
+		v.AddError(field, message)
+	}
+}
+
+// Valid bernilai true jika belum ada kesalahan
+func (v *Validator) Valid() bool {
+	return len(v.errors) == 0
+}
+
+// Err mengembalikan apierror validasi berisi semua kesalahan, atau nil jika valid
+func (v *Validator) Err() error {
+	if v.Valid() {
+		return nil
+	}
+	return apierror.Validation(v.errors)
+}
+
+// Required memastikan string tidak kosong
+func (v *Validator) Required(field, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.AddError(field, "is required")
+		return false
+	}
+	return true
+}
+
+// MaxLength memastikan panjang string (dalam karakter) tidak melebihi max
+func (v *Validator) MaxLength(field, value string, max int) {
+	if utf8.RuneCountInString(value) > max {
+		v.AddError(field, fmt.Sprintf("must be at most %d characters", max))
+	}
+}
+
+// Email memastikan format alamat email valid
+func (v *Validator) Email(field, value string) {
+	if !v.Required(field, value) {
+		return
+	}
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != value || !strings.Contains(value[strings.LastIndex(value, "@"):], ".") {
+		v.AddError(field, "must be a valid email address")
+		return
+	}
+	v.MaxLength(field, value, 100)
+}
+
+// Password memastikan password cukup kuat: 8-72 byte (batas bcrypt),
+// mengandung huruf dan angka
+func (v *Validator) Password(field, value string) {
+	if len(value) < 8 {
+		v.AddError(field, "must be at least 8 characters")
+		return
+	}
+	if len(value) > 72 {
+		v.AddError(field, "must be at most 72 bytes")
+		return
+	}
+
+	var hasLetter, hasDigit bool
+	for _, c := range value {
+		switch {
+		case unicode.IsLetter(c):
+			hasLetter = true
+		case unicode.IsDigit(c):
+			hasDigit = true
+		}
+	}
+	if !hasLetter || !hasDigit {
+		v.AddError(field, "must contain at least one letter and one digit")
+	}
+}
+
+// Phone memastikan nomor telepon berformat internasional sederhana.
+// Spasi, tanda hubung dan kurung diabaikan. Nilai kosong dianggap valid.
+func (v *Validator) Phone(field, value string) {
+	if value == "" {
+		return
+	}
+	normalized := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(value)
+	if !phonePattern.MatchString(normalized) {
+		v.AddError(field, "must be a valid phone number (8-15 digits, optional leading +)")
+	}
+	v.MaxLength(field, value, 20)
+}
+
+// OneOf memastikan value termasuk salah satu nilai yang diizinkan
+func (v *Validator) OneOf(field, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.AddError(field, "must be one of: "+strings.Join(allowed, ", "))
+}
+
+// PositiveInt memastikan bilangan bulat lebih dari nol
+func (v *Validator) PositiveInt(field string, value int) {
+	if value <= 0 {
+		v.AddError(field, "must be greater than 0")
+	}
+}
+
+// Positive memastikan bilangan lebih dari nol
+func (v *Validator) Positive(field string, value float64) {
+	if value <= 0 {
+		v.AddError(field, "must be greater than 0")
+	}
+}
+
+// NonNegative memastikan bilangan tidak negatif
+func (v *Validator) NonNegative(field string, value float64) {
+	if value < 0 {
+		v.AddError(field, "must not be negative")
+	}
+}
+
+// Date mem-parse tanggal berformat YYYY-MM-DD
+func (v *Validator) Date(field, value string) (time.Time, bool) {
+	if !v.Required(field, value) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(DateLayout, value)
+	if err != nil {
+		v.AddError(field, "must be a date in YYYY-MM-DD format")
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,127 @@
+package validate
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"booking_system_app/apierror"
+)
+
+// details mengambil kesalahan field dari hasil Err
+func details(t *testing.T, v *Validator) []apierror.FieldError {
+	t.Helper()
+	err := v.Err()
+	if err == nil {
+		return nil
+	}
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != apierror.CodeValidation {
+		t.Fatalf("Err() = %v, want validation error", err)
+	}
+	return apiErr.Details
+}
+
+func TestValidatorMessages(t *testing.T) {
+	tests := []struct {
+		name  string
+		check func(v *Validator)
+		want  string // pesan yang diharapkan, kosong berarti valid
+	}{
+		{"required empty", func(v *Validator) { v.Required("f", "") }, "is required"},
+		{"required spaces", func(v *Validator) { v.Required("f", "   ") }, "is required"},
+		{"required ok", func(v *Validator) { v.Required("f", "x") }, ""},
+		{"max length counts runes", func(v *Validator) { v.MaxLength("f", "ééé", 3) }, ""},
+		{"max length exceeded", func(v *Validator) { v.MaxLength("f", "abcd", 3) }, "must be at most 3 characters"},
+		{"email ok", func(v *Validator) { v.Email("f", "user@example.com") }, ""},
+		{"email empty", func(v *Validator) { v.Email("f", "") }, "is required"},
+		{"email with name", func(v *Validator) { v.Email("f", "User <user@example.com>") }, "must be a valid email address"},
+		{"email without dot", func(v *Validator) { v.Email("f", "user@localhost") }, "must be a valid email address"},
+		{"email too long", func(v *Validator) { v.Email("f", strings.Repeat("a", 95)+"@x.com") }, "must be at most 100 characters"},
+		{"password ok", func(v *Validator) { v.Password("f", "secret123") }, ""},
+		{"password short", func(v *Validator) { v.Password("f", "abc1") }, "must be at least 8 characters"},
+		{"password long", func(v *Validator) { v.Password("f", strings.Repeat("a1", 37)) }, "must be at most 72 bytes"},
+		{"password letters only", func(v *Validator) { v.Password("f", "abcdefgh") }, "must contain at least one letter and one digit"},
+		{"password digits only", func(v *Validator) { v.Password("f", "12345678") }, "must contain at least one letter and one digit"},
+		{"phone empty", func(v *Validator) { v.Phone("f", "") }, ""},
+		{"phone formatted", func(v *Validator) { v.Phone("f", "+62 (812) 3456-789") }, ""},
+		{"phone letters", func(v *Validator) { v.Phone("f", "0812-abc") }, "must be a valid phone number (8-15 digits, optional leading +)"},
+		{"one of ok", func(v *Validator) { v.OneOf("f", "b", "a", "b") }, ""},
+		{"one of invalid", func(v *Validator) { v.OneOf("f", "c", "a", "b") }, "must be one of: a, b"},
+		{"positive int zero", func(v *Validator) { v.PositiveInt("f", 0) }, "must be greater than 0"},
+		{"positive negative", func(v *Validator) { v.Positive("f", -1.5) }, "must be greater than 0"},
+		{"non-negative zero", func(v *Validator) { v.NonNegative("f", 0) }, ""},
+		{"non-negative negative", func(v *Validator) { v.NonNegative("f", -0.01) }, "must not be negative"},
+		{"check false", func(v *Validator) { v.Check(false, "f", "custom") }, "custom"},
+		{"check true", func(v *Validator) { v.Check(true, "f", "custom") }, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v Validator
+			tt.check(&v)
+			got := details(t, &v)
+			if tt.want == "" {
+				if got != nil {
+					t.Errorf("got %v, want valid", got)
+				}
+				return
+			}
+			want := []apierror.FieldError{{Field: "f", Message: tt.want}}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDate(t *testing.T) {
+	tests := []struct {
+		value  string
+		wantOK bool
+		want   string
+	}{
+		{"2024-02-29", true, ""},
+		{"", false, "is required"},
+		{"2023-02-29", false, "must be a date in YYYY-MM-DD format"},
+		{"29-02-2024", false, "must be a date in YYYY-MM-DD format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var v Validator
+			date, ok := v.Date("date", tt.value)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok {
+				if date.Format(DateLayout) != tt.value {
+					t.Errorf("date = %s, want %s", date.Format(DateLayout), tt.value)
+				}
+				return
+			}
+			want := []apierror.FieldError{{Field: "date", Message: tt.want}}
+			if got := details(t, &v); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestValidatorCollectsAllErrors(t *testing.T) {
+	var v Validator
+	v.Required("name", "")
+	v.Email("email", "invalid")
+	v.PositiveInt("quantity", 0)
+
+	want := []apierror.FieldError{
+		{Field: "name", Message: "is required"},
+		{Field: "email", Message: "must be a valid email address"},
+		{Field: "quantity", Message: "must be greater than 0"},
+	}
+	if got := details(t, &v); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if v.Valid() {
+		t.Error("Valid() = true with errors")
+	}
+}